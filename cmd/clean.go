@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/prathyushnallamothu/aiterate/internal/executor"
+	"github.com/prathyushnallamothu/aiterate/internal/storage"
+)
+
+var (
+	cleanDryRun    bool
+	cleanOlderThan string
+)
+
+func init() {
+	rootCmd.AddCommand(cleanCmd)
+	cleanCmd.Flags().BoolVar(&cleanDryRun, "dry-run", false, "Show what would be removed without deleting anything")
+	cleanCmd.Flags().StringVar(&cleanOlderThan, "older-than", "24h", "Only remove items last modified longer ago than this, e.g. 24h or 7d")
+}
+
+var cleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove leftover temporary workspaces and sessions with no iterations",
+	Long: `Remove aiterate-* directories left in the system temp directory by
+interrupted runs, workspaces kept under its aiterate directory, and sessions
+that never recorded an iteration.
+
+Only items older than --older-than are removed, so runs in progress are left alone.`,
+	Args: cobra.NoArgs,
+	RunE: runClean,
+}
+
+func runClean(cmd *cobra.Command, args []string) error {
+	age, err := parsePeriod(cleanOlderThan)
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-age)
+
+	store, err := openStorage()
+	if err != nil {
+		return err
+	}
+
+	cmd.SilenceUsage = true
+
+	verb := "Removed"
+	if cleanDryRun {
+		verb = "Would remove"
+	}
+
+	var count int
+	var reclaimed int64
+	remove := func(path string, del func() error) error {
+		size := diskUsage(path)
+		if !cleanDryRun {
+			if err := del(); err != nil {
+				return err
+			}
+		}
+		color.Blue("%s %s (%s)", verb, path, formatBytes(size))
+		count++
+		reclaimed += size
+		return nil
+	}
+
+	// Temporary workspaces, mutation scratch dirs and edit files
+	tmpDir := os.TempDir()
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		return fmt.Errorf("failed to read temp directory: %w", err)
+	}
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), "aiterate-") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		path := filepath.Join(tmpDir, entry.Name())
+		if err := remove(path, func() error { return os.RemoveAll(path) }); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+
+	// Per-session workspaces, typically kept with --keep-workspace
+	workspaces := executor.WorkspaceRoot()
+	entries, err = os.ReadDir(workspaces)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read workspace directory: %w", err)
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !entry.IsDir() || info.ModTime().After(cutoff) {
+			continue
+		}
+		path := filepath.Join(workspaces, entry.Name())
+		if err := remove(path, func() error { return os.RemoveAll(path) }); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+
+	// Sessions that never got as far as a test run
+	sessions, err := store.ListSessions(storage.ListFilter{})
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if session.Iterations > 0 || session.UpdatedAt.After(cutoff) {
+			continue
+		}
+		id := session.ID
+		if err := remove(store.SessionDir(id), func() error { return store.DeleteSession(id) }); err != nil {
+			return err
+		}
+	}
+
+	if count == 0 {
+		fmt.Println("Nothing to clean")
+		return nil
+	}
+	color.Green("%s %d items, %s", verb, count, formatBytes(reclaimed))
+	return nil
+}
+
+// diskUsage returns the total size of the regular files under path.
+func diskUsage(path string) int64 {
+	var size int64
+	filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// formatBytes renders a byte count with a binary unit suffix.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+
+	"github.com/prathyushnallamothu/aiterate/internal/ai"
+	"github.com/prathyushnallamothu/aiterate/pkg/aiterate"
+)
+
+// comparison holds the outcome of running the task with one model.
+type comparison struct {
+	model  string
+	result *aiterate.Result
+	err    error
+}
+
+// runComparison runs the same task with each model and prints a comparison
+// table. Each run gets its own workspace, session and output directory.
+func runComparison(ctx context.Context, opts aiterate.Options, models []string) error {
+	comparisons := make([]comparison, 0, len(models))
+	for _, m := range models {
+		color.Cyan("=== Running with model %s ===", m)
+		runOpts := opts
+		runOpts.Model = m
+		runOpts.OutputDirSuffix = "-" + sanitizeName(m)
+		runOpts.Observer = aiterate.EventFunc(printEvent)
+
+		result, err := aiterate.Generate(ctx, runOpts)
+		if err != nil && !errors.Is(err, aiterate.ErrNotConverged) {
+			color.Red("Run with model %s failed: %v", m, err)
+		}
+		comparisons = append(comparisons, comparison{
+			model:  m,
+			result: result,
+			err:    err,
+		})
+	}
+
+	printComparison(comparisons)
+
+	var errs []error
+	for _, c := range comparisons {
+		if c.err == nil && c.result != nil && c.result.Success {
+			return nil
+		}
+		errs = append(errs, c.err)
+	}
+	// Runs that failed before converging, e.g. on API or toolchain errors,
+	// exit with their own code rather than as not converged
+	if err := mostSevere(errs); err != nil && exitCode(err) != exitNotConverged {
+		return fmt.Errorf("no compared model passed: %w", err)
+	}
+	return fmt.Errorf("%w with any of the compared models", aiterate.ErrNotConverged)
+}
+
+func printComparison(comparisons []comparison) {
+	fmt.Println()
+	color.Cyan("Model comparison:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MODEL\tRESULT\tITERATIONS\tTOKENS\tCOST\tTIME\tSESSION")
+	for _, c := range comparisons {
+		status := "passed"
+		iterations, duration, session := "-", "-", "-"
+		var usage aiterate.Usage
+		if c.result != nil {
+			usage = c.result.Usage
+			iterations = fmt.Sprintf("%d", c.result.Iterations)
+			duration = c.result.Duration.Round(100 * time.Millisecond).String()
+			session = c.result.SessionID
+		}
+		switch {
+		case c.err != nil && errors.Is(c.err, aiterate.ErrNotConverged):
+			status = "failed"
+		case c.err != nil:
+			status = "error"
+		}
+
+		cost := "n/a"
+		if amount, ok := ai.EstimateCost(c.model, usage); ok {
+			cost = fmt.Sprintf("$%.4f", amount)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			c.model, status, iterations, usage.TotalTokens(), cost, duration, session)
+	}
+	w.Flush()
+}
+
+// sanitizeName converts a model name into a string safe for directory names.
+func sanitizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '.' {
+			return r
+		}
+		return '-'
+	}, name)
+}
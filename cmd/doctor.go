@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/prathyushnallamothu/aiterate/internal/ai"
+)
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the API key, provider and language toolchains",
+	Long: `Run a checklist of the things AIterate needs: an API key the provider
+accepts, network access to the provider, and the toolchain for each language.
+
+Every check runs regardless of the others. Toolchains are only needed for the
+languages you generate.`,
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+}
+
+// providerURL is probed to check network reachability of OpenAI; with
+// Azure OpenAI, the configured endpoint is probed instead
+const providerURL = "https://api.openai.com/v1/models"
+
+// doctorCheck is a single environment check.
+type doctorCheck struct {
+	name string
+	// required checks count towards the command's result
+	required bool
+	run      func() (detail string, err error)
+	hint     string
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	keyHint := "use --api-key-file, store the key in the OS keyring, or set OPENAI_API_KEY"
+	reachHint := "check your network connection and any proxy settings"
+	if cfg, err := aiConfig(); err == nil && cfg.Azure != nil {
+		keyHint = fmt.Sprintf("use --api-key-file, set %s, or store the key in the OS keyring", ai.AzureAPIKeyEnv)
+		reachHint = fmt.Sprintf("check %s, your network connection and any proxy settings", ai.AzureEndpointEnv)
+	}
+	checks := []doctorCheck{
+		{
+			name:     "API key",
+			required: true,
+			run:      checkAPIKey,
+			hint:     keyHint,
+		},
+		{
+			name:     "Provider reachable",
+			required: true,
+			run:      checkProvider,
+			hint:     reachHint,
+		},
+		toolCheck("Go", "install Go from https://go.dev/dl/", "go", "version"),
+		toolCheck("Python + pytest", "install Python 3, then pip install pytest", "python", "-m", "pytest", "--version"),
+		toolCheck("pip", "install pip for your Python", "pip", "--version"),
+		toolCheck("PHP + Composer", "install PHP and Composer from https://getcomposer.org/", "composer", "--version"),
+		toolCheck(".NET SDK", "install the .NET SDK from https://dotnet.microsoft.com/download", "dotnet", "--version"),
+		toolCheck("Gradle (Kotlin)", "install Gradle from https://gradle.org/install/", "gradle", "--version"),
+		toolCheck("Swift", "install Swift from https://www.swift.org/install/", "swift", "--version"),
+		toolCheck("Elixir + mix", "install Elixir from https://elixir-lang.org/install.html", "mix", "--version"),
+		toolCheck("Dart", "install the Dart SDK from https://dart.dev/get-dart", "dart", "--version"),
+		toolCheck("bats (Bash)", "install bats-core, e.g. brew install bats-core or apt install bats", "bats", "--version"),
+		toolCheck("Docker", "install Docker if you want to run tests in containers", "docker", "version", "--format", "{{.Server.Version}}"),
+	}
+
+	cmd.SilenceUsage = true
+
+	var failed int
+	for _, check := range checks {
+		detail, err := check.run()
+		switch {
+		case err == nil:
+			color.Green("✓ %s: %s", check.name, detail)
+		case check.required:
+			failed++
+			color.Red("✗ %s: %v", check.name, err)
+			fmt.Printf("    %s\n", check.hint)
+		default:
+			color.Yellow("- %s: %v", check.name, err)
+			fmt.Printf("    %s\n", check.hint)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d required check(s) failed", failed)
+	}
+	color.Green("Ready to go")
+	return nil
+}
+
+// toolCheck checks that a command runs, reporting the first line of its output.
+func toolCheck(name, hint, command string, args ...string) doctorCheck {
+	return doctorCheck{
+		name: name,
+		hint: hint,
+		run: func() (string, error) {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			out, err := exec.CommandContext(ctx, command, args...).CombinedOutput()
+			if err != nil {
+				if len(out) > 0 {
+					return "", fmt.Errorf("%s failed: %s", command, firstLine(string(out)))
+				}
+				return "", fmt.Errorf("%s failed: %w", command, err)
+			}
+			return firstLine(string(out)), nil
+		},
+	}
+}
+
+func checkAPIKey() (string, error) {
+	cfg, err := aiConfig()
+	if err != nil {
+		return "", err
+	}
+	client, err := ai.NewAIClient(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx); err != nil {
+		return "", err
+	}
+	return "found and accepted by " + cfg.Provider(), nil
+}
+
+func checkProvider() (string, error) {
+	cfg, err := aiConfig()
+	if err != nil {
+		return "", err
+	}
+	url := providerURL
+	if cfg.Azure != nil {
+		if url, err = cfg.Azure.ResolveEndpoint(); err != nil {
+			return "", err
+		}
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	// Without credentials the provider answers 401 or 404, which still proves it's reachable
+	return fmt.Sprintf("%s at %s (HTTP %d)", cfg.Provider(), url, resp.StatusCode), nil
+}
+
+// aiConfig builds the AI client configuration from the global flags.
+func aiConfig() (ai.Config, error) {
+	opts, err := baseOptions(ai.DefaultModel)
+	if err != nil {
+		return ai.Config{}, err
+	}
+	return ai.Config{
+		Model:      opts.Model,
+		APIKeyFile: opts.APIKeyFile,
+		Headers:    opts.Headers,
+		MaxRetries: -1,
+		Azure:      opts.Azure,
+	}, nil
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return strings.TrimSpace(s[:i])
+	}
+	return s
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/fatih/color"
+
+	"github.com/prathyushnallamothu/aiterate/pkg/aiterate"
+)
+
+// editTests lets the user review the generated tests, in $EDITOR when set,
+// and returns the tests to use for the rest of the run.
+func editTests(testCode, language string) (string, error) {
+	editor := strings.Fields(os.Getenv("EDITOR"))
+	if len(editor) == 0 {
+		color.Yellow("$EDITOR is not set. Generated tests:")
+		fmt.Println(testCode)
+		if !confirm("Use these tests?") {
+			return "", fmt.Errorf("generated tests rejected")
+		}
+		return testCode, nil
+	}
+
+	file, err := os.CreateTemp("", "aiterate-tests-*."+aiterate.FileExtension(language))
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file for editing: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.WriteString(testCode); err != nil {
+		file.Close()
+		return "", fmt.Errorf("failed to write tests for editing: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("failed to write tests for editing: %w", err)
+	}
+
+	color.Blue("Opening tests in %s...", editor[0])
+	cmd := exec.Command(editor[0], append(editor[1:], file.Name())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor exited with error: %w", err)
+	}
+
+	edited, err := os.ReadFile(file.Name())
+	if err != nil {
+		return "", fmt.Errorf("failed to read edited tests: %w", err)
+	}
+	if strings.TrimSpace(string(edited)) == "" {
+		return "", fmt.Errorf("edited tests are empty")
+	}
+	return string(edited), nil
+}
+
+// askFixHint prompts for optional guidance after a failing test run. An
+// empty answer lets the AI fix the failures on its own.
+func askFixHint(iteration int, result *aiterate.TestResult) (string, error) {
+	color.Yellow("Iteration %d failed (%d passed, %d failed).", iteration, result.Passed, result.Failed)
+	if assumeYes {
+		// Nothing to confirm; the AI fixes the failures on its own
+		return "", nil
+	}
+	fmt.Print("Hint for the next fix (press Enter to just fix it): ")
+	scanner := bufio.NewScanner(os.Stdin)
+	if !scanner.Scan() {
+		return "", scanner.Err()
+	}
+	return strings.TrimSpace(scanner.Text()), nil
+}
+
+// confirmModules lists Go modules about to be fetched and asks whether to
+// fetch them.
+func confirmModules(modules []string) (bool, error) {
+	color.Yellow("The code imports %d new module(s):", len(modules))
+	for _, module := range modules {
+		fmt.Printf("  %s\n", module)
+	}
+	return confirm("Fetch them?"), nil
+}
+
+// confirmRenames asks whether to apply the renames proposed with --describe-output.
+func confirmRenames(renames []aiterate.Rename) (bool, error) {
+	color.Yellow("The AI suggests %d rename(s):", len(renames))
+	for _, rename := range renames {
+		fmt.Printf("  %s\n", rename)
+	}
+	return confirm("Apply them?"), nil
+}
+
+// confirmTestChanges asks whether to accept a fix that removes many of the
+// tests' assertions; the diff has been shown already.
+func confirmTestChanges(change aiterate.TestChange) (bool, error) {
+	color.Yellow("Iteration %d's fix leaves %d of %d assertions.", change.Iteration, change.After, change.Before)
+	return confirm("Accept the rewritten tests?"), nil
+}
+
+// confirm asks a yes/no question on stdin, defaulting to no. With
+// --assume-yes it answers yes without reading stdin.
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N]: ", question)
+	if assumeYes {
+		fmt.Println("y (--assume-yes)")
+		return true
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+	if !scanner.Scan() {
+		return false
+	}
+	answer := strings.ToLower(strings.TrimSpace(scanner.Text()))
+	return answer == "y" || answer == "yes"
+}
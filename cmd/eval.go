@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/prathyushnallamothu/aiterate/internal/ai"
+	"github.com/prathyushnallamothu/aiterate/pkg/aiterate"
+)
+
+var (
+	evalFile            string
+	evalModel           string
+	evalLanguage        string
+	evalContinueOnError bool
+)
+
+func init() {
+	rootCmd.AddCommand(evalCmd)
+	evalCmd.Flags().StringVarP(&evalFile, "file", "f", "", "JSONL file with one task per line (required)")
+	evalCmd.Flags().StringVar(&evalModel, "model", aiterate.DefaultModel, "AI model to use")
+	evalCmd.Flags().StringVar(&evalLanguage, "language", "go", "Language for tasks that don't specify one")
+	// Failing fast is the default; the flag only makes the choice explicit
+	evalCmd.Flags().Bool("fail-fast", false, "Stop the batch at the first task that errors (default)")
+	evalCmd.Flags().BoolVar(&evalContinueOnError, "continue-on-error", false, "Record tasks that error and keep running the batch")
+	evalCmd.MarkFlagRequired("file")
+	evalCmd.MarkFlagsMutuallyExclusive("fail-fast", "continue-on-error")
+}
+
+var evalCmd = &cobra.Command{
+	Use:   "eval",
+	Short: "Run a batch of task descriptions and report aggregate results",
+	Long: `Run every task in a JSONL file through the generate/iterate pipeline and
+report the success rate, average iterations and total cost.
+
+Each line is a JSON object:
+  {"description": "reverse a string", "language": "go", "expected": "handles unicode"}
+
+"language" and "expected" are optional. Every task runs as an independent session.`,
+	Args: cobra.NoArgs,
+	RunE: runEval,
+}
+
+// evalTask is a single benchmark task read from the eval file.
+type evalTask struct {
+	Description string `json:"description"`
+	Language    string `json:"language,omitempty"`
+	Expected    string `json:"expected,omitempty"`
+}
+
+// evalOutcome records how one task went.
+type evalOutcome struct {
+	task   evalTask
+	result *aiterate.Result
+	err    error
+}
+
+func runEval(cmd *cobra.Command, args []string) error {
+	tasks, err := loadEvalTasks(evalFile, evalLanguage)
+	if err != nil {
+		return err
+	}
+
+	opts, err := baseOptions(evalModel)
+	if err != nil {
+		return err
+	}
+	opts.Observer = aiterate.EventFunc(printEvent)
+	if err := aiterate.CheckCredentials(opts); err != nil {
+		return fmt.Errorf("failed to initialize AI client: %w", err)
+	}
+
+	cmd.SilenceUsage = true
+
+	outcomes := make([]evalOutcome, 0, len(tasks))
+	for i, task := range tasks {
+		color.Cyan("=== Task %d/%d: %s ===", i+1, len(tasks), task.Description)
+
+		taskOpts := opts
+		taskOpts.Description = task.prompt()
+		taskOpts.Language = task.Language
+		result, err := aiterate.Generate(cmd.Context(), taskOpts)
+		outcomes = append(outcomes, evalOutcome{task: task, result: result, err: err})
+
+		if err != nil && !errors.Is(err, aiterate.ErrNotConverged) {
+			if !evalContinueOnError {
+				printEvalReport(outcomes, evalModel)
+				return fmt.Errorf("task %d failed: %w", i+1, err)
+			}
+			color.Red("Task %d errored (%s): %v", i+1, errorCategory(err), err)
+		}
+	}
+
+	printEvalReport(outcomes, evalModel)
+	return evalError(outcomes)
+}
+
+// evalError returns the error the batch exits with: the most severe error
+// of the tasks that errored, not converged when no task passed, or nil.
+func evalError(outcomes []evalOutcome) error {
+	var errs []error
+	var passed int
+	for _, o := range outcomes {
+		switch {
+		case o.err == nil:
+			passed++
+		case !errors.Is(o.err, aiterate.ErrNotConverged):
+			errs = append(errs, o.err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d of %d task(s) errored: %w", len(errs), len(outcomes), mostSevere(errs))
+	}
+	if passed == 0 {
+		return fmt.Errorf("%w for any of the %d task(s)", aiterate.ErrNotConverged, len(outcomes))
+	}
+	return nil
+}
+
+// prompt returns the description passed to the generator, including any
+// expected behavior from the task file.
+func (t evalTask) prompt() string {
+	if t.Expected == "" {
+		return t.Description
+	}
+	return fmt.Sprintf("%s\nExpected behavior: %s", t.Description, t.Expected)
+}
+
+func loadEvalTasks(path, defaultLanguage string) ([]evalTask, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open eval file: %w", err)
+	}
+	defer file.Close()
+
+	var tasks []evalTask
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var task evalTask
+		if err := json.Unmarshal([]byte(text), &task); err != nil {
+			return nil, fmt.Errorf("invalid task on line %d: %w", line, err)
+		}
+		if strings.TrimSpace(task.Description) == "" {
+			return nil, fmt.Errorf("task on line %d has no description", line)
+		}
+		task.Language = strings.ToLower(strings.TrimSpace(task.Language))
+		if task.Language == "" {
+			task.Language = defaultLanguage
+		}
+		if !aiterate.IsSupported(task.Language) {
+			return nil, fmt.Errorf("task on line %d has unsupported language: %s", line, task.Language)
+		}
+		tasks = append(tasks, task)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read eval file: %w", err)
+	}
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("no tasks found in %s", path)
+	}
+	return tasks, nil
+}
+
+func printEvalReport(outcomes []evalOutcome, model string) {
+	fmt.Println()
+	color.Cyan("Eval report (%s):", model)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tLANGUAGE\tRESULT\tITERATIONS\tTOKENS\tTIME\tDESCRIPTION")
+
+	var passed, notConverged, errored, iterations int
+	var total ai.Usage
+	for i, o := range outcomes {
+		status := "passed"
+		switch {
+		case o.err != nil && errors.Is(o.err, aiterate.ErrNotConverged):
+			status = "failed"
+			notConverged++
+		case o.err != nil:
+			status = "error (" + errorCategory(o.err) + ")"
+			errored++
+		default:
+			passed++
+		}
+
+		iters, duration := "-", "-"
+		var usage aiterate.Usage
+		if o.result != nil {
+			usage = o.result.Usage
+			iterations += o.result.Iterations
+			iters = fmt.Sprintf("%d", o.result.Iterations)
+			duration = o.result.Duration.Round(100 * time.Millisecond).String()
+		}
+		total.PromptTokens += usage.PromptTokens
+		total.CompletionTokens += usage.CompletionTokens
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			i+1, o.task.Language, status, iters, usage.TotalTokens(), duration, truncate(o.task.Description, 50))
+	}
+	w.Flush()
+
+	fmt.Println()
+	fmt.Printf("Success rate:       %d/%d (%.1f%%)\n", passed, len(outcomes), 100*float64(passed)/float64(len(outcomes)))
+	fmt.Printf("Did not converge:   %d\n", notConverged)
+	fmt.Printf("Errored:            %d\n", errored)
+	fmt.Printf("Average iterations: %.2f\n", float64(iterations)/float64(len(outcomes)))
+	fmt.Printf("Total tokens:       %d\n", total.TotalTokens())
+	if cost, ok := ai.EstimateCost(model, total); ok {
+		fmt.Printf("Total cost:         $%.4f\n", cost)
+	} else {
+		fmt.Printf("Total cost:         n/a (unknown pricing for %s)\n", model)
+	}
+
+	if errored > 0 {
+		fmt.Println()
+		color.Red("Errors:")
+		for i, o := range outcomes {
+			if o.err != nil && !errors.Is(o.err, aiterate.ErrNotConverged) {
+				fmt.Printf("  #%d: %v\n", i+1, o.err)
+			}
+		}
+	}
+}
+
+// truncate shortens s to at most n characters, marking the cut with "...".
+func truncate(s string, n int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-3]) + "..."
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/prathyushnallamothu/aiterate/internal/storage"
+)
+
+var (
+	listLanguage string
+	listSince    string
+	listStatus   string
+	listGrep     string
+	listRebuild  bool
+)
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+	listCmd.Flags().StringVar(&listLanguage, "language", "", "Only show sessions for this language")
+	listCmd.Flags().StringVar(&listSince, "since", "", "Only show sessions created within this period, e.g. 7d or 12h")
+	listCmd.Flags().StringVar(&listStatus, "status", "", "Only show sessions that passed or failed")
+	listCmd.Flags().StringVar(&listGrep, "grep", "", "Only show sessions whose description contains this text")
+	listCmd.Flags().BoolVar(&listRebuild, "rebuild-index", false, "Rebuild the session index from the full session data before listing")
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List past sessions, newest first",
+	Args:  cobra.NoArgs,
+	RunE:  runList,
+}
+
+func runList(cmd *cobra.Command, args []string) error {
+	filter := storage.ListFilter{
+		Language: strings.ToLower(strings.TrimSpace(listLanguage)),
+		Grep:     listGrep,
+	}
+
+	switch listStatus {
+	case "", storage.StatusPassed, storage.StatusFailed:
+		filter.Status = listStatus
+	default:
+		return fmt.Errorf("unsupported status: %s. Supported statuses: passed, failed", listStatus)
+	}
+
+	if listSince != "" {
+		period, err := parsePeriod(listSince)
+		if err != nil {
+			return err
+		}
+		filter.Since = time.Now().Add(-period)
+	}
+
+	store, err := openStorage()
+	if err != nil {
+		return err
+	}
+
+	if listRebuild {
+		if err := store.RebuildIndex(); err != nil {
+			return err
+		}
+	}
+
+	sessions, err := store.ListSessions(filter)
+	if err != nil {
+		return err
+	}
+	if len(sessions) == 0 {
+		fmt.Println("No sessions found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SESSION\tCREATED\tLANGUAGE\tMODEL\tRESULT\tITERATIONS\tDESCRIPTION")
+	for _, s := range sessions {
+		model := s.Model
+		if model == "" {
+			model = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n",
+			s.ID, s.CreatedAt.Format("2006-01-02 15:04"), s.Language, model, s.Status(), s.Iterations, truncate(s.Description, 50))
+	}
+	return w.Flush()
+}
+
+// parsePeriod parses a duration that may also be given in days, e.g. "7d".
+func parsePeriod(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid period %q: expected e.g. 7d or 12h", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	period, err := time.ParseDuration(value)
+	if err != nil || period < 0 {
+		return 0, fmt.Errorf("invalid period %q: expected e.g. 7d or 12h", value)
+	}
+	return period, nil
+}
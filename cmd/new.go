@@ -4,33 +4,167 @@ import (
 	"bufio"
 	"fmt"
 	"os"
-	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
 
-	"github.com/prathyushnallamothu/aiterate/internal/ai"
 	"github.com/prathyushnallamothu/aiterate/internal/executor"
 	"github.com/prathyushnallamothu/aiterate/internal/generator"
-	"github.com/prathyushnallamothu/aiterate/internal/storage"
+	"github.com/prathyushnallamothu/aiterate/pkg/aiterate"
 )
 
-const (
-	maxIterations = 5
-	storageDir    = ".aiterate"
+var (
+	useTUI        bool
+	testStyle     string
+	model         string
+	compareModels string
+	gradleDaemon  bool
+	editTestsFlag bool
+	strictTests   bool
+	maxTestLines  int
+	mutationTest  bool
+	testEnv       []string
+	maxDescLength int
+	truncateDesc  bool
+	packageDir    string
+	outputDir     string
+	noWrite       bool
+	dirRetries    int
+	goTestJSON    bool
+	explain       bool
+	languageFlag  string
+	vetFlag       bool
+	commitMessage bool
+	summaryOnly   bool
+	deps          []string
+	fuzz          bool
+	examples      bool
+	fuzzTime      time.Duration
+	interactFix   bool
+	implements    string
+	fixtures      []string
+	fileMode      string
+	withGitignore bool
+	runMain       bool
+	mainArgs      string
+	sessionWS     bool
+	keepWorkspace bool
+	reviewFlag    bool
+	reviewModel   string
+	goModCache    string
+	testFramework string
+	confirmDeps   bool
+	maxNewDeps    int
+	regenTests    bool
+	maxFileSize   int
+	reportFlag    bool
+	httpMode      bool
+	generics      bool
+	implPath      string
+	testsPath     string
+	fixStrategy   string
+	signature     string
+	allowedPkgs   string
+	candidates    int
+	tiebreak      string
+	multiFile     bool
+	maxFiles      int
+	stripMarkers  bool
+	describeOut   bool
+	plateau       int
+	testCommand   string
+	guardTests    bool
+	confirmTests  bool
+	conversation  bool
+	maxIterations int
+	testsFirst    bool
+	goVersion     string
+	reportFormat  string
+	reportFile    string
+	concurrent    bool
+	promptOnly    bool
 )
 
-// Supported languages
-var supportedLanguages = map[string]bool{
-	"go":     true,
-	"python": true,
-}
-
 func init() {
 	rootCmd.AddCommand(newCmd)
+	newCmd.Flags().StringVarP(&languageFlag, "language", "l", "", "Programming language to generate (prompted for when not set)")
+	newCmd.Flags().BoolVar(&useTUI, "tui", false, "Show a live terminal view of the iteration loop (falls back to plain output when not a TTY)")
+	newCmd.Flags().StringVar(&testStyle, "style", aiterate.StyleDefault, "Test style for Go: default or table (table-driven tests with t.Run subtests)")
+	newCmd.Flags().StringVar(&testFramework, "test-framework", "", "Test framework for Python: pytest (default) or unittest")
+	newCmd.Flags().StringVar(&model, "model", aiterate.DefaultModel, "AI model to use")
+	newCmd.Flags().StringVar(&compareModels, "compare-models", "", "Comma-separated list of models to run the same task with and compare")
+	newCmd.Flags().BoolVar(&editTestsFlag, "edit-tests", false, "Open the generated tests in $EDITOR before generating the implementation")
+	newCmd.Flags().BoolVar(&testsFirst, "tests-first-confirm", false, "Before generating the implementation, run the tests against an empty stub and ask the AI to strengthen them if they all pass")
+	newCmd.Flags().BoolVar(&strictTests, "strict-tests", false, "Reject generated tests with too few assertions and ask the AI to strengthen them")
+	newCmd.Flags().BoolVar(&mutationTest, "mutation", false, "After tests pass, check that they catch small deliberate bugs in the implementation (Go only)")
+	newCmd.Flags().StringArrayVar(&testEnv, "env", nil, "Environment variable for the test process, as KEY=VALUE (repeatable)")
+	newCmd.Flags().IntVar(&maxDescLength, "max-description-length", aiterate.DefaultMaxDescriptionLength, "Maximum description length in characters (negative for unlimited)")
+	newCmd.Flags().IntVar(&maxIterations, "max-iterations", 0, "Maximum test runs before giving up (0 for the language's default: 4 for statically typed languages, 6 for dynamically typed ones)")
+	newCmd.Flags().IntVar(&plateau, "plateau", 0, "Stop early when the number of passing tests hasn't improved for this many iterations, keeping the best iteration (0 to always run every iteration)")
+	newCmd.Flags().IntVar(&maxTestLines, "max-test-lines", 0, "Ask the AI to consolidate generated tests longer than this many lines into representative cases (0 for unlimited)")
+	newCmd.Flags().IntVar(&maxFileSize, "max-file-size", aiterate.DefaultMaxFileSize, "Maximum size in bytes of a generated implementation before it is rejected and retried (negative for unlimited)")
+	newCmd.Flags().BoolVar(&truncateDesc, "truncate-description", false, "Truncate descriptions over the length limit instead of rejecting them")
+	newCmd.Flags().StringVar(&outputDir, "output-dir", "", "Directory to write the final files to, instead of one named by the AI in the current directory")
+	newCmd.Flags().BoolVar(&noWrite, "no-write", false, "Keep the final files in the session only, without creating an output directory")
+	newCmd.Flags().IntVar(&dirRetries, "retry-on-empty-dir", 0, "Retry naming the output directory this many times when the AI fails, before falling back to a timestamped generated-function directory")
+	newCmd.Flags().StringVar(&packageDir, "append-to-existing-package", "", "Add the generated Go code to the package in this directory instead of a new directory")
+	newCmd.Flags().BoolVar(&guardTests, "guard-tests", false, "Warn and show the diff when a fix removes more than a quarter of the tests' assertions")
+	newCmd.Flags().BoolVar(&confirmTests, "confirm-test-changes", false, "Like --guard-tests, but ask before accepting such a fix's tests; rejecting keeps the previous tests")
+	newCmd.Flags().BoolVar(&conversation, "conversational", false, "Keep the fix requests and responses as a chat history, so each fix follows up on the earlier attempts instead of starting over")
+	newCmd.Flags().StringVar(&testCommand, "test-command", "", "Shell command that runs the tests in the workspace instead of the language's built-in one, e.g. \"make test\"; it passes when it exits with status 0")
+	newCmd.Flags().BoolVar(&goTestJSON, "go-json", false, "Run Go tests with -json for exact per-test pass, fail and skip counts")
+	newCmd.Flags().BoolVar(&explain, "explain", false, "After success, ask the AI to explain the final code and tests (saved as EXPLANATION.md)")
+	newCmd.Flags().BoolVar(&vetFlag, "vet", false, "After tests pass, run go vet and give the AI one fix iteration for any issues (Go only)")
+	newCmd.Flags().BoolVar(&commitMessage, "commit-message", false, "After success, ask the AI for a conventional-commit message for the code (saved as COMMIT_MSG)")
+	newCmd.Flags().BoolVar(&reportFlag, "report", false, "At the end, print each iteration's test counts and how much the code and tests changed")
+	newCmd.Flags().StringVar(&reportFormat, "report-format", "", "Also write the final summary as json or markdown (description, result, iterations and the final code), to stdout or --report-file")
+	newCmd.Flags().StringVar(&reportFile, "report-file", "", "File to write the --report-format summary to instead of stdout")
+	newCmd.Flags().BoolVar(&promptOnly, "prompt-only", false, "Print the test generation and implementation prompts for the description and exit, without calling the AI")
+	newCmd.Flags().BoolVar(&summaryOnly, "summary-only", false, "Suppress progress output and print only a final summary")
+	newCmd.Flags().StringArrayVar(&deps, "dep", nil, "Pin a dependency version, as module@version (repeatable; Go modules or Python packages)")
+	newCmd.Flags().BoolVar(&examples, "examples-as-tests", false, "Generate Example functions with // Output: comments alongside the tests, which go test runs and verifies (Go only)")
+	newCmd.Flags().BoolVar(&fuzz, "fuzz", false, "Generate fuzz targets and, after tests pass, fuzz them and fix any crash found (Go only)")
+	newCmd.Flags().StringVar(&goVersion, "go-version", "", "Go version the code must build with, e.g. 1.20: set in the workspace's go.mod, and features of later versions are avoided (Go only)")
+	newCmd.Flags().BoolVar(&generics, "generics", false, "Ask for type parameters where appropriate, with tests over several instantiations (Go only)")
+	newCmd.Flags().BoolVar(&concurrent, "concurrent", false, "Generate code safe for concurrent use, with tests calling it from many goroutines, and run the tests with -race (Go only)")
+	newCmd.Flags().BoolVar(&httpMode, "http", false, "Generate HTTP handlers with integration tests that serve them from an httptest.Server (Go only)")
+	newCmd.Flags().DurationVar(&fuzzTime, "fuzz-time", aiterate.DefaultFuzzTime, "How long to run each fuzz target with --fuzz")
+	newCmd.Flags().BoolVar(&interactFix, "interactive-fix", false, "After each failing test run, prompt for an optional hint to guide the next fix")
+	newCmd.Flags().StringVar(&implements, "implements", "", "Go file declaring an interface the generated code must implement (Go only)")
+	newCmd.Flags().StringArrayVar(&fixtures, "fixture", nil, "Test data file copied into testdata/ of the workspace and the output directory, where the tests can load it (repeatable)")
+	newCmd.Flags().StringVar(&fileMode, "file-mode", "", "Octal permissions for output files, e.g. 0600 (directories get matching search permission; default 0644)")
+	newCmd.Flags().BoolVar(&withGitignore, "with-gitignore", false, "Write a .gitignore for build artifacts and coverage files to the output directory")
+	newCmd.Flags().BoolVar(&runMain, "run-main", false, "After tests pass, run the program once and give the AI one fix iteration if it fails (Go, Python, PHP, Bash)")
+	newCmd.Flags().StringVar(&mainArgs, "args", "", "Whitespace-separated arguments for the program run by --run-main")
+	newCmd.Flags().BoolVar(&sessionWS, "session-workspace", false, "Create the workspace at $TMPDIR/aiterate/<session-id> instead of a random temp directory")
+	newCmd.Flags().BoolVar(&keepWorkspace, "keep-workspace", false, "Keep the workspace after the run for debugging")
+	newCmd.Flags().BoolVar(&reviewFlag, "review", false, "After tests pass, have the AI review the code and give it one fix iteration for serious findings")
+	newCmd.Flags().StringVar(&reviewModel, "review-model", "", "Model used for --review (defaults to --model)")
+	newCmd.Flags().BoolVar(&confirmDeps, "confirm-deps", false, "List new Go modules the code imports and ask before fetching them")
+	newCmd.Flags().IntVar(&maxNewDeps, "max-new-deps", 0, "Abort when the Go code imports more than this many new modules (0 for unlimited)")
+	newCmd.Flags().StringVar(&goModCache, "go-mod-cache", "", "Go module cache shared across runs (defaults to an AIterate cache directory unless GOMODCACHE is set)")
+	newCmd.Flags().BoolVar(&regenTests, "regen-tests", false, "Regenerate tests for the existing implementation given by --impl without changing it")
+	newCmd.Flags().StringVar(&implPath, "impl", "", "Existing implementation file for --regen-tests or --tests")
+	newCmd.Flags().StringVar(&testsPath, "tests", "", "Test file to fix the --impl implementation against (e.g. a failing bug reproduction) instead of generating tests")
+	newCmd.Flags().StringVar(&fixStrategy, "fix-strategy", aiterate.FixStrategyBoth, "What fixes may change: both (implementation and tests) or impl-only (tests stay unchanged)")
+	newCmd.Flags().StringVar(&allowedPkgs, "allowed-imports", "", `Comma-separated packages the Go code may import, "std" for the standard library, e.g. "std,github.com/google/uuid"; other imports are sent back to the AI to rewrite`)
+	newCmd.Flags().StringVar(&signature, "signature", "", `Function signature the code must declare, e.g. "func Divide(a, b float64) (float64, error)"; a warning is shown if the final code doesn't match`)
+	newCmd.Flags().IntVar(&candidates, "parallel-candidates", 1, "Generate this many initial implementations in parallel and continue with the best one")
+	newCmd.Flags().StringVar(&tiebreak, "tiebreak", aiterate.TiebreakSize, "How to choose among candidates that all pass: size (smallest code) or coverage (highest statement coverage, Go only)")
+	newCmd.Flags().BoolVar(&stripMarkers, "strip-markers", false, "Remove stray section markers, code fences and trailing prose from the code and tests as soon as the AI returns them")
+	newCmd.Flags().BoolVar(&multiFile, "multi-file-tests", false, "Split the generated tests across several named files, e.g. happy_path_test.go and edge_cases_test.go (Go and Python)")
+	newCmd.Flags().IntVar(&maxFiles, "max-files", aiterate.DefaultMaxFiles, "Maximum number of files the tests may be split into with --multi-file-tests")
+	newCmd.Flags().BoolVar(&describeOut, "describe-output", false, "After tests pass, ask the AI for clearer function, type and parameter names and apply them after confirmation")
+	newCmd.Flags().BoolVar(&gradleDaemon, "gradle-daemon", false, "Reuse a Gradle daemon across iterations for faster Kotlin builds")
 }
 
+// defaultFixDescription is the description used with --tests when none is given
+const defaultFixDescription = "Make the existing implementation pass the provided tests with a minimal fix"
+
 var newCmd = &cobra.Command{
 	Use:   "new",
 	Short: "Create a new function with AI-generated tests and implementation",
@@ -43,28 +177,284 @@ AIterate will:
 }
 
 func runNew(cmd *cobra.Command, args []string) error {
-	// Initialize components
-	aiClient, err := ai.NewAIClient()
-	if err != nil {
-		return fmt.Errorf("failed to initialize AI client: %w", err)
+	if testStyle != aiterate.StyleDefault && testStyle != aiterate.StyleTable {
+		return fmt.Errorf("unsupported test style: %s. Supported styles: default, table", testStyle)
 	}
 
-	testGen := generator.NewTestGenerator(aiClient)
-	codeGen := generator.NewCodeGenerator(aiClient)
-	
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
+	if testFramework != "" && testFramework != aiterate.FrameworkPytest && testFramework != aiterate.FrameworkUnittest {
+		return fmt.Errorf("unsupported test framework: %s. Supported frameworks: pytest, unittest", testFramework)
+	}
+
+	if err := validateEnv(testEnv); err != nil {
+		return err
+	}
+
+	for _, dep := range deps {
+		if _, err := executor.ParseDependency(dep); err != nil {
+			return err
+		}
+	}
+
+	if mainArgs != "" && !runMain {
+		return fmt.Errorf("--args requires --run-main")
+	}
+
+	if reviewModel != "" && !reviewFlag {
+		return fmt.Errorf("--review-model requires --review")
+	}
+
+	if regenTests && testsPath != "" {
+		return fmt.Errorf("--regen-tests cannot be combined with --tests")
+	}
+
+	if (regenTests || testsPath != "") != (implPath != "") {
+		return fmt.Errorf("--impl must be used with --regen-tests or --tests")
+	}
+
+	if testsPath != "" && (compareModels != "" || packageDir != "" || strictTests || testsFirst || editTestsFlag || examples) {
+		return fmt.Errorf("--tests cannot be combined with --compare-models, --append-to-existing-package, --strict-tests, --tests-first-confirm, --edit-tests or --examples-as-tests")
+	}
+
+	if multiFile && (packageDir != "" || regenTests || testsPath != "" || mutationTest || strictTests || testsFirst) {
+		return fmt.Errorf("--multi-file-tests cannot be combined with --append-to-existing-package, --regen-tests, --tests, --mutation, --strict-tests or --tests-first-confirm")
+	}
+
+	if maxFiles < 1 {
+		return fmt.Errorf("--max-files must be at least 1")
+	}
+
+	if cmd.Flags().Changed("max-files") && !multiFile {
+		return fmt.Errorf("--max-files requires --multi-file-tests")
+	}
+
+	if candidates < 1 {
+		return fmt.Errorf("--parallel-candidates must be at least 1")
+	}
+
+	if candidates > 1 && (regenTests || testsPath != "") {
+		return fmt.Errorf("--parallel-candidates cannot be combined with --regen-tests or --tests")
+	}
+
+	if tiebreak != aiterate.TiebreakSize && tiebreak != aiterate.TiebreakCoverage {
+		return fmt.Errorf("unsupported tie-break: %s. Supported tie-breaks: %s, %s", tiebreak, aiterate.TiebreakSize, aiterate.TiebreakCoverage)
+	}
+
+	if fixStrategy != aiterate.FixStrategyBoth && fixStrategy != aiterate.FixStrategyImplOnly {
+		return fmt.Errorf("unsupported fix strategy: %s. Supported strategies: %s, %s", fixStrategy, aiterate.FixStrategyBoth, aiterate.FixStrategyImplOnly)
+	}
+
+	if fixStrategy == aiterate.FixStrategyImplOnly && mutationTest {
+		return fmt.Errorf("--fix-strategy %s cannot be combined with --mutation", aiterate.FixStrategyImplOnly)
+	}
+	if fixStrategy == aiterate.FixStrategyImplOnly && (guardTests || confirmTests) {
+		return fmt.Errorf("--fix-strategy %s cannot be combined with --guard-tests or --confirm-test-changes, since the tests stay unchanged", aiterate.FixStrategyImplOnly)
+	}
+
+	if regenTests && (compareModels != "" || packageDir != "" || implements != "" || vetFlag || fuzz || runMain || reviewFlag || allowedPkgs != "") {
+		return fmt.Errorf("--regen-tests cannot be combined with --compare-models, --append-to-existing-package, --implements, --vet, --fuzz, --run-main, --review or --allowed-imports")
+	}
+
+	if promptOnly && (useTUI || summaryOnly || compareModels != "" || testsPath != "" || reportFormat != "") {
+		return fmt.Errorf("--prompt-only cannot be combined with --tui, --summary-only, --compare-models, --tests or --report-format")
+	}
+	switch reportFormat {
+	case "", reportJSON, reportMarkdown:
+	default:
+		return fmt.Errorf("--report-format must be json or markdown")
+	}
+	if reportFile != "" && reportFormat == "" {
+		return fmt.Errorf("--report-file requires --report-format")
+	}
+	if reportFormat != "" && (useTUI || compareModels != "") {
+		return fmt.Errorf("--report-format cannot be combined with --tui or --compare-models")
+	}
+	if reportFlag && (useTUI || compareModels != "") {
+		return fmt.Errorf("--report cannot be combined with --tui or --compare-models")
+	}
+
+	if editTestsFlag && useTUI {
+		return fmt.Errorf("--edit-tests cannot be combined with --tui")
+	}
+
+	if interactFix && (useTUI || compareModels != "") {
+		return fmt.Errorf("--interactive-fix cannot be combined with --tui or --compare-models")
+	}
+
+	if describeOut && (useTUI || summaryOnly || compareModels != "") {
+		return fmt.Errorf("--describe-output cannot be combined with --tui, --summary-only or --compare-models")
+	}
+
+	if describeOut && (regenTests || testsPath != "") {
+		return fmt.Errorf("--describe-output cannot be combined with --regen-tests or --tests")
+	}
+
+	if confirmDeps && (useTUI || compareModels != "") {
+		return fmt.Errorf("--confirm-deps cannot be combined with --tui or --compare-models")
+	}
+
+	if confirmTests && (useTUI || compareModels != "") {
+		return fmt.Errorf("--confirm-test-changes cannot be combined with --tui or --compare-models")
+	}
+
+	if summaryOnly && (useTUI || editTestsFlag || interactFix || confirmDeps || confirmTests || compareModels != "") {
+		return fmt.Errorf("--summary-only cannot be combined with --tui, --edit-tests, --interactive-fix, --confirm-deps, --confirm-test-changes or --compare-models")
+	}
+
+	if maxNewDeps < 0 {
+		return fmt.Errorf("--max-new-deps must not be negative")
+	}
+
+	if maxTestLines < 0 {
+		return fmt.Errorf("--max-test-lines must not be negative")
+	}
+	if maxIterations < 0 {
+		return fmt.Errorf("--max-iterations must not be negative")
+	}
+	if plateau < 0 {
+		return fmt.Errorf("--plateau must not be negative")
+	}
+	if testCommand != "" && goTestJSON {
+		return fmt.Errorf("--test-command cannot be combined with --go-json")
+	}
+	if dirRetries < 0 {
+		return fmt.Errorf("--retry-on-empty-dir must not be negative")
+	}
+	if outputDir != "" && (compareModels != "" || packageDir != "") {
+		return fmt.Errorf("--output-dir cannot be combined with --compare-models or --append-to-existing-package")
+	}
+
+	if noWrite && (outputDir != "" || packageDir != "" || withGitignore) {
+		return fmt.Errorf("--no-write cannot be combined with --output-dir, --append-to-existing-package or --with-gitignore")
+	}
+
+	if modelFallback != "" && compareModels != "" {
+		return fmt.Errorf("--model-fallback cannot be combined with --compare-models")
 	}
-	
-	store, err := storage.NewStorage(filepath.Join(homeDir, storageDir))
+
+	models := []string{model}
+	if compareModels != "" {
+		models = splitList(compareModels)
+		if len(models) == 0 {
+			return fmt.Errorf("--compare-models requires at least one model")
+		}
+	}
+
+	opts, err := baseOptions(model)
 	if err != nil {
-		return fmt.Errorf("failed to initialize storage: %w", err)
+		return err
+	}
+	opts.TestStyle = testStyle
+	opts.TestFramework = testFramework
+	opts.Env = testEnv
+	opts.GradleDaemon = gradleDaemon
+	opts.StrictTests = strictTests
+	opts.TestsFirst = testsFirst
+	opts.MaxTestLines = maxTestLines
+	opts.MaxIterations = maxIterations
+	opts.Plateau = plateau
+	opts.Conversational = conversation
+	opts.MultiFileTests = multiFile
+	if multiFile {
+		opts.MaxFiles = maxFiles
+	}
+	opts.StripMarkers = stripMarkers
+	opts.MutationTest = mutationTest
+	opts.MaxDescriptionLength = maxDescLength
+	opts.TruncateDescription = truncateDesc
+	opts.MaxFileSize = maxFileSize
+	opts.PackageDir = packageDir
+	opts.OutputDir = outputDir
+	opts.NoWrite = noWrite
+	opts.DirNameRetries = dirRetries
+	opts.GoTestJSON = goTestJSON
+	opts.TestCommand = testCommand
+	opts.Explain = explain
+	opts.Vet = vetFlag
+	opts.CommitMessage = commitMessage
+	opts.Deps = deps
+	opts.Fuzz = fuzz
+	opts.Examples = examples
+	opts.HTTP = httpMode
+	opts.Concurrent = concurrent
+	opts.Generics = generics
+	opts.GoVersion = goVersion
+	opts.Implements = implements
+	opts.Fixtures = fixtures
+	opts.Signature = signature
+	opts.AllowedImports = splitList(allowedPkgs)
+	opts.Candidates = candidates
+	opts.Tiebreak = tiebreak
+	opts.Gitignore = withGitignore
+	opts.RunMain = runMain
+	opts.SessionWorkspace = sessionWS
+	opts.Review = reviewFlag
+	opts.GoModCache = goModCache
+	opts.MaxNewDeps = maxNewDeps
+	if confirmDeps {
+		opts.ConfirmDeps = confirmModules
+	}
+	if guardTests || confirmTests {
+		opts.GuardTests = true
+	}
+	if confirmTests {
+		opts.ConfirmTestChanges = confirmTestChanges
+	}
+	if describeOut {
+		opts.DescribeOutput = true
+		opts.ConfirmRenames = confirmRenames
+	}
+	opts.ReviewModel = reviewModel
+	opts.KeepWorkspace = keepWorkspace
+	opts.MainArgs = strings.Fields(mainArgs)
+	if fileMode != "" {
+		mode, err := parseFileMode(fileMode)
+		if err != nil {
+			return err
+		}
+		opts.FileMode = mode
+	}
+	opts.FuzzTime = fuzzTime
+	if regenTests {
+		code, err := os.ReadFile(implPath)
+		if err != nil {
+			return fmt.Errorf("--impl: %w", err)
+		}
+		opts.RegenerateTests = true
+		opts.Implementation = string(code)
+	}
+	if testsPath != "" {
+		code, err := os.ReadFile(implPath)
+		if err != nil {
+			return fmt.Errorf("--impl: %w", err)
+		}
+		tests, err := os.ReadFile(testsPath)
+		if err != nil {
+			return fmt.Errorf("--tests: %w", err)
+		}
+		opts.Implementation = string(code)
+		opts.Tests = string(tests)
+	}
+	opts.FixStrategy = fixStrategy
+	if editTestsFlag {
+		opts.ReviewTests = editTests
+	}
+	if interactFix {
+		opts.FixHint = askFixHint
+	}
+
+	// Fail before prompting for input when there's no key to use
+	if !promptOnly {
+		if err := aiterate.CheckCredentials(opts); err != nil {
+			return fmt.Errorf("failed to initialize AI client: %w", err)
+		}
 	}
 
 	var description string
 	if len(args) > 0 {
 		description = args[0]
+	} else if testsPath != "" {
+		// The tests say what the code must do
+		description = defaultFixDescription
 	} else {
 		fmt.Print("Enter a description of the function you want to create: ")
 		scanner := bufio.NewScanner(os.Stdin)
@@ -76,13 +466,18 @@ func runNew(cmd *cobra.Command, args []string) error {
 	if description == "" {
 		return fmt.Errorf("description is required")
 	}
+	if _, _, err := generator.PrepareDescription(description, maxDescLength, truncateDesc); err != nil {
+		return err
+	}
 
 	// Get programming language
-	fmt.Print("Enter the programming language (e.g., go, python): ")
-	scanner := bufio.NewScanner(os.Stdin)
-	var language string
-	if scanner.Scan() {
-		language = strings.ToLower(strings.TrimSpace(scanner.Text()))
+	language := strings.ToLower(strings.TrimSpace(languageFlag))
+	if language == "" {
+		fmt.Print("Enter the programming language (e.g., go, python, php, csharp, kotlin, swift, bash, elixir, dart): ")
+		scanner := bufio.NewScanner(os.Stdin)
+		if scanner.Scan() {
+			language = strings.ToLower(strings.TrimSpace(scanner.Text()))
+		}
 	}
 
 	if language == "" {
@@ -90,199 +485,164 @@ func runNew(cmd *cobra.Command, args []string) error {
 	}
 
 	// Validate language
-	if !supportedLanguages[language] {
-		return fmt.Errorf("unsupported language: %s. Supported languages: go, python", language)
+	if !aiterate.IsSupported(language) {
+		return fmt.Errorf("unsupported language: %s. Supported languages: %s", language, aiterate.SupportedLanguageNames())
 	}
 
-	// Create new session
-	session, err := store.CreateSession(description, language)
-	if err != nil {
-		return fmt.Errorf("failed to create session: %w", err)
+	if mutationTest && language != "go" {
+		return fmt.Errorf("--mutation is only supported for Go")
 	}
 
-	// Create test runner with temporary workspace
-	runner := executor.NewTestRunner("")
-	workDir, err := runner.PrepareWorkspace(language)
-	if err != nil {
-		return fmt.Errorf("failed to prepare workspace: %w", err)
+	if vetFlag && language != "go" {
+		return fmt.Errorf("--vet is only supported for Go")
+	}
+	if allowedPkgs != "" && language != "go" {
+		return fmt.Errorf("--allowed-imports is only supported for Go")
 	}
-	defer os.RemoveAll(workDir)
-	runner = executor.NewTestRunner(workDir)
 
-	// Create output directory with AI-generated name
-	outputDirName, err := codeGen.GenerateDirectoryName(description)
-	if err != nil {
-		outputDirName = "generated-function" // fallback name
+	if goVersion != "" && language != "go" {
+		return fmt.Errorf("--go-version is only supported for Go")
 	}
-	
-	// Create the output directory
-	outputDir := filepath.Join(".", outputDirName)
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+	if generics && language != "go" {
+		return fmt.Errorf("--generics is only supported for Go")
 	}
-	color.Blue("Created output directory: %s", outputDir)
 
-	// Generate tests
-	color.Blue("Generating tests...")
-	testCode, err := testGen.GenerateTests(description, language)
-	if err != nil {
-		return fmt.Errorf("failed to generate tests: %w", err)
+	if concurrent && language != "go" {
+		return fmt.Errorf("--concurrent is only supported for Go")
 	}
 
-	// Generate initial implementation
-	color.Blue("Generating initial implementation...")
-	code, err := codeGen.GenerateImplementation(description, testCode, language)
-	if err != nil {
-		return fmt.Errorf("failed to generate implementation: %w", err)
+	if httpMode && language != "go" {
+		return fmt.Errorf("--http is only supported for Go")
 	}
 
-	// Save test and implementation files
-	if err := writeFiles(workDir, testCode, code, language); err != nil {
-		return fmt.Errorf("failed to write files: %w", err)
+	if multiFile && language != "go" && language != "python" {
+		return fmt.Errorf("--multi-file-tests is only supported for Go and Python")
 	}
 
-	// Iteration loop
-	var success bool
-	var lastTestOutput string
-	for i := 0; i < maxIterations; i++ {
-		color.Blue("Running tests (iteration %d/%d)...", i+1, maxIterations)
-		
-		result, err := runner.RunTests(language)
-		if err != nil {
-			return fmt.Errorf("failed to run tests: %w", err)
-		}
+	if tiebreak == aiterate.TiebreakCoverage && language != "go" {
+		return fmt.Errorf("--tiebreak coverage is only supported for Go")
+	}
 
-		lastTestOutput = result.Output
-		// Store iteration
-		if err := store.AddIteration(session.ID, testCode, code, result.Output, result.Success); err != nil {
-			return fmt.Errorf("failed to store iteration: %w", err)
-		}
+	if examples && language != "go" {
+		return fmt.Errorf("--examples-as-tests is only supported for Go")
+	}
+	if fuzz && language != "go" {
+		return fmt.Errorf("--fuzz is only supported for Go")
+	}
 
-		if result.Success {
-			success = true
-			color.Green("All tests passed!")
-			break
-		}
+	if testFramework != "" && language != "python" {
+		return fmt.Errorf("--test-framework is only supported for Python")
+	}
 
-		color.Yellow("Tests failed. Test output:")
-		fmt.Println(result.Output)
-		color.Yellow("Attempting to fix implementation and tests...")
-		
-		// Fix both implementation and tests
-		fixResult, err := codeGen.FixBoth(code, testCode, result.Output, language)
-		if err != nil {
-			return fmt.Errorf("failed to fix code: %w", err)
+	if runMain && !executor.CanRunMain(language) {
+		return fmt.Errorf("--run-main is only supported for Go, Python, PHP and Bash")
+	}
+
+	if implements != "" {
+		if language != "go" {
+			return fmt.Errorf("--implements is only supported for Go")
+		}
+		if _, err := os.Stat(implements); err != nil {
+			return fmt.Errorf("--implements: %w", err)
 		}
+	}
+
+	if len(deps) > 0 && language != "go" && language != "python" {
+		return fmt.Errorf("--dep is only supported for Go and Python")
+	}
 
-		// Update both files
-		code = fixResult.Code
-		testCode = fixResult.TestCode
-		
-		if err := writeFiles(workDir, testCode, code, language); err != nil {
-			return fmt.Errorf("failed to write files: %w", err)
+	if packageDir != "" {
+		if language != "go" {
+			return fmt.Errorf("--append-to-existing-package is only supported for Go")
+		}
+		if _, err := aiterate.DetectGoPackage(packageDir); err != nil {
+			return err
+		}
+		if compareModels != "" {
+			return fmt.Errorf("--append-to-existing-package cannot be combined with --compare-models")
 		}
 	}
 
-	// Always copy files, even if tests didn't pass
-	if err := copyFinalFiles(workDir, outputDir, language); err != nil {
-		return fmt.Errorf("failed to copy final files: %w", err)
+	// Input is valid; failures from here on aren't usage errors
+	cmd.SilenceUsage = true
+
+	opts.Description = description
+	opts.Language = language
+
+	if promptOnly {
+		return printPrompts(opts)
 	}
 
-	if !success {
-		color.Red("Failed to generate passing implementation after %d iterations", maxIterations)
-		color.Yellow("Last test output:")
-		fmt.Println(lastTestOutput)
-		color.Yellow("Files have been saved to: %s", outputDir)
-	} else {
-		color.Green("Successfully generated code! Check %s for the files.", outputDir)
+	if compareModels != "" {
+		return runComparison(cmd.Context(), opts, models)
 	}
-	return nil
-}
 
-func getFileExtension(language string) string {
-	switch language {
-	case "python":
-		return "py"
-	case "go":
-		return "go"
-	default:
-		return ""
+	if useTUI && isatty.IsTerminal(os.Stdout.Fd()) {
+		return runWithTUI(cmd.Context(), opts)
 	}
-}
 
-func writeFiles(dir, testCode, code, language string) error {
-	color.Blue("Writing files to temporary directory: %s", dir)
-	
-	ext := getFileExtension(language)
-	if ext == "" {
-		return fmt.Errorf("unsupported language: %s", language)
-	}
-	
-	// Write test file
-	testFile := filepath.Join(dir, fmt.Sprintf("main_test.%s", ext))
-	color.Blue("Writing test file: %s", testFile)
-	if err := os.WriteFile(testFile, []byte(testCode), 0644); err != nil {
-		return fmt.Errorf("failed to write test file: %w", err)
-	}
-
-	// Write implementation file
-	implFile := filepath.Join(dir, fmt.Sprintf("main.%s", ext))
-	color.Blue("Writing implementation file: %s", implFile)
-	if err := os.WriteFile(implFile, []byte(code), 0644); err != nil {
-		return fmt.Errorf("failed to write implementation file: %w", err)
-	}
-
-	// Update dependencies if it's a Go project
-	if language == "go" {
-		runner := executor.NewTestRunner(dir)
-		if err := runner.UpdateDependencies(code, testCode); err != nil {
-			return fmt.Errorf("failed to update dependencies: %w", err)
+	var result *aiterate.Result
+	if summaryOnly {
+		result, err = runSummaryOnly(cmd.Context(), opts)
+	} else {
+		opts.Observer = aiterate.EventFunc(printEvent)
+		result, err = aiterate.Generate(cmd.Context(), opts)
+	}
+	if reportFlag && result != nil {
+		if reportErr := printIterationReport(result.SessionID); reportErr != nil {
+			color.Yellow("Failed to build the iteration report: %v", reportErr)
+		}
+	}
+	if reportFormat != "" {
+		if reportErr := writeSummaryReport(reportFormat, reportFile, opts, result, err); reportErr != nil {
+			if err == nil {
+				return reportErr
+			}
+			color.Yellow("%v", reportErr)
 		}
 	}
+	return err
+}
 
+// printPrompts prints the prompts a run with opts would send first.
+func printPrompts(opts aiterate.Options) error {
+	prompts, err := aiterate.BuildPrompts(opts)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("=== Tests prompt ===\n%s\n", prompts.Tests)
+	if prompts.Implementation != "" {
+		fmt.Printf("\n=== Implementation prompt ===\n%s\n", prompts.Implementation)
+	}
 	return nil
 }
 
-func writeImplementation(dir, code, language string) error {
-	ext := getFileExtension(language)
-	if ext == "" {
-		return fmt.Errorf("unsupported language: %s", language)
+// parseFileMode parses octal file permissions like 0600.
+func parseFileMode(value string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode == 0 || mode > uint64(os.ModePerm) {
+		return 0, fmt.Errorf("invalid --file-mode %q: expected octal permissions like 0644", value)
 	}
-	
-	implFile := filepath.Join(dir, fmt.Sprintf("main.%s", ext))
-	color.Blue("Updating implementation file: %s", implFile)
-	return os.WriteFile(implFile, []byte(code), 0644)
+	return os.FileMode(mode), nil
 }
 
-func copyFinalFiles(srcDir, dstDir, language string) error {
-	color.Blue("Copying files from %s to %s", srcDir, dstDir)
-	
-	ext := getFileExtension(language)
-	if ext == "" {
-		return fmt.Errorf("unsupported language: %s", language)
-	}
-	
-	files := []string{
-		fmt.Sprintf("main_test.%s", ext),
-		fmt.Sprintf("main.%s", ext),
-	}
-
-	for _, file := range files {
-		src := filepath.Join(srcDir, file)
-		dst := filepath.Join(dstDir, file)
-		
-		color.Blue("Reading from: %s", src)
-		data, err := os.ReadFile(src)
-		if err != nil {
-			return fmt.Errorf("failed to read %s: %w", file, err)
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
 		}
-		
-		color.Blue("Writing to: %s", dst)
-		if err := os.WriteFile(dst, data, 0644); err != nil {
-			return fmt.Errorf("failed to write %s: %w", file, err)
-		}
-		color.Green("Successfully copied %s", file)
 	}
+	return items
+}
 
+// validateEnv checks that --env values are KEY=VALUE pairs.
+func validateEnv(env []string) error {
+	for _, kv := range env {
+		if key, _, ok := strings.Cut(kv, "="); !ok || key == "" {
+			return fmt.Errorf("invalid --env value %q: expected KEY=VALUE", kv)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+
+	"github.com/prathyushnallamothu/aiterate/pkg/aiterate"
+)
+
+// printEvent prints a progress event as a colored log line.
+func printEvent(event aiterate.Event) {
+	switch event.Kind {
+	case aiterate.EventInfo:
+		color.Blue("%s", event.Message)
+	case aiterate.EventWarn:
+		color.Yellow("%s", event.Message)
+	case aiterate.EventSuccess:
+		color.Green("%s", event.Message)
+	case aiterate.EventFailure:
+		color.Red("%s", event.Message)
+	case aiterate.EventOutput:
+		fmt.Println(event.Message)
+	case aiterate.EventIterationStart:
+		color.Blue("Running tests (iteration %d/%d)...", event.Iteration, event.Max)
+	case aiterate.EventTestResult:
+		if !event.Result.Success {
+			color.Yellow("Tests failed. Test output:")
+			fmt.Println(event.Result.Output)
+		}
+	}
+}
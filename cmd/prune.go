@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/prathyushnallamothu/aiterate/internal/storage"
+)
+
+var (
+	pruneKeep      int
+	pruneOlderThan string
+	pruneDryRun    bool
+)
+
+func init() {
+	rootCmd.AddCommand(pruneCmd)
+	pruneCmd.Flags().IntVar(&pruneKeep, "keep", 0, "Keep this many of the most recent sessions and remove the rest")
+	pruneCmd.Flags().StringVar(&pruneOlderThan, "older-than", "", "Remove sessions not updated for this long, e.g. 30d or 720h")
+	pruneCmd.Flags().BoolVar(&pruneDryRun, "dry-run", false, "Show what would be removed without deleting anything")
+}
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove old sessions from the session store",
+	Long: `Remove sessions beyond the --keep most recent ones, or not updated for
+longer than --older-than, to bound the disk space used by the session store.
+
+Runs can apply the same policy automatically with --keep-sessions and
+--max-session-age.`,
+	Args: cobra.NoArgs,
+	RunE: runPrune,
+}
+
+func runPrune(cmd *cobra.Command, args []string) error {
+	if pruneKeep < 0 {
+		return fmt.Errorf("--keep must not be negative")
+	}
+	policy := storage.RetentionPolicy{Keep: pruneKeep}
+	if pruneOlderThan != "" {
+		age, err := parsePeriod(pruneOlderThan)
+		if err != nil {
+			return err
+		}
+		policy.MaxAge = age
+	}
+	if policy.IsZero() {
+		return fmt.Errorf("prune requires --keep or --older-than")
+	}
+
+	store, err := openStorage()
+	if err != nil {
+		return err
+	}
+
+	cmd.SilenceUsage = true
+
+	sessions, err := store.Expired(policy)
+	if err != nil {
+		return err
+	}
+	if len(sessions) == 0 {
+		fmt.Println("Nothing to prune")
+		return nil
+	}
+
+	verb := "Removed"
+	if pruneDryRun {
+		verb = "Would remove"
+	}
+	var reclaimed int64
+	for _, session := range sessions {
+		size := diskUsage(store.SessionDir(session.ID))
+		if !pruneDryRun {
+			if err := store.DeleteSession(session.ID); err != nil {
+				return err
+			}
+		}
+		color.Blue("%s session %s from %s: %s (%s)", verb, session.ID, session.CreatedAt.Format("2006-01-02"), truncate(session.Description, 50), formatBytes(size))
+		reclaimed += size
+	}
+	color.Green("%s %d sessions, %s", verb, len(sessions), formatBytes(reclaimed))
+	return nil
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/prathyushnallamothu/aiterate/internal/storage"
+	"github.com/prathyushnallamothu/aiterate/pkg/aiterate"
+)
+
+// printIterationReport prints, for each stored iteration of a session, the
+// test counts and how much the implementation and tests changed since the
+// previous iteration.
+func printIterationReport(sessionID string) error {
+	store, err := openStorage()
+	if err != nil {
+		return err
+	}
+	session, err := store.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if len(session.Iterations) == 0 {
+		return nil
+	}
+
+	fmt.Println("\nIteration report:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ITERATION\tRESULT\tPASSED\tFAILED\tCHANGES")
+	var previous storage.Iteration
+	for i, iteration := range session.Iterations {
+		result := storage.StatusFailed
+		if iteration.Success {
+			result = storage.StatusPassed
+		}
+		changes := "initial code"
+		if i > 0 {
+			changes = describeChanges(previous, iteration)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%s\n", iteration.Number, result, iteration.Passed, iteration.Failed, changes)
+		previous = iteration
+	}
+	return w.Flush()
+}
+
+// describeChanges summarizes the line changes between two iterations.
+func describeChanges(previous, current storage.Iteration) string {
+	var parts []string
+	if added, removed := aiterate.LineChanges(previous.Code, current.Code); added+removed > 0 {
+		parts = append(parts, fmt.Sprintf("impl +%d -%d", added, removed))
+	}
+	if added, removed := aiterate.LineChanges(previous.TestCode, current.TestCode); added+removed > 0 {
+		parts = append(parts, fmt.Sprintf("tests +%d -%d", added, removed))
+	}
+	if len(parts) == 0 {
+		return "no changes"
+	}
+	return strings.Join(parts, ", ")
+}
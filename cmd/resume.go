@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/prathyushnallamothu/aiterate/pkg/aiterate"
+)
+
+var (
+	resumeIteration int
+	resumeModel     string
+	resumeOutputDir string
+	resumeNoWrite   bool
+	resumeMaxIter   int
+	resumeEnv       []string
+)
+
+func init() {
+	rootCmd.AddCommand(resumeCmd)
+	resumeCmd.Flags().IntVar(&resumeIteration, "from-iteration", 0, "Iteration to continue from, counting from 1 (0 for the last one)")
+	resumeCmd.Flags().StringVar(&resumeModel, "model", "", "AI model to use (defaults to the session's model)")
+	resumeCmd.Flags().StringVar(&resumeOutputDir, "output-dir", "", "Directory to write the final files to, instead of one named by the AI in the current directory")
+	resumeCmd.Flags().IntVar(&resumeMaxIter, "max-iterations", 0, "Maximum test runs of the resumed run before giving up (0 for the language's default)")
+	resumeCmd.Flags().BoolVar(&resumeNoWrite, "no-write", false, "Keep the final files in the new session only, without creating an output directory")
+	resumeCmd.Flags().StringArrayVar(&resumeEnv, "env", nil, "Environment variable for the test process, as KEY=VALUE; every --env of the session's run must be given again, as values aren't stored (repeatable)")
+}
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume <session-id>",
+	Short: "Continue iterating from a stored session",
+	Long: `Rebuild the workspace from the tests and code of a stored session's
+iteration and continue the fix loop from there, with the options the
+session's run was started with, such as its test framework, fix strategy,
+interface file and fixtures.
+
+By default the last iteration is used; --from-iteration branches from an
+earlier one, e.g. when later iterations went off the rails. The resumed run
+is recorded as a new session, so the original is kept as it is.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runResume,
+}
+
+func runResume(cmd *cobra.Command, args []string) error {
+	if resumeIteration < 0 {
+		return fmt.Errorf("--from-iteration must not be negative")
+	}
+	if resumeMaxIter < 0 {
+		return fmt.Errorf("--max-iterations must not be negative")
+	}
+	if resumeNoWrite && resumeOutputDir != "" {
+		return fmt.Errorf("--no-write cannot be combined with --output-dir")
+	}
+	if err := validateEnv(resumeEnv); err != nil {
+		return err
+	}
+
+	store, err := openStorage()
+	if err != nil {
+		return err
+	}
+	session, err := store.GetSession(args[0])
+	if err != nil {
+		return err
+	}
+	if len(session.Iterations) == 0 {
+		return fmt.Errorf("session %s has no iterations to resume from", session.ID)
+	}
+	number := resumeIteration
+	if number == 0 {
+		number = len(session.Iterations)
+	}
+	if number > len(session.Iterations) {
+		return fmt.Errorf("--from-iteration %d is out of range: session %s has %d iteration(s)", number, session.ID, len(session.Iterations))
+	}
+	iteration := session.Iterations[number-1]
+	if !aiterate.IsSupported(session.Language) {
+		return fmt.Errorf("unsupported language: %s. Supported languages: %s", session.Language, aiterate.SupportedLanguageNames())
+	}
+
+	model := resumeModel
+	if model == "" {
+		model = session.Model
+	}
+	if model == "" {
+		model = aiterate.DefaultModel
+	}
+	opts, err := baseOptions(model)
+	if err != nil {
+		return err
+	}
+	opts.Description = session.Description
+	opts.Language = session.Language
+	opts.Tests = iteration.TestCode
+	opts.Implementation = iteration.Code
+	opts.ResumedFrom = &aiterate.ResumePoint{Session: session.ID, Iteration: number}
+	opts.Env = resumeEnv
+	if err := aiterate.RestoreRunOptions(&opts, session.Options); err != nil {
+		return fmt.Errorf("session %s can't be resumed: %w", session.ID, err)
+	}
+	if session.Options == nil {
+		color.Yellow("Session %s predates recorded run options; resuming with the default options", session.ID)
+	}
+	opts.OutputDir = resumeOutputDir
+	opts.NoWrite = resumeNoWrite
+	opts.MaxIterations = resumeMaxIter
+	opts.Observer = aiterate.EventFunc(printEvent)
+	if err := aiterate.CheckCredentials(opts); err != nil {
+		return fmt.Errorf("failed to initialize AI client: %w", err)
+	}
+
+	// Input is valid; failures from here on aren't usage errors
+	cmd.SilenceUsage = true
+	_, err = aiterate.Generate(cmd.Context(), opts)
+	return err
+}
@@ -1,10 +1,22 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+
+	"github.com/prathyushnallamothu/aiterate/internal/ai"
+	"github.com/prathyushnallamothu/aiterate/internal/storage"
+	"github.com/prathyushnallamothu/aiterate/internal/telemetry"
+	"github.com/prathyushnallamothu/aiterate/pkg/aiterate"
 )
 
 var rootCmd = &cobra.Command{
@@ -13,11 +25,264 @@ var rootCmd = &cobra.Command{
 	Long: `AIterate is a tool that uses AI to generate and iterate on code until it passes tests.
 It first generates tests based on your requirements, then creates an implementation,
 and iteratively improves the code until all tests pass.`,
+	// Execute reports errors itself, concisely with --quiet-ai-errors
+	SilenceErrors: true,
+}
+
+var (
+	headers           []string
+	apiKeyFile        string
+	requestsPerMinute int
+	maxConcurrent     int
+	maxRetries        int
+	totalRetries      int
+	modelFallback     string
+	otel              bool
+	assumeYes         bool
+	promptPrefix      string
+	promptSuffix      string
+	quietAIErrors     bool
+	keepSessions      int
+	maxSessionAge     string
+	reasoningEffort   string
+	aiLog             bool
+	verboseAI         bool
+	mockAI            bool
+	mockResponses     string
+	azure             bool
+	azureEndpoint     string
+	azureAPIVersion   string
+	azureDeployments  []string
+	sessionDir        string
+	// tracer exports the spans of every run in the command, when enabled
+	tracer *telemetry.OTLPTracer
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringArrayVar(&headers, "header", nil, `Extra HTTP header for AI provider requests, as "Key: Value" (repeatable)`)
+	rootCmd.PersistentFlags().StringVar(&apiKeyFile, "api-key-file", "", "File containing the OpenAI API key (checked before the OS keyring and OPENAI_API_KEY)")
+	rootCmd.PersistentFlags().IntVar(&requestsPerMinute, "rpm", 0, "Maximum AI requests per minute (0 for unlimited)")
+	rootCmd.PersistentFlags().IntVar(&maxConcurrent, "max-concurrent", 0, "Maximum concurrent AI requests (0 for unlimited)")
+	rootCmd.PersistentFlags().IntVar(&maxRetries, "retries", ai.DefaultMaxRetries, "Retries per AI request after rate limiting or provider errors (0 for none)")
+	rootCmd.PersistentFlags().IntVar(&totalRetries, "total-retries", 0, "Maximum AI request retries across a whole run (0 for unlimited)")
+	rootCmd.PersistentFlags().StringVar(&promptPrefix, "prompt-prefix", "", `Text added before every generation prompt, e.g. "Do not use any third-party libraries."`)
+	rootCmd.PersistentFlags().StringVar(&promptSuffix, "prompt-suffix", "", `Text added after every generation prompt, e.g. "Target Go 1.20 syntax."`)
+	rootCmd.PersistentFlags().BoolVarP(&assumeYes, "assume-yes", "y", false, "Answer yes to every confirmation prompt, for scripting")
+	rootCmd.PersistentFlags().BoolVar(&quietAIErrors, "quiet-ai-errors", false, "Report AI provider failures as one line with their category instead of the full error chain")
+	rootCmd.PersistentFlags().BoolVar(&otel, "otel", false, "Export OpenTelemetry spans for each phase to the OTLP/HTTP endpoint in OTEL_EXPORTER_OTLP_ENDPOINT (default localhost:4318)")
+	rootCmd.PersistentFlags().IntVar(&keepSessions, "keep-sessions", 0, "Keep at most this many sessions, pruning the oldest when a run starts (0 for unlimited)")
+	rootCmd.PersistentFlags().StringVar(&maxSessionAge, "max-session-age", "", "Prune sessions not updated for this long when a run starts, e.g. 30d or 720h")
+	rootCmd.PersistentFlags().StringVar(&reasoningEffort, "reasoning-effort", "", "Reasoning effort (low, medium or high) for models that support it, trading latency and cost for quality; ignored with a warning for other models")
+	rootCmd.PersistentFlags().BoolVar(&aiLog, "ai-log", false, "Record every AI request and raw response, with its phase, model and token usage, to transcript.jsonl in the session directory")
+	rootCmd.PersistentFlags().BoolVar(&verboseAI, "verbose-ai", false, "Print a line after every AI request with its phase, model, prompt and completion tokens, and the estimated cost so far")
+	rootCmd.PersistentFlags().BoolVar(&mockAI, "mock-ai", false, "Answer AI requests offline with canned responses instead of calling the provider, for testing")
+	rootCmd.PersistentFlags().StringVar(&mockResponses, "mock-responses", "", "Directory of canned responses for --mock-ai, named by prompt hash (implies --mock-ai)")
+	rootCmd.PersistentFlags().BoolVar(&azure, "azure", false, "Use Azure OpenAI instead of the OpenAI API (also enabled by AITERATE_AZURE=1); the key is read from AZURE_OPENAI_API_KEY before the usual places")
+	rootCmd.PersistentFlags().StringVar(&azureEndpoint, "azure-endpoint", "", "Azure OpenAI resource URL, e.g. https://myresource.openai.azure.com (default AZURE_OPENAI_ENDPOINT)")
+	rootCmd.PersistentFlags().StringVar(&azureAPIVersion, "azure-api-version", "", "Azure OpenAI API version (default AZURE_OPENAI_API_VERSION, then 2024-02-01)")
+	rootCmd.PersistentFlags().StringArrayVar(&azureDeployments, "azure-deployment", nil, `Azure deployment for a model, as "model=deployment" (repeatable; added to AZURE_OPENAI_DEPLOYMENTS); other models use their name without dots`)
+	rootCmd.PersistentFlags().StringVar(&sessionDir, "save-session-dir", "", "Directory to record sessions in (default AITERATE_HOME, then ~/.aiterate)")
+	rootCmd.PersistentFlags().StringVar(&modelFallback, "model-fallback", "", "Comma-separated models to fall back to, in order, when the model keeps failing with rate limits or provider errors")
+}
+
+// baseOptions builds run options for a model from the global flags.
+func baseOptions(model string) (aiterate.Options, error) {
+	parsed, err := ai.ParseHeaders(headers)
+	if err != nil {
+		return aiterate.Options{}, err
+	}
+	if requestsPerMinute < 0 || maxConcurrent < 0 {
+		return aiterate.Options{}, fmt.Errorf("--rpm and --max-concurrent must not be negative")
+	}
+	if maxRetries < 0 || totalRetries < 0 {
+		return aiterate.Options{}, fmt.Errorf("--retries and --total-retries must not be negative")
+	}
+	// The library treats zero as the default and negative as none
+	retries := maxRetries
+	if retries == 0 {
+		retries = -1
+	}
+	if reasoningEffort != "" && !ai.ValidReasoningEffort(reasoningEffort) {
+		return aiterate.Options{}, fmt.Errorf("--reasoning-effort must be low, medium or high")
+	}
+	if keepSessions < 0 {
+		return aiterate.Options{}, fmt.Errorf("--keep-sessions must not be negative")
+	}
+	retention := aiterate.RetentionPolicy{Keep: keepSessions}
+	if maxSessionAge != "" {
+		age, err := parsePeriod(maxSessionAge)
+		if err != nil {
+			return aiterate.Options{}, fmt.Errorf("--max-session-age: %w", err)
+		}
+		retention.MaxAge = age
+	}
+	if (otel || telemetry.EnabledFromEnv()) && tracer == nil {
+		tracer = telemetry.NewOTLPTracer(telemetry.ConfigFromEnv())
+	}
+	opts := aiterate.Options{
+		Model:             model,
+		APIKeyFile:        apiKeyFile,
+		Headers:           parsed,
+		RequestsPerMinute: requestsPerMinute,
+		MaxConcurrent:     maxConcurrent,
+		MaxRetries:        retries,
+		TotalRetries:      totalRetries,
+		FallbackModels:    splitList(modelFallback),
+		PromptPrefix:      promptPrefix,
+		PromptSuffix:      promptSuffix,
+		Retention:         retention,
+		ReasoningEffort:   reasoningEffort,
+		AILog:             aiLog,
+		VerboseAI:         verboseAI,
+		MockAI:            mockAI || mockResponses != "",
+		MockResponses:     mockResponses,
+		StorageDir:        sessionDir,
+	}
+	if azure || azureFromEnv() {
+		deployments, err := ai.ParseDeployments(strings.Join(azureDeployments, ","))
+		if err != nil {
+			return aiterate.Options{}, fmt.Errorf("--azure-deployment: %w", err)
+		}
+		opts.Azure = &aiterate.AzureConfig{Endpoint: azureEndpoint, APIVersion: azureAPIVersion, Deployments: deployments}
+	} else if azureEndpoint != "" || azureAPIVersion != "" || len(azureDeployments) > 0 {
+		return aiterate.Options{}, fmt.Errorf("--azure-endpoint, --azure-api-version and --azure-deployment require --azure")
+	}
+	if tracer != nil {
+		opts.Tracer = tracer
+	}
+	return opts, nil
+}
+
+// azureEnv enables Azure OpenAI like --azure when set to a true value
+const azureEnv = "AITERATE_AZURE"
+
+// azureFromEnv reports whether AITERATE_AZURE enables Azure OpenAI.
+func azureFromEnv() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(azureEnv))
+	return enabled
+}
+
+// openStorage opens the session store chosen by --save-session-dir or
+// AITERATE_HOME, by default in the user's home directory.
+func openStorage() (*storage.Storage, error) {
+	dir := sessionDir
+	if dir == "" {
+		var err error
+		dir, err = aiterate.DefaultStorageDir()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	store, err := storage.NewStorage(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	return store, nil
 }
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+	err := rootCmd.Execute()
+	if tracer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := tracer.Shutdown(ctx); err != nil {
+			color.Yellow("%v", err)
+		}
+		cancel()
+	}
+	if err != nil {
+		var apiErr *aiterate.APIError
+		if quietAIErrors && errors.As(err, &apiErr) {
+			fmt.Fprintf(os.Stderr, "Could not reach AI provider (%s): %s\n", apiErr.Category(), apiErr.Brief())
+		} else {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		var dirErr *aiterate.OutputDirError
+		if errors.As(err, &dirErr) {
+			color.Yellow("%s", outputDirRemedy(dirErr))
+		}
+		os.Exit(exitCode(err))
+	}
+}
+
+// outputDirRemedy suggests how to get past a failure to create the output directory.
+func outputDirRemedy(err *aiterate.OutputDirError) string {
+	switch err.Reason {
+	case aiterate.OutputDirPermission:
+		return "You don't have permission to write there; use --output-dir to pick a writable location"
+	case aiterate.OutputDirNotDirectory:
+		return fmt.Sprintf("Remove or rename %s, or use --output-dir to pick another location", err.Path)
+	case aiterate.OutputDirReadOnly:
+		return "The filesystem is mounted read-only; use --output-dir to pick a location on a writable one"
+	case aiterate.OutputDirNoSpace:
+		return "Free up disk space, or use --output-dir to pick a location on another disk"
+	default:
+		return "Use --output-dir to pick another location"
+	}
+}
+
+// Exit codes reported to the shell
+const (
+	exitFailure          = 1
+	exitNotConverged     = 2
+	exitAPIError         = 3
+	exitToolchainMissing = 4
+	exitInvalidResponse  = 5
+)
+
+// exitSeverity orders exit codes from the most to the least severe, for
+// commands reporting a single code for several runs
+var exitSeverity = []int{exitToolchainMissing, exitAPIError, exitInvalidResponse, exitFailure, exitNotConverged}
+
+// mostSevere returns the error among errs whose exit code is the most
+// severe, the first one among equals, or nil when all are nil.
+func mostSevere(errs []error) error {
+	var worst error
+	rank := len(exitSeverity)
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		for i, code := range exitSeverity {
+			if code == exitCode(err) && i < rank {
+				worst, rank = err, i
+			}
+		}
+	}
+	return worst
+}
+
+// errorCategory names the kind of failure for reporting.
+func errorCategory(err error) string {
+	switch exitCode(err) {
+	case exitNotConverged:
+		return "not converged"
+	case exitAPIError:
+		var apiErr *aiterate.APIError
+		if errors.As(err, &apiErr) {
+			return "api: " + apiErr.Category()
+		}
+		return "api"
+	case exitToolchainMissing:
+		return "toolchain"
+	case exitInvalidResponse:
+		return "invalid response"
+	default:
+		return "other"
+	}
+}
+
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, aiterate.ErrNotConverged), errors.Is(err, aiterate.ErrImplementationBug):
+		return exitNotConverged
+	case errors.Is(err, aiterate.ErrAPIFailure):
+		return exitAPIError
+	case errors.Is(err, aiterate.ErrToolchainMissing), errors.Is(err, exec.ErrNotFound):
+		return exitToolchainMissing
+	case errors.Is(err, aiterate.ErrInvalidAIResponse):
+		return exitInvalidResponse
+	default:
+		return exitFailure
 	}
 }
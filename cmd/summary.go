@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/prathyushnallamothu/aiterate/pkg/aiterate"
+)
+
+// runSummaryOnly runs the loop with all progress output suppressed and
+// prints only a final summary block.
+func runSummaryOnly(ctx context.Context, opts aiterate.Options) (*aiterate.Result, error) {
+	opts.Observer = aiterate.NopObserver{}
+	result, err := aiterate.Generate(ctx, opts)
+
+	// A summary report on stdout replaces the plain summary
+	if reportFormat == "" || reportFile != "" {
+		printSummary(opts.Language, result, err)
+	}
+	return result, err
+}
+
+// summaryStatus describes the outcome of a run.
+func summaryStatus(err error) string {
+	switch {
+	case err == nil:
+		return "passed"
+	case exitCode(err) == exitNotConverged:
+		return "failed"
+	default:
+		return "error (" + errorCategory(err) + ")"
+	}
+}
+
+func printSummary(language string, result *aiterate.Result, err error) {
+	fmt.Printf("Result:     %s\n", summaryStatus(err))
+	if result == nil {
+		return
+	}
+	fmt.Printf("Iterations: %d\n", result.Iterations)
+	fmt.Printf("Time:       %s\n", result.Duration.Round(100*time.Millisecond))
+	if result.OutputDir != "" {
+		fmt.Printf("Files:      %s\n", result.OutputDir)
+	}
+	fmt.Printf("Session:    %s\n", result.SessionID)
+
+	signatures := summaryAPI(language, result)
+	if len(signatures) == 0 {
+		return
+	}
+	fmt.Println("API:")
+	for _, signature := range signatures {
+		fmt.Printf("  %s\n", signature)
+	}
+}
+
+// summaryAPI returns the public API of the final code, as recorded on
+// success or else as found in the code.
+func summaryAPI(language string, result *aiterate.Result) []string {
+	if len(result.API) > 0 {
+		return result.API
+	}
+	return aiterate.PublicSignatures(result.Code, language)
+}
+
+// Formats of the summary report written with --report-format
+const (
+	reportJSON     = "json"
+	reportMarkdown = "markdown"
+)
+
+// summaryReport is the end-of-run summary written with --report-format.
+type summaryReport struct {
+	Description      string   `json:"description"`
+	Language         string   `json:"language"`
+	Model            string   `json:"model"`
+	Result           string   `json:"result"`
+	Error            string   `json:"error,omitempty"`
+	Iterations       int      `json:"iterations"`
+	DurationSeconds  float64  `json:"duration_seconds"`
+	PromptTokens     int      `json:"prompt_tokens"`
+	CompletionTokens int      `json:"completion_tokens"`
+	Session          string   `json:"session,omitempty"`
+	OutputDir        string   `json:"output_dir,omitempty"`
+	API              []string `json:"api,omitempty"`
+	Code             string   `json:"code,omitempty"`
+	Tests            string   `json:"tests,omitempty"`
+	// finished is set when the run returned a result to report on
+	finished bool
+}
+
+func newSummaryReport(opts aiterate.Options, result *aiterate.Result, err error) summaryReport {
+	report := summaryReport{
+		Description: opts.Description,
+		Language:    opts.Language,
+		Model:       opts.Model,
+		Result:      summaryStatus(err),
+	}
+	if err != nil {
+		report.Error = err.Error()
+	}
+	if result == nil {
+		return report
+	}
+	report.finished = true
+	report.Iterations = result.Iterations
+	report.DurationSeconds = result.Duration.Round(100 * time.Millisecond).Seconds()
+	report.PromptTokens = result.Usage.PromptTokens
+	report.CompletionTokens = result.Usage.CompletionTokens
+	report.Session = result.SessionID
+	report.OutputDir = result.OutputDir
+	report.API = summaryAPI(opts.Language, result)
+	report.Code = result.Code
+	report.Tests = result.TestCode
+	return report
+}
+
+// writeSummaryReport writes the summary of a run in format to path, or to
+// stdout when path is empty.
+func writeSummaryReport(format, path string, opts aiterate.Options, result *aiterate.Result, err error) error {
+	report := newSummaryReport(opts, result, err)
+	var data []byte
+	switch format {
+	case reportJSON:
+		// Code and errors are full of <, > and &, which are kept readable
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		if encodeErr := encoder.Encode(report); encodeErr != nil {
+			return fmt.Errorf("failed to encode the summary report: %w", encodeErr)
+		}
+		data = buf.Bytes()
+	case reportMarkdown:
+		data = []byte(report.markdown())
+	default:
+		return fmt.Errorf("unknown report format %q", format)
+	}
+
+	if path == "" {
+		_, writeErr := os.Stdout.Write(data)
+		return writeErr
+	}
+	if writeErr := os.WriteFile(path, data, 0644); writeErr != nil {
+		return fmt.Errorf("failed to write the summary report: %w", writeErr)
+	}
+	return nil
+}
+
+// markdown renders the report for docs and PR comments.
+func (r summaryReport) markdown() string {
+	var b strings.Builder
+	b.WriteString("## AIterate summary\n\n")
+	for _, line := range strings.Split(strings.TrimSpace(r.Description), "\n") {
+		b.WriteString(strings.TrimRight("> "+line, " ") + "\n")
+	}
+	b.WriteString("\n")
+	fmt.Fprintf(&b, "- **Result:** %s\n", r.Result)
+	fmt.Fprintf(&b, "- **Language:** %s\n", r.Language)
+	fmt.Fprintf(&b, "- **Model:** %s\n", r.Model)
+	if r.finished {
+		fmt.Fprintf(&b, "- **Iterations:** %d\n", r.Iterations)
+		fmt.Fprintf(&b, "- **Time:** %.1fs\n", r.DurationSeconds)
+	}
+	if r.Session != "" {
+		fmt.Fprintf(&b, "- **Session:** `%s`\n", r.Session)
+	}
+	if r.OutputDir != "" {
+		fmt.Fprintf(&b, "- **Files:** `%s`\n", r.OutputDir)
+	}
+	if r.Error != "" && r.Result != "passed" {
+		fmt.Fprintf(&b, "\n**Error:** %s\n", r.Error)
+	}
+	if len(r.API) > 0 {
+		b.WriteString("\n### API\n\n")
+		for _, signature := range r.API {
+			fmt.Fprintf(&b, "- `%s`\n", signature)
+		}
+	}
+	if strings.TrimSpace(r.Code) != "" {
+		fence := codeFence(r.Code)
+		fmt.Fprintf(&b, "\n### Implementation\n\n%s%s\n%s\n%s\n", fence, r.Language, strings.TrimRight(r.Code, "\n"), fence)
+	}
+	return b.String()
+}
+
+// codeFence returns a Markdown code fence longer than any run of backticks
+// in code, so the code can't close it.
+func codeFence(code string) string {
+	longest, run := 0, 0
+	for _, c := range code {
+		if c == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	if longest < 3 {
+		return "```"
+	}
+	return strings.Repeat("`", longest+1)
+}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/prathyushnallamothu/aiterate/pkg/aiterate"
+)
+
+type tickMsg time.Time
+
+type doneMsg struct {
+	err error
+}
+
+// tuiModel renders a live view of the iteration loop.
+type tuiModel struct {
+	description string
+	language    string
+	started     time.Time
+	elapsed     time.Duration
+	status      string
+	iteration   int
+	max         int
+	passed      int
+	failed      int
+	output      viewport.Model
+	ready       bool
+	finished    bool
+	err         error
+	interrupted bool
+}
+
+func newTUIModel(description, language string) tuiModel {
+	return tuiModel{
+		description: description,
+		language:    language,
+		started:     time.Now(),
+		status:      "Starting...",
+	}
+}
+
+func tick() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+		return tickMsg(t)
+	})
+}
+
+func (m tuiModel) Init() tea.Cmd {
+	return tick()
+}
+
+func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmds []tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c":
+			m.interrupted = true
+			return m, tea.Quit
+		case "q", "esc":
+			if m.finished {
+				return m, tea.Quit
+			}
+		}
+	case tea.WindowSizeMsg:
+		// Reserve room for the header lines above the output pane
+		height := msg.Height - 8
+		if height < 3 {
+			height = 3
+		}
+		if !m.ready {
+			m.output = viewport.New(msg.Width, height)
+			m.ready = true
+		} else {
+			m.output.Width = msg.Width
+			m.output.Height = height
+		}
+	case tickMsg:
+		if !m.finished {
+			m.elapsed = time.Since(m.started)
+			cmds = append(cmds, tick())
+		}
+	case aiterate.Event:
+		switch msg.Kind {
+		case aiterate.EventIterationStart:
+			m.iteration = msg.Iteration
+			m.max = msg.Max
+			m.status = fmt.Sprintf("Running tests (iteration %d/%d)...", msg.Iteration, msg.Max)
+		case aiterate.EventTestResult:
+			m.passed = msg.Result.Passed
+			m.failed = msg.Result.Failed
+			if m.ready {
+				m.output.SetContent(msg.Result.Output)
+				m.output.GotoTop()
+			}
+		case aiterate.EventOutput:
+			if m.ready {
+				m.output.SetContent(msg.Message)
+			}
+		default:
+			m.status = msg.Message
+		}
+	case doneMsg:
+		m.finished = true
+		m.err = msg.err
+		m.elapsed = time.Since(m.started)
+	}
+
+	var cmd tea.Cmd
+	m.output, cmd = m.output.Update(msg)
+	cmds = append(cmds, cmd)
+	return m, tea.Batch(cmds...)
+}
+
+func (m tuiModel) View() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "AIterate [%s] %s\n", m.language, m.description)
+	if m.max > 0 {
+		fmt.Fprintf(&b, "Iteration: %d/%d", m.iteration, m.max)
+	} else {
+		b.WriteString("Iteration: -")
+	}
+	fmt.Fprintf(&b, "   Passed: %d   Failed: %d   Elapsed: %s\n", m.passed, m.failed, m.elapsed.Round(time.Second))
+	fmt.Fprintf(&b, "Status: %s\n", m.status)
+	b.WriteString(strings.Repeat("─", 40) + "\n")
+	if m.ready {
+		b.WriteString(m.output.View())
+	}
+	b.WriteString("\n" + strings.Repeat("─", 40) + "\n")
+	switch {
+	case m.finished && m.err != nil:
+		fmt.Fprintf(&b, "Error: %v (press q to exit)\n", m.err)
+	case m.finished:
+		b.WriteString("Done (press q to exit)\n")
+	default:
+		b.WriteString("↑/↓ scroll test output • ctrl+c abort\n")
+	}
+	return b.String()
+}
+
+// runWithTUI runs the generate/iterate loop in the background while
+// rendering its progress in an interactive terminal view.
+func runWithTUI(ctx context.Context, opts aiterate.Options) error {
+	program := tea.NewProgram(newTUIModel(opts.Description, opts.Language), tea.WithAltScreen())
+	opts.Observer = aiterate.EventFunc(func(event aiterate.Event) { program.Send(event) })
+
+	// Leaving the TUI stops the run, which is waited for so its test
+	// processes end and its workspace is removed before returning
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := aiterate.Generate(ctx, opts)
+		errCh <- err
+		program.Send(doneMsg{err: err})
+	}()
+
+	final, err := program.Run()
+	if err != nil {
+		cancel()
+		<-errCh
+		return fmt.Errorf("failed to run TUI: %w", err)
+	}
+	if final.(tuiModel).interrupted {
+		cancel()
+		<-errCh
+		return fmt.Errorf("interrupted")
+	}
+	return <-errCh
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/spf13/cobra"
+
+	"github.com/prathyushnallamothu/aiterate/internal/ai"
+)
+
+// version is set at build time with
+// -ldflags "-X github.com/prathyushnallamothu/aiterate/cmd.version=v1.0.0"
+var version = "dev"
+
+func init() {
+	rootCmd.AddCommand(versionCmd)
+}
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print version, build and AI provider information",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Printf("AIterate %s\n", buildVersion())
+		fmt.Printf("Go:            %s (%s/%s)\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+		fmt.Printf("Provider:      %s\n", ai.ProviderName)
+		fmt.Printf("Default model: %s\n", ai.DefaultModel)
+	},
+}
+
+// buildVersion returns the ldflags version, falling back to the module
+// version recorded by `go install`.
+func buildVersion() string {
+	if version != "dev" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return version
+}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/prathyushnallamothu/aiterate/pkg/aiterate"
+)
+
+// watchPollInterval is how often the description file is checked for
+// changes. Polling a single file's size and modification time is cheap and
+// works the same on every platform and filesystem, including network and
+// container mounts where change notifications are unreliable.
+const watchPollInterval = 250 * time.Millisecond
+
+var (
+	watchDescFile  string
+	watchLanguage  string
+	watchOutputDir string
+	watchModel     string
+	watchDebounce  time.Duration
+)
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+	watchCmd.Flags().StringVar(&watchDescFile, "description-file", "", "File containing the description to watch (required)")
+	watchCmd.Flags().StringVarP(&watchLanguage, "language", "l", "", "Programming language to generate (required)")
+	watchCmd.Flags().StringVar(&watchOutputDir, "output-dir", "", "Directory updated with the files of each run (required)")
+	watchCmd.Flags().StringVar(&watchModel, "model", aiterate.DefaultModel, "AI model to use")
+	watchCmd.Flags().DurationVar(&watchDebounce, "debounce", time.Second, "How long the file must stay unchanged before a run starts")
+	watchCmd.MarkFlagRequired("description-file")
+	watchCmd.MarkFlagRequired("language")
+	watchCmd.MarkFlagRequired("output-dir")
+}
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Regenerate the code whenever a description file changes",
+	Long: `Run the generate/iterate pipeline on a description file, then watch the file
+and run it again each time it changes, updating the output directory.
+
+Each run is recorded as a new session. Press Ctrl+C to stop.`,
+	Args: cobra.NoArgs,
+	RunE: runWatch,
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	language := strings.ToLower(strings.TrimSpace(watchLanguage))
+	if !aiterate.IsSupported(language) {
+		return fmt.Errorf("unsupported language: %s. Supported languages: %s", language, aiterate.SupportedLanguageNames())
+	}
+	if watchDebounce < 0 {
+		return fmt.Errorf("--debounce must not be negative")
+	}
+
+	opts, err := baseOptions(watchModel)
+	if err != nil {
+		return err
+	}
+	opts.Language = language
+	opts.OutputDir = watchOutputDir
+	opts.Observer = aiterate.EventFunc(printEvent)
+	if err := aiterate.CheckCredentials(opts); err != nil {
+		return fmt.Errorf("failed to initialize AI client: %w", err)
+	}
+	if _, err := os.Stat(watchDescFile); err != nil {
+		return fmt.Errorf("--description-file: %w", err)
+	}
+
+	cmd.SilenceUsage = true
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer stop()
+
+	var last string
+	for {
+		description, err := waitForChange(ctx, watchDescFile, last, watchDebounce)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				color.Blue("Stopped watching %s", watchDescFile)
+				return nil
+			}
+			return err
+		}
+		last = description
+
+		if strings.TrimSpace(description) == "" {
+			color.Yellow("%s is empty; waiting for a description", watchDescFile)
+			continue
+		}
+
+		color.Blue("Running the pipeline for %s...", watchDescFile)
+		opts.Description = description
+		result, err := aiterate.Generate(ctx, opts)
+		switch {
+		case errors.Is(err, context.Canceled):
+			color.Blue("Stopped watching %s", watchDescFile)
+			return nil
+		case err != nil && result == nil:
+			// Report the failure and keep watching for a fixed description
+			color.Red("Run failed: %v", err)
+		case err != nil:
+			color.Red("Run failed after %d iteration(s): %v", result.Iterations, err)
+		}
+		color.Blue("Watching %s for changes (Ctrl+C to stop)...", watchDescFile)
+	}
+}
+
+// waitForChange polls path until its content differs from last and then
+// stays unchanged for debounce, and returns the new content. The file is
+// only read again when its size or modification time changes. It returns
+// immediately on the first call, when last is empty.
+func waitForChange(ctx context.Context, path, last string, debounce time.Duration) (string, error) {
+	content, err := readWatched(path)
+	if err != nil {
+		return "", err
+	}
+	if last == "" && content != "" {
+		return content, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+	var changedAt time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-ticker.C:
+		}
+
+		current, err := os.Stat(path)
+		if err != nil {
+			// Editors often replace the file on save; wait for it to reappear
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return "", fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if current.Size() != info.Size() || !current.ModTime().Equal(info.ModTime()) {
+			info = current
+			text, err := readWatched(path)
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					continue
+				}
+				return "", err
+			}
+			if text != content {
+				content, changedAt = text, time.Now()
+			}
+			continue
+		}
+		if content != last && time.Since(changedAt) >= debounce {
+			return content, nil
+		}
+	}
+}
+
+func readWatched(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,106 @@
+package ai
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Environment variables configuring Azure OpenAI
+const (
+	AzureEndpointEnv    = "AZURE_OPENAI_ENDPOINT"
+	AzureAPIVersionEnv  = "AZURE_OPENAI_API_VERSION"
+	AzureDeploymentsEnv = "AZURE_OPENAI_DEPLOYMENTS"
+	AzureAPIKeyEnv      = "AZURE_OPENAI_API_KEY"
+)
+
+// defaultAzureAPIVersion is the Azure OpenAI API version used when none is
+// configured
+const defaultAzureAPIVersion = "2024-02-01"
+
+// AzureProviderName identifies Azure OpenAI as the provider backing AIClient
+const AzureProviderName = "Azure OpenAI"
+
+// AzureConfig selects Azure OpenAI instead of the OpenAI API.
+type AzureConfig struct {
+	// Endpoint is the resource URL, e.g. https://myresource.openai.azure.com;
+	// AZURE_OPENAI_ENDPOINT when empty
+	Endpoint string
+	// APIVersion is the Azure OpenAI API version; AZURE_OPENAI_API_VERSION,
+	// then defaultAzureAPIVersion, when empty
+	APIVersion string
+	// Deployments maps model names to deployment names, added to those in
+	// AZURE_OPENAI_DEPLOYMENTS. Models without an entry use the deployment
+	// named like the model without dots and colons, e.g. gpt-35-turbo
+	Deployments map[string]string
+}
+
+// azureUnsafeChars are removed from model names to get the default deployment name
+var azureUnsafeChars = regexp.MustCompile(`[.:]`)
+
+// ParseDeployments parses comma-separated "model=deployment" pairs.
+func ParseDeployments(value string) (map[string]string, error) {
+	deployments := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		model, deployment, ok := strings.Cut(pair, "=")
+		model, deployment = strings.TrimSpace(model), strings.TrimSpace(deployment)
+		if !ok || model == "" || deployment == "" {
+			return nil, fmt.Errorf("invalid deployment %q: expected \"model=deployment\"", pair)
+		}
+		deployments[model] = deployment
+	}
+	return deployments, nil
+}
+
+// ResolveEndpoint returns the resource URL, from the config or
+// AZURE_OPENAI_ENDPOINT, without a trailing slash.
+func (a *AzureConfig) ResolveEndpoint() (string, error) {
+	endpoint := a.Endpoint
+	if endpoint == "" {
+		endpoint = os.Getenv(AzureEndpointEnv)
+	}
+	if endpoint == "" {
+		return "", fmt.Errorf("no Azure OpenAI endpoint: set %s or pass one", AzureEndpointEnv)
+	}
+	return strings.TrimRight(endpoint, "/"), nil
+}
+
+// clientConfig returns the client configuration for Azure OpenAI, filling
+// in the settings from the environment.
+func (a *AzureConfig) clientConfig(apiKey string) (openai.ClientConfig, error) {
+	endpoint, err := a.ResolveEndpoint()
+	if err != nil {
+		return openai.ClientConfig{}, err
+	}
+
+	deployments, err := ParseDeployments(os.Getenv(AzureDeploymentsEnv))
+	if err != nil {
+		return openai.ClientConfig{}, fmt.Errorf("invalid %s: %w", AzureDeploymentsEnv, err)
+	}
+	for model, deployment := range a.Deployments {
+		deployments[model] = deployment
+	}
+
+	config := openai.DefaultAzureConfig(apiKey, endpoint)
+	config.APIVersion = a.APIVersion
+	if config.APIVersion == "" {
+		config.APIVersion = os.Getenv(AzureAPIVersionEnv)
+	}
+	if config.APIVersion == "" {
+		config.APIVersion = defaultAzureAPIVersion
+	}
+	config.AzureModelMapperFunc = func(model string) string {
+		if deployment, ok := deployments[model]; ok {
+			return deployment
+		}
+		return azureUnsafeChars.ReplaceAllString(model, "")
+	}
+	return config, nil
+}
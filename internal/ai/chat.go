@@ -0,0 +1,44 @@
+package ai
+
+import (
+	"context"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Roles of the messages in a conversation
+const (
+	RoleUser      = openai.ChatMessageRoleUser
+	RoleAssistant = openai.ChatMessageRoleAssistant
+)
+
+// Message is one turn of a conversation with the model.
+type Message struct {
+	Role    string
+	Content string
+}
+
+// UserMessage returns a message from the user.
+func UserMessage(content string) Message {
+	return Message{Role: RoleUser, Content: content}
+}
+
+// ChatCompleter is a Completer that can also answer a whole conversation.
+// Completers that can't get the conversation as a single prompt.
+type ChatCompleter interface {
+	CompleteChat(ctx context.Context, model string, messages []Message) (string, Usage, error)
+}
+
+// promptText renders messages as a single prompt: the content of a lone
+// message as it is, or each message under its role.
+func promptText(messages []Message) string {
+	if len(messages) == 1 {
+		return messages[0].Content
+	}
+	parts := make([]string, len(messages))
+	for i, message := range messages {
+		parts[i] = "[" + message.Role + "]\n" + message.Content
+	}
+	return strings.Join(parts, "\n\n")
+}
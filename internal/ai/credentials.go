@@ -0,0 +1,69 @@
+package ai
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	dotenv "github.com/joho/godotenv"
+	"github.com/zalando/go-keyring"
+)
+
+// Keyring entry holding the API key
+const (
+	KeyringService = "aiterate"
+	KeyringUser    = "openai-api-key"
+)
+
+// resolveAPIKey finds the API key, checking the key file, then the OS
+// keyring, then the OPENAI_API_KEY environment variable.
+func resolveAPIKey(keyFile string) (string, error) {
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read API key file: %w", err)
+		}
+		key := strings.TrimSpace(string(data))
+		if key == "" {
+			return "", fmt.Errorf("API key file %s is empty", keyFile)
+		}
+		return key, nil
+	}
+
+	if key, err := keyring.Get(KeyringService, KeyringUser); err == nil && strings.TrimSpace(key) != "" {
+		return strings.TrimSpace(key), nil
+	}
+
+	if key := os.Getenv("OPENAI_API_KEY"); key != "" {
+		return key, nil
+	}
+
+	return "", fmt.Errorf("no OpenAI API key found: use --api-key-file, store it in the OS keyring (service %q, user %q), or set OPENAI_API_KEY",
+		KeyringService, KeyringUser)
+}
+
+// CheckAPIKey reports whether an API key can be found, without creating a client.
+func CheckAPIKey(keyFile string) error {
+	dotenv.Load()
+	_, err := resolveAPIKey(keyFile)
+	return err
+}
+
+// resolveAzureAPIKey finds the Azure OpenAI key: the key file when given,
+// then AZURE_OPENAI_API_KEY, then the places resolveAPIKey looks.
+func resolveAzureAPIKey(keyFile string) (string, error) {
+	if keyFile == "" {
+		if key := os.Getenv(AzureAPIKeyEnv); key != "" {
+			return key, nil
+		}
+	}
+	return resolveAPIKey(keyFile)
+}
+
+// CheckAzureAPIKey reports whether an Azure OpenAI key can be found,
+// without creating a client.
+func CheckAzureAPIKey(keyFile string) error {
+	dotenv.Load()
+	_, err := resolveAzureAPIKey(keyFile)
+	return err
+}
@@ -0,0 +1,105 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// ErrAPIFailure is returned, wrapped in an *APIError, when a request to the
+// AI provider fails.
+var ErrAPIFailure = errors.New("failed to generate completion")
+
+// APIError describes a failed provider request.
+type APIError struct {
+	// StatusCode is the HTTP status returned by the provider, or 0 when the
+	// request didn't get a response
+	StatusCode int
+	Err        error
+}
+
+func (e *APIError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%s (status %d): %v", ErrAPIFailure, e.StatusCode, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", ErrAPIFailure, e.Err)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Is makes errors.Is(err, ErrAPIFailure) match any *APIError.
+func (e *APIError) Is(target error) bool {
+	return target == ErrAPIFailure
+}
+
+// Failure categories reported by APIError.Category
+const (
+	CategoryAuth      = "authentication"
+	CategoryRateLimit = "rate limited"
+	CategoryProvider  = "provider error"
+	CategoryRequest   = "rejected request"
+	CategoryNetwork   = "network"
+	CategoryCanceled  = "canceled"
+	CategoryOther     = "other"
+)
+
+// Category classifies the failure, e.g. CategoryRateLimit or CategoryNetwork.
+func (e *APIError) Category() string {
+	switch {
+	case e.StatusCode == http.StatusUnauthorized, e.StatusCode == http.StatusForbidden:
+		return CategoryAuth
+	case e.StatusCode == http.StatusTooManyRequests:
+		return CategoryRateLimit
+	case e.StatusCode >= 500:
+		return CategoryProvider
+	case e.StatusCode != 0:
+		return CategoryRequest
+	case errors.Is(e.Err, context.Canceled), errors.Is(e.Err, context.DeadlineExceeded):
+		return CategoryCanceled
+	}
+	var netErr net.Error
+	if errors.As(e.Err, &netErr) {
+		return CategoryNetwork
+	}
+	return CategoryOther
+}
+
+// Brief returns the provider's own message, or the first line of the
+// underlying error, without the chain of wrapping context.
+func (e *APIError) Brief() string {
+	var openaiErr *openai.APIError
+	if errors.As(e.Err, &openaiErr) && openaiErr.Message != "" {
+		return openaiErr.Message
+	}
+	message := e.Err.Error()
+	// Network errors end with the root cause, e.g. "connection refused"
+	var netErr net.Error
+	if errors.As(e.Err, &netErr) {
+		if i := strings.LastIndex(message, ": "); i >= 0 {
+			message = message[i+2:]
+		}
+	}
+	message, _, _ = strings.Cut(message, "\n")
+	return message
+}
+
+// newAPIError wraps a provider error, extracting the HTTP status when available.
+func newAPIError(err error) *APIError {
+	apiErr := &APIError{Err: err}
+	var openaiErr *openai.APIError
+	var requestErr *openai.RequestError
+	switch {
+	case errors.As(err, &openaiErr):
+		apiErr.StatusCode = openaiErr.HTTPStatusCode
+	case errors.As(err, &requestErr):
+		apiErr.StatusCode = requestErr.HTTPStatusCode
+	}
+	return apiErr
+}
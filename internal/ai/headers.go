@@ -0,0 +1,52 @@
+package ai
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// headersEnv holds extra request headers as semicolon-separated "Key: Value" pairs
+const headersEnv = "AITERATE_HEADERS"
+
+// headerTransport adds a fixed set of headers to every outgoing request,
+// for API gateways and auth proxies in front of the provider.
+type headerTransport struct {
+	headers http.Header
+	base    http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for key, values := range t.headers {
+		req.Header.Del(key)
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+// ParseHeaders parses "Key: Value" strings into an http.Header.
+func ParseHeaders(values []string) (http.Header, error) {
+	headers := make(http.Header)
+	for _, value := range values {
+		key, val, ok := strings.Cut(value, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid header %q: expected \"Key: Value\"", value)
+		}
+		headers.Add(key, strings.TrimSpace(val))
+	}
+	return headers, nil
+}
+
+func splitHeaderEnv(value string) []string {
+	var headers []string
+	for _, header := range strings.Split(value, ";") {
+		if header = strings.TrimSpace(header); header != "" {
+			headers = append(headers, header)
+		}
+	}
+	return headers
+}
@@ -0,0 +1,135 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	// MockTemplate is the MockCompleter response for prompts without a
+	// canned response or a template for their phase
+	MockTemplate = "// mock response\n"
+	// mockDefaultFile, in the mock directory, replaces the templates
+	mockDefaultFile = "default.txt"
+)
+
+// volatilePatterns match the parts of a prompt that change from run to run
+// of the same task, such as test timings and workspace paths, which
+// PromptHash ignores
+var volatilePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\d+(\.\d+)?(ns|µs|us|ms|s)\b`),
+	regexp.MustCompile(`aiterate-[\w-]+`),
+	regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`),
+}
+
+// PhaseCompleter is a Completer told what each request is for, which
+// AIClient uses instead of Complete.
+type PhaseCompleter interface {
+	CompletePhase(ctx context.Context, phase, model, prompt string) (string, Usage, error)
+}
+
+// MockCompleter answers completions offline from canned responses, for
+// testing the pipeline deterministically without API calls. The response
+// to the nth request of a phase is read from <phase>-<n>.txt in its
+// directory, with spaces in the phase replaced by hyphens (e.g. fix-2.txt
+// for the second fix), then from <hash>.txt, where hash is PromptHash of
+// the prompt, falling back to default.txt, the phase's template and then
+// MockTemplate. A prompt without a canned response is saved as
+// <phase>-<n>.prompt so one can be written for it.
+type MockCompleter struct {
+	dir       string
+	templates map[string]string
+
+	mu sync.Mutex
+	// requests counts the requests made so far by phase
+	requests map[string]int
+}
+
+// NewMockCompleter returns a MockCompleter reading responses from dir and
+// answering prompts without one with the templates by phase; with an empty
+// dir every prompt is answered with a template.
+func NewMockCompleter(dir string, templates map[string]string) *MockCompleter {
+	return &MockCompleter{dir: dir, templates: templates, requests: make(map[string]int)}
+}
+
+// PromptHash returns the key of a prompt's canned response. Test timings
+// and workspace paths are left out, so reruns of a task hash the same.
+func PromptHash(prompt string) string {
+	for _, pattern := range volatilePatterns {
+		prompt = pattern.ReplaceAllString(prompt, "_")
+	}
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+func (m *MockCompleter) Complete(ctx context.Context, model, prompt string) (string, Usage, error) {
+	return m.CompletePhase(ctx, "", model, prompt)
+}
+
+func (m *MockCompleter) CompletePhase(ctx context.Context, phase, model, prompt string) (string, Usage, error) {
+	if err := ctx.Err(); err != nil {
+		return "", Usage{}, err
+	}
+	if m.dir == "" {
+		return m.template(phase), Usage{}, nil
+	}
+
+	key := m.nextKey(phase)
+	response, ok, err := m.read(key + ".txt")
+	if err != nil || ok {
+		return response, Usage{}, err
+	}
+	response, ok, err = m.read(PromptHash(prompt) + ".txt")
+	if err != nil || ok {
+		return response, Usage{}, err
+	}
+	if err := os.WriteFile(filepath.Join(m.dir, key+".prompt"), []byte(prompt), 0644); err != nil {
+		return "", Usage{}, fmt.Errorf("failed to save mock prompt: %w", err)
+	}
+	response, ok, err = m.read(mockDefaultFile)
+	if err != nil || ok {
+		return response, Usage{}, err
+	}
+	return m.template(phase), Usage{}, nil
+}
+
+// nextKey counts a request of phase and returns its sequence key, or the
+// prompt hash's place for requests without a phase.
+func (m *MockCompleter) nextKey(phase string) string {
+	if phase == "" {
+		phase = "request"
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[phase]++
+	return fmt.Sprintf("%s-%d", strings.ReplaceAll(phase, " ", "-"), m.requests[phase])
+}
+
+// template returns the response for a phase's prompts without a canned one.
+func (m *MockCompleter) template(phase string) string {
+	if response, ok := m.templates[phase]; ok {
+		return response
+	}
+	return MockTemplate
+}
+
+// read returns the canned response in name and whether it exists.
+func (m *MockCompleter) read(name string) (string, bool, error) {
+	data, err := os.ReadFile(filepath.Join(m.dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read mock response: %w", err)
+	}
+	return string(data), true, nil
+}
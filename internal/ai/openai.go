@@ -2,55 +2,410 @@ package ai
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
 	openai "github.com/sashabaranov/go-openai"
 
 	dotenv"github.com/joho/godotenv"
 )
 
+const (
+	// ProviderName identifies the AI provider backing AIClient
+	ProviderName = "OpenAI"
+	// DefaultModel is the model used when none is specified
+	DefaultModel = "gpt-4o"
+)
+
+// Usage is the number of tokens consumed by completion requests.
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// TotalTokens returns the sum of prompt and completion tokens.
+func (u Usage) TotalTokens() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// Config configures an AIClient.
+type Config struct {
+	// Model is the model used for completions; DefaultModel when empty
+	Model string
+	// FallbackModels are tried in order when a request to the previous
+	// model still fails with a transient error after its retries
+	FallbackModels []string
+	// OnFallback, when set, is called before switching to a fallback model
+	OnFallback func(from, to string, err error)
+	// APIKeyFile is a file containing the API key, checked before the keyring and environment
+	APIKeyFile string
+	// Headers are extra HTTP headers attached to every provider request
+	Headers http.Header
+	// RequestsPerMinute caps the request rate; zero means unlimited
+	RequestsPerMinute int
+	// MaxConcurrent caps in-flight requests; zero means unlimited
+	MaxConcurrent int
+	// MaxRetries is how often each request is retried after a transient
+	// error; DefaultMaxRetries when zero, none when negative
+	MaxRetries int
+	// TotalRetries caps the retries spent across all requests made by the
+	// client; zero means unlimited
+	TotalRetries int
+	// PromptPrefix and PromptSuffix are added before and after every
+	// prompt, separated by a blank line, e.g. for global constraints
+	PromptPrefix string
+	PromptSuffix string
+	// ReasoningEffort is sent as the reasoning effort (low, medium or high)
+	// to models that support it, see SupportsReasoningEffort, and ignored
+	// for others
+	ReasoningEffort string
+	// Mock answers every completion offline with a MockCompleter instead
+	// of calling the provider, so no API key is needed
+	Mock bool
+	// MockDir is the directory of canned responses used with Mock
+	MockDir string
+	// MockTemplates answer Mock requests without a canned response by
+	// phase, so each gets a response in the format its phase expects
+	MockTemplates map[string]string
+	// OnExchange, when set, is called after every completion request with
+	// the raw prompt and response, e.g. to record a Transcript
+	OnExchange func(Exchange)
+	// Azure, when set, sends requests to Azure OpenAI, mapping models to
+	// deployments
+	Azure *AzureConfig
+}
+
+// Provider returns the name of the provider the configuration sends
+// requests to.
+func (c Config) Provider() string {
+	if c.Azure != nil {
+		return AzureProviderName
+	}
+	return ProviderName
+}
+
+// Completer answers a single completion request for a model. AIClient
+// wraps it with rate limiting, retries and model fallback.
+type Completer interface {
+	Complete(ctx context.Context, model, prompt string) (string, Usage, error)
+}
+
 type AIClient struct {
-	client *openai.Client
+	// client is nil when completer doesn't call the provider
+	client    *openai.Client
+	completer Completer
+	model     string
+	limiter   *limiter
+	retries   int
+	budget    *retryBudget
+	// fallbacks are the models tried after model, in order
+	fallbacks  []string
+	onFallback func(from, to string, err error)
+	onExchange func(Exchange)
+	// prefix and suffix wrap every prompt
+	prefix, suffix string
+
+	mu    sync.Mutex
+	usage Usage
+	// lastModel is the model that answered the most recent completion
+	lastModel string
 }
 
-func NewAIClient() (*AIClient, error) {
+func NewAIClient(cfg Config) (*AIClient, error) {
+	model := cfg.Model
+	if model == "" {
+		model = DefaultModel
+	}
+	if cfg.Mock {
+		return newClient(cfg, model, nil, NewMockCompleter(cfg.MockDir, cfg.MockTemplates)), nil
+	}
+
 	dotenv.Load()
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("OPENAI_API_KEY environment variable is not set")
+	resolve := resolveAPIKey
+	if cfg.Azure != nil {
+		resolve = resolveAzureAPIKey
+	}
+	apiKey, err := resolve(cfg.APIKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	headers, err := ParseHeaders(splitHeaderEnv(os.Getenv(headersEnv)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", headersEnv, err)
+	}
+	for key, values := range cfg.Headers {
+		headers[key] = values
+	}
+
+	config := openai.DefaultConfig(apiKey)
+	if cfg.Azure != nil {
+		if config, err = cfg.Azure.clientConfig(apiKey); err != nil {
+			return nil, err
+		}
+	}
+	var transport http.RoundTripper = http.DefaultTransport
+	if len(headers) > 0 {
+		transport = &headerTransport{headers: headers, base: transport}
+	}
+	if cfg.ReasoningEffort != "" {
+		transport = &reasoningTransport{effort: cfg.ReasoningEffort, base: transport}
+	}
+	if transport != http.DefaultTransport {
+		config.HTTPClient = &http.Client{Transport: transport}
+	}
+
+	client := openai.NewClientWithConfig(config)
+	return newClient(cfg, model, client, &openAICompleter{client: client}), nil
+}
+
+// newClient creates an AIClient answering with completer.
+func newClient(cfg Config, model string, client *openai.Client, completer Completer) *AIClient {
+	retries := cfg.MaxRetries
+	switch {
+	case retries == 0:
+		retries = DefaultMaxRetries
+	case retries < 0:
+		retries = 0
+	}
+
+	return &AIClient{
+		client:     client,
+		completer:  completer,
+		model:      model,
+		limiter:    newLimiter(cfg.RequestsPerMinute, cfg.MaxConcurrent),
+		retries:    retries,
+		budget:     newRetryBudget(cfg.TotalRetries),
+		fallbacks:  cfg.FallbackModels,
+		onFallback: cfg.OnFallback,
+		onExchange: cfg.OnExchange,
+		prefix:     strings.TrimSpace(cfg.PromptPrefix),
+		suffix:     strings.TrimSpace(cfg.PromptSuffix),
+	}
+}
+
+// WithModel returns a client for model without fallback models that
+// shares c's provider, settings, rate and concurrency limits and total
+// retry budget, so requests through either client count toward the same
+// limits. Token usage is counted separately.
+func (c *AIClient) WithModel(model string) *AIClient {
+	return &AIClient{
+		client:     c.client,
+		completer:  c.completer,
+		model:      model,
+		limiter:    c.limiter,
+		retries:    c.retries,
+		budget:     c.budget,
+		onFallback: c.onFallback,
+		onExchange: c.onExchange,
+		prefix:     c.prefix,
+		suffix:     c.suffix,
+	}
+}
+
+// Model returns the model used for completions.
+func (c *AIClient) Model() string {
+	return c.model
+}
+
+// LastModel returns the model that answered the most recent successful
+// completion, which differs from Model after a fallback.
+func (c *AIClient) LastModel() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lastModel == "" {
+		return c.model
+	}
+	return c.lastModel
+}
+
+// Usage returns the tokens consumed by all completions made so far.
+func (c *AIClient) Usage() Usage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.usage
+}
+
+// GenerateCompletion sends prompt, made for phase, to the model, retrying transient errors
+// within both the per-request limit and the client's total retry budget.
+// When the model keeps failing with transient errors, the fallback models
+// are tried in order. Cancelling ctx aborts the request in flight as well
+// as waits for the rate limiter and between retries.
+func (c *AIClient) GenerateCompletion(ctx context.Context, phase, prompt string) (string, error) {
+	return c.GenerateChat(ctx, phase, []Message{UserMessage(prompt)})
+}
+
+// GenerateChat is GenerateCompletion for a conversation, e.g. earlier
+// requests and responses followed by a new request. The prompt prefix is
+// added to the first message and the suffix to the last.
+func (c *AIClient) GenerateChat(ctx context.Context, phase string, messages []Message) (string, error) {
+	messages = c.wrapMessages(messages)
+	models := append([]string{c.model}, c.fallbacks...)
+	var err error
+	for i, model := range models {
+		var response string
+		response, err = c.completeWithRetries(ctx, phase, model, messages)
+		if err == nil {
+			c.mu.Lock()
+			c.lastModel = model
+			c.mu.Unlock()
+			return response, nil
+		}
+
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || !isRetryable(apiErr) || ctx.Err() != nil {
+			break
+		}
+		if i < len(models)-1 && c.onFallback != nil {
+			c.onFallback(model, models[i+1], err)
+		}
+	}
+	return "", err
+}
+
+// wrapMessages adds the configured prefix to the first message and the
+// suffix to the last, leaving messages unchanged.
+func (c *AIClient) wrapMessages(messages []Message) []Message {
+	if len(messages) == 0 || (c.prefix == "" && c.suffix == "") {
+		return messages
+	}
+	wrapped := append([]Message(nil), messages...)
+	last := len(wrapped) - 1
+	wrapped[0].Content = WrapPrompt(c.prefix, wrapped[0].Content, "")
+	wrapped[last].Content = WrapPrompt("", wrapped[last].Content, c.suffix)
+	return wrapped
+}
+
+// WrapPrompt adds Config.PromptPrefix and Config.PromptSuffix to prompt as
+// the client does before sending it.
+func WrapPrompt(prefix, prompt, suffix string) string {
+	if prefix != "" {
+		prompt = prefix + "\n\n" + prompt
+	}
+	if suffix != "" {
+		prompt = prompt + "\n\n" + suffix
+	}
+	return prompt
+}
+
+// completeWithRetries sends messages to model, retrying transient errors.
+func (c *AIClient) completeWithRetries(ctx context.Context, phase, model string, messages []Message) (string, error) {
+	for attempt := 0; ; attempt++ {
+		response, err := c.complete(ctx, phase, model, messages)
+		if err == nil {
+			return response, nil
+		}
+
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || !isRetryable(apiErr) || attempt == c.retries || ctx.Err() != nil {
+			return "", err
+		}
+		if !c.budget.take() {
+			return "", fmt.Errorf("%w (retry budget exhausted)", err)
+		}
+		if err := sleep(ctx, retryBaseDelay<<attempt); err != nil {
+			return "", newAPIError(err)
+		}
+	}
+}
+
+// complete makes a single completion request.
+func (c *AIClient) complete(ctx context.Context, phase, model string, messages []Message) (string, error) {
+	release, err := c.limiter.acquire(ctx)
+	if err != nil {
+		return "", newAPIError(err)
 	}
+	defer release()
 
-	client := openai.NewClient(apiKey)
-	return &AIClient{client: client}, nil
+	// Tokens are counted even when the response turns out to be unusable
+	prompt := promptText(messages)
+	var response string
+	var usage Usage
+	if chat, ok := c.completer.(ChatCompleter); ok {
+		response, usage, err = chat.CompleteChat(ctx, model, messages)
+	} else if phased, ok := c.completer.(PhaseCompleter); ok {
+		response, usage, err = phased.CompletePhase(ctx, phase, model, prompt)
+	} else {
+		response, usage, err = c.completer.Complete(ctx, model, prompt)
+	}
+	c.mu.Lock()
+	c.usage.PromptTokens += usage.PromptTokens
+	c.usage.CompletionTokens += usage.CompletionTokens
+	c.mu.Unlock()
+
+	if c.onExchange != nil {
+		exchange := Exchange{
+			Time:             time.Now(),
+			Phase:            phase,
+			Model:            model,
+			Prompt:           prompt,
+			Response:         response,
+			PromptTokens:     usage.PromptTokens,
+			CompletionTokens: usage.CompletionTokens,
+		}
+		if err != nil {
+			exchange.Error = err.Error()
+		}
+		c.onExchange(exchange)
+	}
+
+	if err != nil {
+		return "", newAPIError(err)
+	}
+	return response, nil
 }
 
-func (c *AIClient) GenerateCompletion(prompt string) (string, error) {
-	resp, err := c.client.CreateChatCompletion(
-		context.Background(),
+// openAICompleter answers completions with the OpenAI chat API.
+type openAICompleter struct {
+	client *openai.Client
+}
+
+func (o *openAICompleter) Complete(ctx context.Context, model, prompt string) (string, Usage, error) {
+	return o.CompleteChat(ctx, model, []Message{UserMessage(prompt)})
+}
+
+func (o *openAICompleter) CompleteChat(ctx context.Context, model string, messages []Message) (string, Usage, error) {
+	chat := []openai.ChatCompletionMessage{
+		{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: "You are a helpful programming assistant that generates code and tests.",
+		},
+	}
+	for _, message := range messages {
+		chat = append(chat, openai.ChatCompletionMessage{Role: message.Role, Content: message.Content})
+	}
+	resp, err := o.client.CreateChatCompletion(
+		ctx,
 		openai.ChatCompletionRequest{
-			Model: "gpt-4o",
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleSystem,
-					Content: "You are a helpful programming assistant that generates code and tests.",
-				},
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: prompt,
-				},
-			},
+			Model:       model,
+			Messages:    chat,
 			Temperature: 0.2,
 		},
 	)
-
 	if err != nil {
-		return "", fmt.Errorf("failed to generate completion: %w", err)
+		return "", Usage{}, err
 	}
 
+	usage := Usage{PromptTokens: resp.Usage.PromptTokens, CompletionTokens: resp.Usage.CompletionTokens}
 	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no completion choices returned")
+		return "", usage, errors.New("no completion choices returned")
 	}
+	return resp.Choices[0].Message.Content, usage, nil
+}
 
-	return resp.Choices[0].Message.Content, nil
+// Ping checks that the provider is reachable and accepts the API key with
+// a cheap models-list request.
+func (c *AIClient) Ping(ctx context.Context) error {
+	if c.client == nil {
+		return nil
+	}
+	if _, err := c.client.ListModels(ctx); err != nil {
+		return newAPIError(err)
+	}
+	return nil
 }
@@ -0,0 +1,42 @@
+package ai
+
+import "strings"
+
+// modelPrice is the cost in USD per million tokens.
+type modelPrice struct {
+	prompt     float64
+	completion float64
+}
+
+// Published list prices for common models, keyed by model name prefix
+var modelPrices = map[string]modelPrice{
+	"gpt-4o-mini":   {prompt: 0.15, completion: 0.60},
+	"gpt-4o":        {prompt: 2.50, completion: 10.00},
+	"gpt-4.1-nano":  {prompt: 0.10, completion: 0.40},
+	"gpt-4.1-mini":  {prompt: 0.40, completion: 1.60},
+	"gpt-4.1":       {prompt: 2.00, completion: 8.00},
+	"gpt-4-turbo":   {prompt: 10.00, completion: 30.00},
+	"gpt-4":         {prompt: 30.00, completion: 60.00},
+	"gpt-3.5-turbo": {prompt: 0.50, completion: 1.50},
+	"o1-mini":       {prompt: 1.10, completion: 4.40},
+	"o1":            {prompt: 15.00, completion: 60.00},
+	"o3-mini":       {prompt: 1.10, completion: 4.40},
+}
+
+// EstimateCost returns the approximate cost in USD of the given usage for a
+// model. The second return value is false when the model's price is unknown.
+func EstimateCost(model string, usage Usage) (float64, bool) {
+	// Prefer the longest matching prefix so "gpt-4o-mini" doesn't match "gpt-4o"
+	var best string
+	for prefix := range modelPrices {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return 0, false
+	}
+	price := modelPrices[best]
+	cost := float64(usage.PromptTokens)*price.prompt/1e6 + float64(usage.CompletionTokens)*price.completion/1e6
+	return cost, true
+}
@@ -0,0 +1,52 @@
+package ai
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// limiter throttles completion requests shared by every caller of an AIClient.
+type limiter struct {
+	rate *rate.Limiter
+	sem  chan struct{}
+}
+
+// newLimiter creates a limiter allowing requestsPerMinute requests, spaced
+// evenly, with at most maxConcurrent in flight. Zero disables either limit.
+func newLimiter(requestsPerMinute, maxConcurrent int) *limiter {
+	l := &limiter{}
+	if requestsPerMinute > 0 {
+		l.rate = rate.NewLimiter(rate.Every(time.Minute/time.Duration(requestsPerMinute)), 1)
+	}
+	if maxConcurrent > 0 {
+		l.sem = make(chan struct{}, maxConcurrent)
+	}
+	return l
+}
+
+// acquire blocks until a request may be sent and returns a function that
+// must be called once the request completes.
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	if l.sem != nil {
+		select {
+		case l.sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {
+		if l.sem != nil {
+			<-l.sem
+		}
+	}
+
+	if l.rate != nil {
+		if err := l.rate.Wait(ctx); err != nil {
+			release()
+			return nil, err
+		}
+	}
+	return release, nil
+}
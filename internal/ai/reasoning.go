@@ -0,0 +1,81 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Reasoning efforts accepted by reasoning models
+var ReasoningEfforts = []string{"low", "medium", "high"}
+
+// reasoningModels are the prefixes of models accepting a reasoning effort
+var reasoningModels = []string{"o1", "o3", "o4", "gpt-5"}
+
+// ValidReasoningEffort reports whether effort is one of ReasoningEfforts.
+func ValidReasoningEffort(effort string) bool {
+	for _, valid := range ReasoningEfforts {
+		if effort == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportsReasoningEffort reports whether model accepts a reasoning effort.
+// The first reasoning previews, o1-mini and o1-preview, don't.
+func SupportsReasoningEffort(model string) bool {
+	if strings.HasPrefix(model, "o1-mini") || strings.HasPrefix(model, "o1-preview") {
+		return false
+	}
+	for _, prefix := range reasoningModels {
+		if model == prefix || strings.HasPrefix(model, prefix+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// reasoningTransport sets the reasoning effort on chat completion requests
+// to models that support it. The client library predates the parameter, so
+// it is added to the request body here. Reasoning models only accept the
+// default temperature, so it is dropped.
+type reasoningTransport struct {
+	effort string
+	base   http.RoundTripper
+}
+
+func (t *reasoningTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodPost || !strings.HasSuffix(req.URL.Path, "/chat/completions") || req.Body == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err == nil {
+		var model string
+		json.Unmarshal(fields["model"], &model)
+		if SupportsReasoningEffort(model) {
+			fields["reasoning_effort"], _ = json.Marshal(t.effort)
+			delete(fields, "temperature")
+			if rewritten, err := json.Marshal(fields); err == nil {
+				body = rewritten
+			}
+		}
+	}
+
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,69 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultMaxRetries is how often a single request is retried after a transient error
+const DefaultMaxRetries = 2
+
+// retryBaseDelay is the wait before the first retry; it doubles for each further retry
+const retryBaseDelay = time.Second
+
+// retryBudget caps the retries spent across every request made by an
+// AIClient, so a degraded provider can't multiply the per-request retries
+// over a whole run.
+type retryBudget struct {
+	mu        sync.Mutex
+	limited   bool
+	remaining int
+}
+
+// newRetryBudget creates a budget of total retries; zero means unlimited.
+func newRetryBudget(total int) *retryBudget {
+	return &retryBudget{limited: total > 0, remaining: total}
+}
+
+// take uses one retry from the budget, reporting false once it's spent.
+func (b *retryBudget) take() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.limited {
+		return true
+	}
+	if b.remaining == 0 {
+		return false
+	}
+	b.remaining--
+	return true
+}
+
+// isRetryable reports whether a failed request may succeed if sent again:
+// rate limiting, provider-side errors and network failures.
+func isRetryable(err *APIError) bool {
+	switch {
+	case err.StatusCode == http.StatusTooManyRequests, err.StatusCode >= 500:
+		return true
+	case err.StatusCode != 0:
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err.Err, &netErr)
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,57 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Exchange is a single completion request and the raw response to it.
+type Exchange struct {
+	Time time.Time `json:"time"`
+	// Phase is what the request was made for, such as "tests" or "fix"
+	Phase            string `json:"phase"`
+	Model            string `json:"model"`
+	Prompt           string `json:"prompt"`
+	Response         string `json:"response"`
+	Error            string `json:"error,omitempty"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+}
+
+// Transcript appends exchanges to a file as JSON lines. It is safe for
+// concurrent use.
+type Transcript struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// OpenTranscript opens the transcript at path, appending to it when it
+// already exists.
+func OpenTranscript(path string) (*Transcript, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open transcript: %w", err)
+	}
+	return &Transcript{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+// Record appends exchange to the transcript.
+func (t *Transcript) Record(exchange Exchange) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.enc.Encode(exchange); err != nil {
+		return fmt.Errorf("failed to write transcript: %w", err)
+	}
+	return nil
+}
+
+// Close closes the transcript file.
+func (t *Transcript) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.file.Close()
+}
@@ -0,0 +1,110 @@
+package executor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Dependency is a module or package pinned to an exact version.
+type Dependency struct {
+	Module  string
+	Version string
+}
+
+// ParseDependency parses a module@version pin.
+func ParseDependency(value string) (Dependency, error) {
+	module, version, ok := strings.Cut(value, "@")
+	module, version = strings.TrimSpace(module), strings.TrimSpace(version)
+	if !ok || module == "" || version == "" {
+		return Dependency{}, fmt.Errorf("invalid dependency %q: expected module@version", value)
+	}
+	return Dependency{Module: module, Version: version}, nil
+}
+
+// defaultGoVersions are the versions of well-known modules fetched when
+// the code imports them and they aren't pinned
+var defaultGoVersions = []Dependency{{Module: "github.com/stretchr/testify", Version: "v1.8.4"}}
+
+// pinFor returns the pinned or default dependency providing the Go
+// package pkg, if any. Pins take precedence over defaults.
+func (r *TestRunner) pinFor(pkg string) (Dependency, bool) {
+	var best Dependency
+	for _, dep := range append(defaultGoVersions, r.opts.Deps...) {
+		if (pkg == dep.Module || strings.HasPrefix(pkg, dep.Module+"/")) && len(dep.Module) >= len(best.Module) {
+			best = dep
+		}
+	}
+	return best, best.Module != ""
+}
+
+// seededModules returns the modules with versions known up front, which
+// are fetched without approval when imported: testify plus every pin.
+func (r *TestRunner) seededModules() map[string]string {
+	versions := make(map[string]string)
+	for _, dep := range append(defaultGoVersions, r.opts.Deps...) {
+		versions[dep.Module] = dep.Version
+	}
+	return versions
+}
+
+// ExternalModules returns the modules Go sources import beyond the
+// standard library and the seeded modules, i.e. what UpdateDependencies
+// would fetch without a known version, sorted.
+func (r *TestRunner) ExternalModules(sources ...string) []string {
+	seeded := r.seededModules()
+	found := make(map[string]bool)
+	for pkg := range goImports(sources...) {
+		if isStandardPackage(pkg) {
+			continue
+		}
+		module := modulePath(pkg)
+		if _, ok := seeded[module]; !ok {
+			found[module] = true
+		}
+	}
+
+	modules := make([]string, 0, len(found))
+	for module := range found {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+	return modules
+}
+
+// modulePath guesses the module of an import path from the hosting
+// conventions of well-known code hosts; other paths are kept whole.
+func modulePath(pkg string) string {
+	parts := strings.Split(pkg, "/")
+	switch {
+	case parts[0] == "gopkg.in" && len(parts) >= 2:
+		return strings.Join(parts[:2], "/")
+	case (parts[0] == "github.com" || parts[0] == "gitlab.com" || parts[0] == "bitbucket.org" || parts[0] == "golang.org") && len(parts) >= 3:
+		return strings.Join(parts[:3], "/")
+	default:
+		return pkg
+	}
+}
+
+// pythonRequirements returns the requirements.txt contents: pytest, unless
+// the tests use unittest, plus every pin, with pins taking precedence.
+func (r *TestRunner) pythonRequirements() string {
+	requirements := map[string]string{}
+	if !r.opts.PythonUnittest {
+		requirements["pytest"] = ">=7.0.0"
+	}
+	for _, dep := range r.opts.Deps {
+		requirements[dep.Module] = "==" + dep.Version
+	}
+	names := make([]string, 0, len(requirements))
+	for name := range requirements {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name + requirements[name] + "\n")
+	}
+	return b.String()
+}
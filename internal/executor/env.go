@@ -0,0 +1,61 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultGoModCache returns the AIterate-managed Go module cache in the
+// user's cache directory, shared by the workspaces of every run.
+func DefaultGoModCache() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get cache directory: %w", err)
+	}
+	return filepath.Join(cacheDir, "aiterate", "gomod"), nil
+}
+
+// goEnv returns the environment for go commands, pointing them at the
+// shared module cache, or nil to inherit the environment unchanged.
+func (r *TestRunner) goEnv() []string {
+	if r.opts.GoModCache == "" {
+		return nil
+	}
+	// Keep the cache writable so it can be removed without go clean -modcache
+	goFlags := strings.TrimSpace(os.Getenv("GOFLAGS") + " -modcacherw")
+	return append(os.Environ(), "GOMODCACHE="+r.opts.GoModCache, "GOFLAGS="+goFlags)
+}
+
+// testEnv returns the environment for test processes: the go environment
+// for Go plus Options.Env, or nil to inherit the environment unchanged.
+func (r *TestRunner) testEnv(language string) []string {
+	var env []string
+	if language == "go" {
+		env = r.goEnv()
+	}
+	if len(r.opts.Env) == 0 {
+		return env
+	}
+	if env == nil {
+		env = os.Environ()
+	}
+	return append(env, r.opts.Env...)
+}
+
+// checkRaceSupport fails when go test -race can't run: the race detector
+// needs cgo, which the go command disables without a C compiler.
+func (r *TestRunner) checkRaceSupport() error {
+	cmd := exec.Command("go", "env", "CGO_ENABLED")
+	cmd.Env = r.testEnv("go")
+	output, err := cmd.Output()
+	if err != nil {
+		return toolchainError("go", err)
+	}
+	if strings.TrimSpace(string(output)) != "1" {
+		return fmt.Errorf("the race detector needs cgo: install a C compiler such as gcc and set CGO_ENABLED=1")
+	}
+	return nil
+}
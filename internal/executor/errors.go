@@ -0,0 +1,39 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// ErrToolchainMissing is returned, wrapped in a *ToolchainError, when a
+// required tool such as go, python or dotnet isn't installed.
+var ErrToolchainMissing = errors.New("toolchain missing")
+
+// ToolchainError reports a tool that couldn't be found on the PATH.
+type ToolchainError struct {
+	Tool string
+	Err  error
+}
+
+func (e *ToolchainError) Error() string {
+	return fmt.Sprintf("%s not found: install it and make sure it is on your PATH: %v", e.Tool, e.Err)
+}
+
+func (e *ToolchainError) Unwrap() error {
+	return e.Err
+}
+
+// Is makes errors.Is(err, ErrToolchainMissing) match any *ToolchainError.
+func (e *ToolchainError) Is(target error) bool {
+	return target == ErrToolchainMissing
+}
+
+// toolchainError converts a "not found" error from running tool into a
+// *ToolchainError, returning other errors unchanged.
+func toolchainError(tool string, err error) error {
+	if errors.Is(err, exec.ErrNotFound) {
+		return &ToolchainError{Tool: tool, Err: err}
+	}
+	return err
+}
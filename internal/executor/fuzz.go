@@ -0,0 +1,85 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// DefaultFuzzTime is how long each fuzz target runs
+const DefaultFuzzTime = 10 * time.Second
+
+// FuzzCrash is an input that made a fuzz target fail.
+type FuzzCrash struct {
+	Target string
+	// Input is the corpus file content go test wrote for the failing input
+	Input string
+	// Output is the go test output for the failing run
+	Output string
+}
+
+var (
+	fuzzTargetPattern   = regexp.MustCompile(`(?m)^func (Fuzz\w*)\(\w+ \*testing\.F\)`)
+	failingInputPattern = regexp.MustCompile(`Failing input written to (\S+)`)
+)
+
+// FuzzTargets returns the names of the fuzz targets in Go test code.
+func FuzzTargets(testCode string) []string {
+	var targets []string
+	for _, match := range fuzzTargetPattern.FindAllStringSubmatch(testCode, -1) {
+		targets = append(targets, match[1])
+	}
+	return targets
+}
+
+// RunFuzz fuzzes each target in turn for fuzzTime and returns the first
+// crash found, or nil when none is. go test keeps the failing input in the
+// workspace's testdata, so later test runs replay it as a regular test.
+// Cancelling ctx stops the fuzzing.
+func (r *TestRunner) RunFuzz(ctx context.Context, targets []string, fuzzTime time.Duration) (*FuzzCrash, error) {
+	if fuzzTime <= 0 {
+		fuzzTime = DefaultFuzzTime
+	}
+	for _, target := range targets {
+		// -fuzz must match exactly one target
+		pattern := "^" + target + "$"
+		r.info("Running go test -run=^$ -fuzz=%s -fuzztime=%s", pattern, fuzzTime)
+		cmd := exec.CommandContext(ctx, "go", "test", "-run=^$", "-fuzz="+pattern, "-fuzztime="+fuzzTime.String(), ".")
+		cmd.WaitDelay = cancelWaitDelay
+		cmd.Dir = r.workDir
+		cmd.Env = r.testEnv("go")
+		var output bytes.Buffer
+		cmd.Stdout = &output
+		cmd.Stderr = &output
+
+		err := cmd.Run()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, toolchainError("go", err)
+		}
+		if err == nil {
+			r.success("No crashes found by %s", target)
+			continue
+		}
+
+		crash := &FuzzCrash{Target: target, Output: output.String()}
+		if match := failingInputPattern.FindStringSubmatch(crash.Output); match != nil {
+			input, err := os.ReadFile(filepath.Join(r.workDir, match[1]))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read failing fuzz input: %w", err)
+			}
+			crash.Input = string(input)
+		}
+		r.warn("%s found a failing input", target)
+		return crash, nil
+	}
+	return nil, nil
+}
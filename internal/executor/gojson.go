@@ -0,0 +1,84 @@
+package executor
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Test statuses reported in TestCase.Status
+const (
+	StatusPass = "pass"
+	StatusFail = "fail"
+	StatusSkip = "skip"
+)
+
+// TestCase is the outcome of a single test, as reported by structured test output.
+type TestCase struct {
+	Package string
+	Name    string
+	Status  string
+	Elapsed time.Duration
+}
+
+// goTestEvent is a line of `go test -json` output.
+type goTestEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Output  string
+	Elapsed float64
+}
+
+// parseGoTestJSON parses `go test -json` output into per-test results and
+// the plain text output the events carry. Lines that aren't JSON, such as
+// build errors, are kept in the text as-is. ok is false when the output
+// contains no test events, so callers can fall back to text parsing.
+func parseGoTestJSON(raw string) (text string, cases []TestCase, ok bool) {
+	var b strings.Builder
+	for _, line := range strings.Split(raw, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "{") {
+			if line != "" {
+				b.WriteString(line + "\n")
+			}
+			continue
+		}
+
+		var event goTestEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			b.WriteString(line + "\n")
+			continue
+		}
+		ok = true
+
+		switch event.Action {
+		case "output", "build-output":
+			b.WriteString(event.Output)
+		case StatusPass, StatusFail, StatusSkip:
+			if event.Test != "" {
+				cases = append(cases, TestCase{
+					Package: event.Package,
+					Name:    event.Test,
+					Status:  event.Action,
+					Elapsed: time.Duration(event.Elapsed * float64(time.Second)),
+				})
+			}
+		}
+	}
+	return b.String(), cases, ok
+}
+
+// countCases tallies test cases by status.
+func countCases(cases []TestCase) (passed, failed, skipped int) {
+	for _, c := range cases {
+		switch c.Status {
+		case StatusPass:
+			passed++
+		case StatusFail:
+			failed++
+		case StatusSkip:
+			skipped++
+		}
+	}
+	return passed, failed, skipped
+}
@@ -0,0 +1,55 @@
+package executor
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// goSumErrors are go command messages about checksums that are missing or
+// don't match, which come from the module cache or go.sum rather than the code
+var goSumErrors = []string{
+	"missing go.sum entry",
+	"updates to go.sum needed",
+	"checksum mismatch",
+	"SECURITY ERROR",
+	"verifying module:",
+	"verifying go.mod:",
+}
+
+// isGoSumError reports whether go command output shows a go.sum or
+// checksum verification failure.
+func isGoSumError(output string) bool {
+	for _, message := range goSumErrors {
+		if strings.Contains(output, message) {
+			return true
+		}
+	}
+	return false
+}
+
+// healGoSum rebuilds the workspace's go.sum from scratch: it removes the
+// file, downloads the required modules again and tidies the module.
+func (r *TestRunner) healGoSum() error {
+	if err := os.Remove(filepath.Join(r.workDir, "go.sum")); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove go.sum: %w", err)
+	}
+	for _, args := range [][]string{{"mod", "download"}, {"mod", "tidy"}} {
+		r.info("Running go %s...", strings.Join(args, " "))
+		cmd := exec.Command("go", args...)
+		cmd.Dir = r.workDir
+		cmd.Env = r.goEnv()
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			r.failure("Failed to run go %s: %v\nOutput: %s\nError: %s",
+				strings.Join(args, " "), err, stdout.String(), stderr.String())
+			return fmt.Errorf("failed to run go %s: %w", strings.Join(args, " "), toolchainError("go", err))
+		}
+	}
+	return nil
+}
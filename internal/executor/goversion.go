@@ -0,0 +1,56 @@
+package executor
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+)
+
+// goVersionOutput matches the toolchain version in `go version` output,
+// e.g. "go version go1.21.5 linux/amd64"
+var goVersionOutput = regexp.MustCompile(`\bgo1\.(\d+)(?:\.(\d+))?`)
+
+// goDirective matches a go directive version such as 1.21 or 1.21.3
+var goDirective = regexp.MustCompile(`^1\.(\d+)(?:\.(\d+))?$`)
+
+// installedGoVersion returns the minor and patch version of the go command.
+func (r *TestRunner) installedGoVersion() (minor, patch int, err error) {
+	cmd := exec.Command("go", "version")
+	cmd.Env = r.goEnv()
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, 0, toolchainError("go", err)
+	}
+	match := goVersionOutput.FindStringSubmatch(string(output))
+	if match == nil {
+		return 0, 0, fmt.Errorf("unexpected go version output: %s", output)
+	}
+	minor, _ = strconv.Atoi(match[1])
+	patch, _ = strconv.Atoi(match[2])
+	return minor, patch, nil
+}
+
+// clampGoVersion returns version, the go directive of a workspace, lowered
+// to the installed toolchain's when it's newer. Otherwise the go command
+// refuses to build, or tries to download a newer toolchain, which fails
+// offline.
+func (r *TestRunner) clampGoVersion(version string) string {
+	match := goDirective.FindStringSubmatch(version)
+	if match == nil {
+		return version
+	}
+	minor, patch, err := r.installedGoVersion()
+	if err != nil {
+		r.warn("Could not check the installed Go version: %v", err)
+		return version
+	}
+	wantMinor, _ := strconv.Atoi(match[1])
+	wantPatch, _ := strconv.Atoi(match[2])
+	if wantMinor < minor || (wantMinor == minor && wantPatch <= patch) {
+		return version
+	}
+	clamped := fmt.Sprintf("1.%d", minor)
+	r.warn("go %s is newer than the installed Go toolchain (1.%d.%d); using go %s in go.mod", version, minor, patch, clamped)
+	return clamped
+}
@@ -0,0 +1,56 @@
+package executor
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+)
+
+// Level is the kind of a TestRunner progress message.
+type Level int
+
+const (
+	LevelInfo Level = iota
+	LevelWarn
+	LevelSuccess
+	LevelFailure
+)
+
+// Logger receives a TestRunner's progress messages.
+type Logger func(level Level, message string)
+
+// log sends a progress message to Options.Logger, or prints it in color
+// to stdout without one.
+func (r *TestRunner) log(level Level, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	if r.opts.Logger != nil {
+		r.opts.Logger(level, message)
+		return
+	}
+	switch level {
+	case LevelWarn:
+		color.Yellow("%s", message)
+	case LevelSuccess:
+		color.Green("%s", message)
+	case LevelFailure:
+		color.Red("%s", message)
+	default:
+		color.Blue("%s", message)
+	}
+}
+
+func (r *TestRunner) info(format string, args ...interface{}) {
+	r.log(LevelInfo, format, args...)
+}
+
+func (r *TestRunner) warn(format string, args ...interface{}) {
+	r.log(LevelWarn, format, args...)
+}
+
+func (r *TestRunner) success(format string, args ...interface{}) {
+	r.log(LevelSuccess, format, args...)
+}
+
+func (r *TestRunner) failure(format string, args ...interface{}) {
+	r.log(LevelFailure, format, args...)
+}
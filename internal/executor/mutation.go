@@ -0,0 +1,134 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// MaxMutants caps how many mutants are run per check
+const MaxMutants = 8
+
+// Mutant is a copy of the implementation with one small deliberate bug.
+type Mutant struct {
+	Description string
+	Code        string
+}
+
+// Operator swaps that keep the code compiling but change its behavior
+var mutationOps = map[token.Token]token.Token{
+	token.EQL:  token.NEQ,
+	token.NEQ:  token.EQL,
+	token.LSS:  token.LEQ,
+	token.LEQ:  token.LSS,
+	token.GTR:  token.GEQ,
+	token.GEQ:  token.GTR,
+	token.LAND: token.LOR,
+	token.LOR:  token.LAND,
+	token.SUB:  token.ADD,
+}
+
+// GenerateGoMutants returns up to MaxMutants mutants of Go source code, each
+// flipping a single comparison, logical or arithmetic operator.
+func GenerateGoMutants(code string) ([]Mutant, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "main.go", code, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse implementation: %w", err)
+	}
+
+	var mutants []Mutant
+	ast.Inspect(file, func(n ast.Node) bool {
+		expr, ok := n.(*ast.BinaryExpr)
+		if !ok {
+			return true
+		}
+		replacement, ok := mutationOps[expr.Op]
+		if !ok {
+			return true
+		}
+
+		pos := fset.Position(expr.OpPos)
+		original := expr.Op.String()
+		mutated := code[:pos.Offset] + replacement.String() + code[pos.Offset+len(original):]
+		mutants = append(mutants, Mutant{
+			Description: fmt.Sprintf("line %d: `%s` changed to `%s`", pos.Line, original, replacement),
+			Code:        mutated,
+		})
+		return true
+	})
+
+	// Spread the sample across the file when there are too many candidates
+	if len(mutants) > MaxMutants {
+		sampled := make([]Mutant, 0, MaxMutants)
+		for i := 0; i < MaxMutants; i++ {
+			sampled = append(sampled, mutants[i*len(mutants)/MaxMutants])
+		}
+		mutants = sampled
+	}
+	return mutants, nil
+}
+
+// FindSurvivingMutants runs the tests against each mutant in a scratch copy
+// of the workspace and returns the mutants the tests failed to catch.
+func (r *TestRunner) FindSurvivingMutants(ctx context.Context, language, implFile string, mutants []Mutant) ([]Mutant, error) {
+	scratchDir, err := os.MkdirTemp("", "aiterate-mutants-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mutation workspace: %w", err)
+	}
+	defer os.RemoveAll(scratchDir)
+
+	if err := copyDir(r.workDir, scratchDir); err != nil {
+		return nil, fmt.Errorf("failed to copy workspace: %w", err)
+	}
+
+	// Keep the mutant runs quiet; only the summary matters
+	quiet := r.opts
+	quiet.Logger = func(Level, string) {}
+	scratch := NewTestRunner(scratchDir, quiet)
+	scratch.language = r.language
+	var survivors []Mutant
+	for _, mutant := range mutants {
+		if err := os.WriteFile(filepath.Join(scratchDir, implFile), []byte(mutant.Code), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write mutant: %w", err)
+		}
+		result, err := scratch.RunTests(ctx, language)
+		if err != nil {
+			return nil, err
+		}
+		if result.Success {
+			survivors = append(survivors, mutant)
+		}
+	}
+	return survivors, nil
+}
+
+// copyDir recursively copies the regular files and directories under src into dst.
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, 0644)
+	})
+}
@@ -0,0 +1,121 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// MainTimeout bounds a RunMain smoke test, so a program waiting for input
+// or looping forever doesn't stall the run
+const MainTimeout = 30 * time.Second
+
+// MainResult is the outcome of running the generated program once.
+type MainResult struct {
+	// Command is the command line that was run
+	Command  string
+	ExitCode int
+	// TimedOut is set when the program was killed after MainTimeout
+	TimedOut bool
+	Stdout   string
+	Stderr   string
+}
+
+// Success reports whether the program exited cleanly.
+func (r *MainResult) Success() bool {
+	return r.ExitCode == 0 && !r.TimedOut
+}
+
+// CanRunMain reports whether RunMain supports a language.
+func CanRunMain(language string) bool {
+	return mainCommand(language) != nil
+}
+
+// mainBinary is the program go build writes in the workspace, so its own
+// exit code is reported rather than go run's
+const mainBinary = "aiterate-main"
+
+// mainCommand returns the command that runs a language's implementation
+// as a program.
+func mainCommand(language string) []string {
+	switch language {
+	case "go":
+		return []string{"./" + mainBinary}
+	case "python":
+		return []string{"python", "main.py"}
+	case "php":
+		return []string{"php", "Main.php"}
+	case "bash":
+		return []string{"bash", "main.sh"}
+	default:
+		return nil
+	}
+}
+
+// RunMain builds and runs the implementation once as a program with args,
+// with no input on stdin. Cancelling ctx stops the build or the program.
+func (r *TestRunner) RunMain(ctx context.Context, language string, args []string) (*MainResult, error) {
+	command := mainCommand(language)
+	if command == nil {
+		return nil, fmt.Errorf("running the program is not supported for %s", language)
+	}
+	command = append(command, args...)
+
+	if language == "go" {
+		r.info("Running go build -o %s .", mainBinary)
+		build := exec.CommandContext(ctx, "go", "build", "-o", mainBinary, ".")
+		build.WaitDelay = cancelWaitDelay
+		build.Dir = r.workDir
+		build.Env = r.goEnv()
+		var output bytes.Buffer
+		build.Stdout = &output
+		build.Stderr = &output
+		if err := build.Run(); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if errors.Is(err, exec.ErrNotFound) {
+				return nil, toolchainError("go", err)
+			}
+			return &MainResult{Command: "go build .", ExitCode: build.ProcessState.ExitCode(), Stderr: output.String()}, nil
+		}
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, MainTimeout)
+	defer cancel()
+
+	result := &MainResult{Command: strings.Join(command, " ")}
+	r.info("Running %s", result.Command)
+	cmd := exec.CommandContext(runCtx, command[0], command[1:]...)
+	cmd.WaitDelay = cancelWaitDelay
+	cmd.Dir = r.workDir
+	cmd.Env = r.testEnv(language)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, toolchainError(command[0], err)
+	}
+	result.Stdout, result.Stderr = stdout.String(), stderr.String()
+
+	var exitErr *exec.ExitError
+	switch {
+	case runCtx.Err() != nil:
+		result.TimedOut = true
+		result.ExitCode = -1
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	case err != nil:
+		return nil, fmt.Errorf("failed to run %s: %w", result.Command, err)
+	}
+	return result, nil
+}
@@ -2,124 +2,549 @@ package executor
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
-
-	"github.com/fatih/color"
+	"time"
 )
 
+// cancelWaitDelay is how long a cancelled test run waits for the output of
+// processes left behind by the killed test command
+const cancelWaitDelay = 2 * time.Second
+
 type TestResult struct {
 	Success bool
-	Output  string
+	// Output is Stdout followed by Stderr
+	Output string
+	// Stdout holds the test log (decoded from JSON with go test -json)
+	Stdout string
+	// Stderr holds what the toolchain wrote to stderr, typically build errors
+	Stderr  string
 	Error   error
+	Passed  int
+	Failed  int
+	Skipped int
+	// Tests holds per-test results when the runner reports them (go test -json)
+	Tests []TestCase
 }
 
+// Options controls how tests are run.
+type Options struct {
+	// GradleDaemon keeps a Gradle daemon alive between iterations for faster Kotlin builds
+	GradleDaemon bool
+	// Env holds extra KEY=VALUE variables set only for the test process
+	Env []string
+	// GoJSON runs Go tests with -json for exact per-test results
+	GoJSON bool
+	// Deps pins dependency versions: Go modules in go.mod, packages in requirements.txt
+	Deps []Dependency
+	// PythonUnittest runs Python tests with the standard library's unittest instead of pytest
+	PythonUnittest bool
+	// GoModCache, when set, is the module cache for go commands, so
+	// downloads are reused across workspaces
+	GoModCache string
+	// WorkspaceDir, when set, is where PrepareWorkspace creates the
+	// workspace instead of a randomly named temp directory
+	WorkspaceDir string
+	// MultiFileTests runs every *_test.py file for Python instead of
+	// main_test.py alone; Go runs all test files either way
+	MultiFileTests bool
+	// GoTestTimeout, when set, is passed to go test -timeout so that hung
+	// tests fail the run instead of stalling it
+	GoTestTimeout time.Duration
+	// GoRace runs Go tests with the race detector, so data races fail them
+	GoRace bool
+	// TestCommand, when set, replaces the language's test command. It is
+	// run by sh in the workspace and passes when it exits with status 0
+	TestCommand string
+	// GoVersion, when set, is the go directive of Go workspaces instead of
+	// workspaceGoVersion, so the go command compiles with that version's
+	// language semantics. Either is lowered to the installed toolchain's
+	GoVersion string
+	// Logger, when set, receives the runner's progress messages instead of
+	// them being printed to stdout
+	Logger Logger
+}
+
+// workspaceGoVersion is the go directive of workspace modules; generics
+// need at least 1.18, and cmp.Ordered 1.21
+const workspaceGoVersion = "1.21"
+
 type TestRunner struct {
 	workDir string
+	opts    Options
+	// language is the language the workspace was prepared for, if known
+	language string
 }
 
-func NewTestRunner(workDir string) *TestRunner {
-	return &TestRunner{workDir: workDir}
+func NewTestRunner(workDir string, opts Options) *TestRunner {
+	return &TestRunner{workDir: workDir, opts: opts}
 }
 
-func (r *TestRunner) RunTests(language string) (*TestResult, error) {
-	color.Blue("Running tests in directory: %s", r.workDir)
-	
+// RunTests runs the workspace's tests. A Go run failing on go.sum or
+// checksum verification is an environment problem the code can't fix, so
+// the module checksums are rebuilt and the tests run once more. Cancelling
+// ctx kills the test process and returns ctx's error.
+func (r *TestRunner) RunTests(ctx context.Context, language string) (*TestResult, error) {
+	result, err := r.runTests(ctx, language)
+	if err != nil || result.Success || language != "go" || !isGoSumError(result.Output) {
+		return result, err
+	}
+	r.warn("go.sum verification failed; rebuilding module checksums and retrying...")
+	if err := r.healGoSum(); err != nil {
+		r.warn("Could not rebuild module checksums: %v", err)
+		return result, nil
+	}
+	return r.runTests(ctx, language)
+}
+
+func (r *TestRunner) runTests(ctx context.Context, language string) (*TestResult, error) {
+	if r.language != "" && language != r.language {
+		return nil, fmt.Errorf("workspace was prepared for %s, cannot run %s tests", r.language, language)
+	}
+
+	r.info("Running tests in directory: %s", r.workDir)
+
+	if r.opts.TestCommand != "" {
+		// Through the shell, so the command may use arguments, pipes and
+		// variables, e.g. "make test"
+		r.info("Running %s", r.opts.TestCommand)
+		return r.execTests(ctx, exec.CommandContext(ctx, "sh", "-c", r.opts.TestCommand), language)
+	}
+
 	var cmd *exec.Cmd
 	switch language {
 	case "go":
-		color.Blue("Running go test -v ./...")
-		cmd = exec.Command("go", "test", "-v", "./...")
+		args := []string{"test", "-v"}
+		if r.opts.GoJSON {
+			args[1] = "-json"
+		}
+		if r.opts.GoRace {
+			args = append(args, "-race")
+		}
+		if r.opts.GoTestTimeout > 0 {
+			args = append(args, "-timeout="+r.opts.GoTestTimeout.String())
+		}
+		args = append(args, "./...")
+		r.info("Running go %s", strings.Join(args, " "))
+		cmd = exec.CommandContext(ctx, "go", args...)
 	case "python":
-		color.Blue("Running python -m pytest main_test.py -v")
-		cmd = exec.Command("python", "-m", "pytest", "main_test.py", "-v")
+		switch {
+		case r.opts.PythonUnittest && r.opts.MultiFileTests:
+			r.info("Running python -m unittest discover -v -p '*_test.py'")
+			cmd = exec.CommandContext(ctx, "python", "-m", "unittest", "discover", "-v", "-p", "*_test.py")
+		case r.opts.PythonUnittest:
+			r.info("Running python -m unittest -v main_test")
+			cmd = exec.CommandContext(ctx, "python", "-m", "unittest", "-v", "main_test")
+		case r.opts.MultiFileTests:
+			// pytest collects *_test.py files by default
+			r.info("Running python -m pytest -v")
+			cmd = exec.CommandContext(ctx, "python", "-m", "pytest", "-v")
+		default:
+			r.info("Running python -m pytest main_test.py -v")
+			cmd = exec.CommandContext(ctx, "python", "-m", "pytest", "main_test.py", "-v")
+		}
+	case "php":
+		r.info("Running vendor/bin/phpunit MainTest.php")
+		cmd = exec.CommandContext(ctx, "vendor/bin/phpunit", "MainTest.php")
+	case "csharp":
+		r.info("Running dotnet test")
+		cmd = exec.CommandContext(ctx, "dotnet", "test", "--nologo")
+	case "kotlin":
+		daemonFlag := "--no-daemon"
+		if r.opts.GradleDaemon {
+			daemonFlag = "--daemon"
+		}
+		r.info("Running gradle test %s", daemonFlag)
+		cmd = exec.CommandContext(ctx, "gradle", "test", "--console=plain", daemonFlag)
+	case "swift":
+		r.info("Running swift test")
+		cmd = exec.CommandContext(ctx, "swift", "test")
+	case "bash":
+		r.info("Running bats --tap main_test.bats")
+		cmd = exec.CommandContext(ctx, "bats", "--tap", "main_test.bats")
+	case "elixir":
+		// Compiler warnings fail the run so they reach the fix loop too
+		r.info("Running mix test --warnings-as-errors")
+		cmd = exec.CommandContext(ctx, "mix", "test", "--warnings-as-errors")
+	case "dart":
+		// Compile errors are reported as failures to load the test file
+		r.info("Running dart test --reporter expanded")
+		cmd = exec.CommandContext(ctx, "dart", "test", "--reporter", "expanded")
 	default:
 		return nil, fmt.Errorf("unsupported language: %s", language)
 	}
-	
+	return r.execTests(ctx, cmd, language)
+}
+
+// execTests runs a test command in the workspace and collects its result.
+func (r *TestRunner) execTests(ctx context.Context, cmd *exec.Cmd, language string) (*TestResult, error) {
 	cmd.Dir = r.workDir
+	// Processes started by the test command can keep its output open after
+	// it is killed; don't wait for them once ctx is cancelled
+	cmd.WaitDelay = cancelWaitDelay
+	cmd.Env = r.testEnv(language)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 	
 	err := cmd.Run()
-	output := stdout.String() + stderr.String()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, toolchainError(cmd.Args[0], err)
+	}
+	stdoutText, stderrText := stdout.String(), stderr.String()
+	output := stdoutText + stderrText
+	passed, failed := countResults(language, output)
+	var skipped int
+	var cases []TestCase
+	if language == "go" && r.opts.GoJSON {
+		if text, parsed, ok := parseGoTestJSON(stdoutText); ok {
+			stdoutText = text
+			output = stdoutText + stderrText
+			cases = parsed
+			passed, failed, skipped = countCases(cases)
+		} else {
+			r.warn("Could not parse go test -json output; counting results from text")
+		}
+	}
 	
 	if err != nil {
-		color.Yellow("Tests failed: %v", err)
-		color.Yellow("Tests failed. Test output:")
-		fmt.Println(output)
+		// The output is in the result, for the caller to show
+		r.warn("Tests failed: %v", err)
 		return &TestResult{
 			Success: false,
 			Output:  output,
+			Stdout:  stdoutText,
+			Stderr:  stderrText,
+			Passed:  passed,
+			Failed:  failed,
+			Skipped: skipped,
+			Tests:   cases,
 		}, nil
 	}
 	
 	return &TestResult{
 		Success: true,
 		Output:  output,
+		Stdout:  stdoutText,
+		Stderr:  stderrText,
+		Passed:  passed,
+		Failed:  failed,
+		Skipped: skipped,
+		Tests:   cases,
 	}, nil
 }
 
+// RunVet runs go vet in the workspace. The result's Output holds the
+// reported issues; Success is false when there are any.
+func (r *TestRunner) RunVet(ctx context.Context) (*TestResult, error) {
+	r.info("Running go vet ./...")
+	cmd := exec.CommandContext(ctx, "go", "vet", "./...")
+	cmd.WaitDelay = cancelWaitDelay
+	cmd.Dir = r.workDir
+	cmd.Env = r.testEnv("go")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, toolchainError("go", err)
+	}
+	return &TestResult{
+		Success: err == nil,
+		Output:  stdout.String() + stderr.String(),
+		Stdout:  stdout.String(),
+		Stderr:  stderr.String(),
+	}, nil
+}
+
+// goCoveragePattern matches the statement coverage go test -cover reports per package
+var goCoveragePattern = regexp.MustCompile(`coverage: (\d+(?:\.\d+)?)% of statements`)
+
+// RunCoverage runs the Go tests with -cover and returns the percentage of
+// statements they cover.
+func (r *TestRunner) RunCoverage(ctx context.Context) (float64, error) {
+	r.info("Running go test -cover ./...")
+	cmd := exec.CommandContext(ctx, "go", "test", "-cover", "./...")
+	cmd.WaitDelay = cancelWaitDelay
+	cmd.Dir = r.workDir
+	cmd.Env = r.testEnv("go")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		return 0, fmt.Errorf("failed to run go test -cover: %w\n%s", toolchainError("go", err), stdout.String()+stderr.String())
+	}
+	match := goCoveragePattern.FindStringSubmatch(stdout.String())
+	if match == nil {
+		return 0, fmt.Errorf("no coverage reported by go test -cover")
+	}
+	return strconv.ParseFloat(match[1], 64)
+}
+
+// countResults counts passing and failing tests in verbose test output.
+func countResults(language, output string) (passed, failed int) {
+	switch language {
+	case "php":
+		return countPHPUnitResults(output)
+	case "csharp":
+		return countDotnetResults(output)
+	case "elixir":
+		return countMixResults(output)
+	case "dart":
+		return countDartResults(output)
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch language {
+		case "go":
+			if strings.HasPrefix(line, "--- PASS:") {
+				passed++
+			} else if strings.HasPrefix(line, "--- FAIL:") {
+				failed++
+			}
+		case "bash":
+			// TAP: "ok 1 name", "not ok 2 name", "ok 3 name # skip"
+			if strings.HasPrefix(line, "not ok ") {
+				failed++
+			} else if strings.HasPrefix(line, "ok ") && !strings.Contains(line, "# skip") {
+				passed++
+			}
+		case "swift":
+			// XCTest: Test Case '-[SolutionTests.SolutionTests testAdd]' passed (0.001 seconds).
+			if strings.HasPrefix(line, "Test Case '") {
+				if strings.Contains(line, "' passed") {
+					passed++
+				} else if strings.Contains(line, "' failed") {
+					failed++
+				}
+			}
+		case "kotlin":
+			if strings.HasSuffix(line, " PASSED") {
+				passed++
+			} else if strings.HasSuffix(line, " FAILED") {
+				failed++
+			}
+		case "python":
+			// pytest: "main_test.py::test_add PASSED"; unittest: "test_add (main_test.TestAdd) ... ok"
+			if strings.Contains(line, " PASSED") || strings.HasSuffix(line, " ... ok") {
+				passed++
+			} else if strings.Contains(line, " FAILED") || strings.Contains(line, " ERROR") || strings.HasSuffix(line, " ... FAIL") {
+				failed++
+			}
+		}
+	}
+	return passed, failed
+}
+
+var (
+	phpunitOKRegex      = regexp.MustCompile(`OK \((\d+) tests?`)
+	phpunitSummaryRegex = regexp.MustCompile(`Tests: (\d+)`)
+	phpunitCountRegex   = regexp.MustCompile(`(Failures|Errors): (\d+)`)
+)
+
+// countPHPUnitResults reads pass/fail counts from PHPUnit's summary line,
+// e.g. "OK (5 tests, 9 assertions)" or "Tests: 5, Assertions: 9, Failures: 2."
+func countPHPUnitResults(output string) (passed, failed int) {
+	if match := phpunitOKRegex.FindStringSubmatch(output); match != nil {
+		passed, _ = strconv.Atoi(match[1])
+		return passed, 0
+	}
+
+	match := phpunitSummaryRegex.FindStringSubmatch(output)
+	if match == nil {
+		return 0, 0
+	}
+	total, _ := strconv.Atoi(match[1])
+	for _, count := range phpunitCountRegex.FindAllStringSubmatch(output, -1) {
+		n, _ := strconv.Atoi(count[2])
+		failed += n
+	}
+	if failed > total {
+		failed = total
+	}
+	return total - failed, failed
+}
+
+var dotnetSummaryRegex = regexp.MustCompile(`Failed:\s+(\d+), Passed:\s+(\d+)`)
+
+// countDotnetResults reads pass/fail counts from dotnet test's summary line,
+// e.g. "Failed!  - Failed:     2, Passed:     3, Skipped:     0, Total:     5".
+func countDotnetResults(output string) (passed, failed int) {
+	match := dotnetSummaryRegex.FindStringSubmatch(output)
+	if match == nil {
+		return 0, 0
+	}
+	failed, _ = strconv.Atoi(match[1])
+	passed, _ = strconv.Atoi(match[2])
+	return passed, failed
+}
+
+var mixSummaryRegex = regexp.MustCompile(`(\d+) tests?, (\d+) failures?`)
+
+// countMixResults reads pass/fail counts from mix test's summary line,
+// e.g. "1 doctest, 5 tests, 2 failures"; doctests are counted separately.
+func countMixResults(output string) (passed, failed int) {
+	match := mixSummaryRegex.FindStringSubmatch(output)
+	if match == nil {
+		return 0, 0
+	}
+	total, _ := strconv.Atoi(match[1])
+	failed, _ = strconv.Atoi(match[2])
+	if failed > total {
+		failed = total
+	}
+	return total - failed, failed
+}
+
+var dartProgressRegex = regexp.MustCompile(`(?m)^\d+:\d+ \+(\d+)(?: ~\d+)?(?: -(\d+))?:`)
+
+// countDartResults reads pass/fail counts from the last progress line of
+// dart test's expanded reporter, e.g. "00:02 +3 ~1 -2: Some tests failed.".
+func countDartResults(output string) (passed, failed int) {
+	matches := dartProgressRegex.FindAllStringSubmatch(output, -1)
+	if matches == nil {
+		return 0, 0
+	}
+	last := matches[len(matches)-1]
+	passed, _ = strconv.Atoi(last[1])
+	if last[2] != "" {
+		failed, _ = strconv.Atoi(last[2])
+	}
+	return passed, failed
+}
+
+// WorkspaceRoot is the directory holding per-session workspaces.
+func WorkspaceRoot() string {
+	return filepath.Join(os.TempDir(), "aiterate")
+}
+
+// SessionWorkspaceDir returns the predictable workspace location for a
+// session, for use as Options.WorkspaceDir.
+func SessionWorkspaceDir(sessionID string) string {
+	return filepath.Join(WorkspaceRoot(), sessionID)
+}
+
+// PrepareWorkspace creates a temporary workspace set up for language and
+// points the runner at it. Later runs must use the same language.
 func (r *TestRunner) PrepareWorkspace(language string) (string, error) {
 	// Create a temporary directory for this run
-	tmpDir, err := os.MkdirTemp("", "aiterate-*")
+	tmpDir, err := r.createWorkspaceDir()
 	if err != nil {
 		return "", fmt.Errorf("failed to create workspace: %w", err)
 	}
-	color.Blue("Created temporary workspace: %s", tmpDir)
+	r.info("Created temporary workspace: %s", tmpDir)
 
 	switch language {
 	case "go":
+		if r.opts.GoRace {
+			if err := r.checkRaceSupport(); err != nil {
+				os.RemoveAll(tmpDir)
+				return "", err
+			}
+		}
 		if err := r.initGoModule(tmpDir); err != nil {
 			os.RemoveAll(tmpDir) // Clean up on failure
 			return "", err
 		}
 		
-		// Create a go.mod file with common dependencies
-		goMod := `module temp
-
-go 1.21
-
-require (
-	github.com/stretchr/testify v1.8.4
-)
-`
+		// Start without requirements; UpdateDependencies adds the modules
+		// the code imports, so stdlib-only code keeps a minimal go.mod
+		goVersion := workspaceGoVersion
+		if r.opts.GoVersion != "" {
+			goVersion = r.opts.GoVersion
+		}
+		goVersion = r.clampGoVersion(goVersion)
+		goMod := "module temp\n\ngo " + goVersion + "\n"
 		if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte(goMod), 0644); err != nil {
 			os.RemoveAll(tmpDir)
 			return "", fmt.Errorf("failed to write go.mod: %w", err)
 		}
-
-		// Run go mod tidy to download dependencies
-		cmd := exec.Command("go", "mod", "tidy")
-		cmd.Dir = tmpDir
-		var stdout, stderr bytes.Buffer
-		cmd.Stdout = &stdout
-		cmd.Stderr = &stderr
-		
-		if err := cmd.Run(); err != nil {
-			color.Red("Failed to run go mod tidy: %v\nOutput: %s\nError: %s",
-				err, stdout.String(), stderr.String())
+	case "python":
+		if err := r.initPythonEnv(tmpDir); err != nil {
+			return "", err
+		}
+	case "php":
+		if err := r.initPHPProject(tmpDir); err != nil {
 			os.RemoveAll(tmpDir)
 			return "", err
 		}
-	case "python":
-		if err := r.initPythonEnv(tmpDir); err != nil {
+	case "csharp":
+		if err := r.initDotnetProject(tmpDir); err != nil {
+			os.RemoveAll(tmpDir)
+			return "", err
+		}
+	case "kotlin":
+		if err := r.initGradleProject(tmpDir); err != nil {
+			os.RemoveAll(tmpDir)
+			return "", err
+		}
+	case "swift":
+		if err := r.initSwiftPackage(tmpDir); err != nil {
+			os.RemoveAll(tmpDir)
+			return "", err
+		}
+	case "elixir":
+		if err := r.initMixProject(tmpDir); err != nil {
+			os.RemoveAll(tmpDir)
+			return "", err
+		}
+	case "dart":
+		if err := r.initDartPackage(tmpDir); err != nil {
+			os.RemoveAll(tmpDir)
 			return "", err
 		}
+	case "bash":
+		// bats runs the tests directly; it only needs to be installed
+		if _, err := exec.LookPath("bats"); err != nil {
+			os.RemoveAll(tmpDir)
+			return "", toolchainError("bats", err)
+		}
 	}
 
+	r.workDir = tmpDir
+	r.language = language
 	return tmpDir, nil
 }
 
+// createWorkspaceDir creates Options.WorkspaceDir, which must not exist
+// yet, or a randomly named temp directory when it's unset.
+func (r *TestRunner) createWorkspaceDir() (string, error) {
+	if r.opts.WorkspaceDir == "" {
+		return os.MkdirTemp("", "aiterate-*")
+	}
+	if err := os.MkdirAll(filepath.Dir(r.opts.WorkspaceDir), 0755); err != nil {
+		return "", err
+	}
+	if err := os.Mkdir(r.opts.WorkspaceDir, 0755); err != nil {
+		return "", err
+	}
+	return r.opts.WorkspaceDir, nil
+}
+
 func (r *TestRunner) initGoModule(dir string) error {
-	color.Blue("Initializing Go module in: %s", dir)
+	r.info("Initializing Go module in: %s", dir)
 	cmd := exec.Command("go", "mod", "init", "temp")
 	cmd.Dir = dir
+	cmd.Env = r.goEnv()
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -127,25 +552,31 @@ func (r *TestRunner) initGoModule(dir string) error {
 
 	err := cmd.Run()
 	if err != nil {
-		color.Red("Failed to initialize Go module: %v\nOutput: %s\nError: %s",
+		r.failure("Failed to initialize Go module: %v\nOutput: %s\nError: %s",
 			err, stdout.String(), stderr.String())
-		return err
+		return toolchainError("go", err)
 	}
 
-	color.Green("Successfully initialized Go module")
+	r.success("Successfully initialized Go module")
 	return nil
 }
 
 func (r *TestRunner) initPythonEnv(dir string) error {
 	// Create requirements.txt
 	requirementsPath := filepath.Join(dir, "requirements.txt")
-	requirements := []byte("pytest>=7.0.0\n")
+	requirements := []byte(r.pythonRequirements())
 	if err := os.WriteFile(requirementsPath, requirements, 0644); err != nil {
 		return fmt.Errorf("failed to create requirements.txt: %w", err)
 	}
 
+	// unittest needs nothing installed unless dependencies are pinned
+	if len(requirements) == 0 {
+		r.success("Successfully initialized Python environment")
+		return nil
+	}
+
 	// Install requirements
-	color.Blue("Installing Python requirements...")
+	r.info("Installing Python requirements...")
 	cmd := exec.Command("pip", "install", "-r", "requirements.txt")
 	cmd.Dir = dir
 	var stdout, stderr bytes.Buffer
@@ -153,30 +584,301 @@ func (r *TestRunner) initPythonEnv(dir string) error {
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to install Python requirements: %v\nOutput: %s\nError: %s",
-			err, stdout.String(), stderr.String())
+		return fmt.Errorf("failed to install Python requirements: %w\nOutput: %s\nError: %s",
+			toolchainError("pip", err), stdout.String(), stderr.String())
+	}
+
+	r.success("Successfully initialized Python environment")
+	return nil
+}
+
+func (r *TestRunner) initPHPProject(dir string) error {
+	// Create composer.json with PHPUnit as a dev dependency
+	composerJSON := `{
+    "require-dev": {
+        "phpunit/phpunit": "^10.5"
+    }
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "composer.json"), []byte(composerJSON), 0644); err != nil {
+		return fmt.Errorf("failed to create composer.json: %w", err)
+	}
+
+	// Install PHPUnit
+	r.info("Installing PHPUnit with composer...")
+	cmd := exec.Command("composer", "install", "--no-interaction", "--no-progress")
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to install PHPUnit: %w\nOutput: %s\nError: %s",
+			toolchainError("composer", err), stdout.String(), stderr.String())
+	}
+
+	r.success("Successfully initialized PHP project")
+	return nil
+}
+
+func (r *TestRunner) initDotnetProject(dir string) error {
+	// Scaffold an xUnit project; the implementation and tests are compiled
+	// together from Main.cs and MainTests.cs
+	r.info("Creating xUnit project with dotnet new...")
+	cmd := exec.Command("dotnet", "new", "xunit", "--name", "Main", "--output", dir, "--force")
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to create xUnit project: %w\nOutput: %s\nError: %s",
+			toolchainError("dotnet", err), stdout.String(), stderr.String())
 	}
 
-	color.Green("Successfully initialized Python environment")
+	// Remove the template's sample test
+	if err := os.Remove(filepath.Join(dir, "UnitTest1.cs")); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove template test: %w", err)
+	}
+	if err := addDotnetTestdata(filepath.Join(dir, "Main.csproj")); err != nil {
+		return err
+	}
+
+	r.success("Successfully initialized .NET project")
+	return nil
+}
+
+// dotnetTestdataItem copies testdata/ next to the test assembly, since
+// dotnet test runs the tests from the build output directory
+const dotnetTestdataItem = `  <ItemGroup>
+    <None Include="testdata/**" CopyToOutputDirectory="PreserveNewest" />
+  </ItemGroup>
+`
+
+// addDotnetTestdata adds dotnetTestdataItem to a project file, so tests
+// open test data files by the same relative paths as in other languages.
+func addDotnetTestdata(project string) error {
+	data, err := os.ReadFile(project)
+	if err != nil {
+		return fmt.Errorf("failed to read project file: %w", err)
+	}
+	content := string(data)
+	end := strings.LastIndex(content, "</Project>")
+	if end < 0 {
+		return fmt.Errorf("unexpected project file %s: no </Project>", filepath.Base(project))
+	}
+	content = content[:end] + dotnetTestdataItem + "\n" + content[end:]
+	if err := os.WriteFile(project, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write project file: %w", err)
+	}
+	return nil
+}
+
+func (r *TestRunner) initGradleProject(dir string) error {
+	if _, err := exec.LookPath("gradle"); err != nil {
+		return toolchainError("gradle", err)
+	}
+
+	r.info("Creating Gradle project in: %s", dir)
+	buildFile := `plugins {
+    kotlin("jvm") version "1.9.22"
+}
+
+repositories {
+    mavenCentral()
+}
+
+dependencies {
+    testImplementation(kotlin("test"))
+    testImplementation("org.junit.jupiter:junit-jupiter:5.10.1")
+}
+
+tasks.test {
+    useJUnitPlatform()
+    testLogging {
+        events("passed", "skipped", "failed")
+        exceptionFormat = org.gradle.api.tasks.testing.logging.TestExceptionFormat.FULL
+    }
+}
+`
+	files := map[string]string{
+		"build.gradle.kts":    buildFile,
+		"settings.gradle.kts": "rootProject.name = \"main\"\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+
+	for _, sub := range []string{"src/main/kotlin", "src/test/kotlin"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", sub, err)
+		}
+	}
+
+	r.success("Successfully initialized Gradle project")
+	return nil
+}
+
+func (r *TestRunner) initSwiftPackage(dir string) error {
+	if _, err := exec.LookPath("swift"); err != nil {
+		return toolchainError("swift", err)
+	}
+
+	// The module is named Solution rather than Main: a main.swift file is
+	// treated as top-level code, and Main.swift collides with it on
+	// case-insensitive file systems
+	r.info("Creating Swift package in: %s", dir)
+	manifest := `// swift-tools-version:5.7
+import PackageDescription
+
+let package = Package(
+    name: "Solution",
+    targets: [
+        .target(name: "Solution"),
+        .testTarget(name: "SolutionTests", dependencies: ["Solution"]),
+    ]
+)
+`
+	if err := os.WriteFile(filepath.Join(dir, "Package.swift"), []byte(manifest), 0644); err != nil {
+		return fmt.Errorf("failed to write Package.swift: %w", err)
+	}
+
+	for _, sub := range []string{"Sources/Solution", "Tests/SolutionTests"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", sub, err)
+		}
+	}
+
+	r.success("Successfully initialized Swift package")
+	return nil
+}
+
+func (r *TestRunner) initMixProject(dir string) error {
+	if _, err := exec.LookPath("mix"); err != nil {
+		return toolchainError("mix", err)
+	}
+
+	// mix new asks before writing into an existing directory
+	r.info("Creating Mix project with mix new...")
+	cmd := exec.Command("mix", "new", ".", "--app", "main", "--module", "Main")
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader("y\n")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to create Mix project: %w\nOutput: %s\nError: %s",
+			toolchainError("mix", err), stdout.String(), stderr.String())
+	}
+
+	r.success("Successfully initialized Mix project")
+	return nil
+}
+
+func (r *TestRunner) initDartPackage(dir string) error {
+	if _, err := exec.LookPath("dart"); err != nil {
+		return toolchainError("dart", err)
+	}
+
+	r.info("Creating Dart package in: %s", dir)
+	pubspec := `name: main
+environment:
+  sdk: ^3.0.0
+
+dev_dependencies:
+  test: ^1.24.0
+`
+	if err := os.WriteFile(filepath.Join(dir, "pubspec.yaml"), []byte(pubspec), 0644); err != nil {
+		return fmt.Errorf("failed to write pubspec.yaml: %w", err)
+	}
+
+	for _, sub := range []string{"lib", "test"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", sub, err)
+		}
+	}
+
+	// Install the test package
+	r.info("Installing package:test with dart pub get...")
+	cmd := exec.Command("dart", "pub", "get")
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to install package:test: %w\nOutput: %s\nError: %s",
+			toolchainError("dart", err), stdout.String(), stderr.String())
+	}
+
+	r.success("Successfully initialized Dart package")
 	return nil
 }
 
 func (r *TestRunner) UpdateDependencies(code, testCode string) error {
-	color.Blue("Checking for dependencies...")
+	r.info("Checking for dependencies...")
+
+	imports := goImports(code, testCode)
+	if len(imports) == 0 {
+		r.info("No external dependencies found")
+		return nil
+	}
+
+	// Update go.mod file
+	for pkg := range imports {
+		if !isStandardPackage(pkg) {
+			target := pkg
+			if dep, ok := r.pinFor(pkg); ok {
+				target = dep.Module + "@" + dep.Version
+			}
+			r.info("Adding dependency: %s", target)
+			cmd := exec.Command("go", "get", target)
+			cmd.Dir = r.workDir
+			cmd.Env = r.goEnv()
+			var stdout, stderr bytes.Buffer
+			cmd.Stdout = &stdout
+			cmd.Stderr = &stderr
+			
+			if err := cmd.Run(); err != nil {
+				r.failure("Failed to add dependency %s: %v\nOutput: %s\nError: %s",
+					pkg, err, stdout.String(), stderr.String())
+				return fmt.Errorf("failed to add dependency %s: %w", pkg, toolchainError("go", err))
+			}
+		}
+	}
+
+	// Run go mod tidy to clean up dependencies
+	r.info("Running go mod tidy...")
+	cmd := exec.Command("go", "mod", "tidy")
+	cmd.Dir = r.workDir
+	cmd.Env = r.goEnv()
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
 	
+	if err := cmd.Run(); err != nil {
+		r.failure("Failed to run go mod tidy: %v\nOutput: %s\nError: %s",
+			err, stdout.String(), stderr.String())
+		return fmt.Errorf("failed to run go mod tidy: %w", toolchainError("go", err))
+	}
+
+	r.success("Dependencies updated successfully")
+	return nil
+}
+
+// goImports returns the packages imported by Go sources.
+func goImports(sources ...string) map[string]bool {
 	// Extract import statements using regex
 	importRegex := regexp.MustCompile(`import\s*\(([\s\S]*?)\)|\bimport\s+"([^"]+)"`)
 	
 	// Combine all code for import scanning
-	allCode := code + "\n" + testCode
+	allCode := strings.Join(sources, "\n")
 	
 	// Find all imports
 	matches := importRegex.FindAllStringSubmatch(allCode, -1)
-	
-	if len(matches) == 0 {
-		color.Blue("No external dependencies found")
-		return nil
-	}
 
 	// Create a map to store unique imports
 	imports := make(map[string]bool)
@@ -200,41 +902,7 @@ func (r *TestRunner) UpdateDependencies(code, testCode string) error {
 			}
 		}
 	}
-
-	// Update go.mod file
-	for pkg := range imports {
-		if !isStandardPackage(pkg) {
-			color.Blue("Adding dependency: %s", pkg)
-			cmd := exec.Command("go", "get", pkg)
-			cmd.Dir = r.workDir
-			var stdout, stderr bytes.Buffer
-			cmd.Stdout = &stdout
-			cmd.Stderr = &stderr
-			
-			if err := cmd.Run(); err != nil {
-				color.Red("Failed to add dependency %s: %v\nOutput: %s\nError: %s",
-					pkg, err, stdout.String(), stderr.String())
-				return fmt.Errorf("failed to add dependency %s: %w", pkg, err)
-			}
-		}
-	}
-
-	// Run go mod tidy to clean up dependencies
-	color.Blue("Running go mod tidy...")
-	cmd := exec.Command("go", "mod", "tidy")
-	cmd.Dir = r.workDir
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	
-	if err := cmd.Run(); err != nil {
-		color.Red("Failed to run go mod tidy: %v\nOutput: %s\nError: %s",
-			err, stdout.String(), stderr.String())
-		return fmt.Errorf("failed to run go mod tidy: %w", err)
-	}
-
-	color.Green("Dependencies updated successfully")
-	return nil
+	return imports
 }
 
 func extractPackagePath(line string) string {
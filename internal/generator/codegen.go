@@ -1,18 +1,61 @@
 package generator
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/prathyushnallamothu/aiterate/internal/ai"
 )
 
+// Phases name the purpose of each AI request in transcripts
+const (
+	PhaseDirectoryName  = "directory name"
+	PhaseTests          = "tests"
+	PhaseStrengthen     = "stronger tests"
+	PhaseConsolidate    = "consolidated tests"
+	PhaseImplementation = "implementation"
+	PhaseFix            = "fix"
+	PhaseFixCode        = "implementation fix"
+	PhaseFixTests       = "test fix"
+	PhaseReview         = "review"
+	PhaseDiagnosis      = "diagnosis"
+	PhaseExplanation    = "explanation"
+	PhaseNames          = "names"
+	PhaseCommitMessage  = "commit message"
+	PhaseStub           = "stub"
+)
+
 type CodeGenerator struct {
-	ai *ai.AIClient
+	ai           *ai.AIClient
+	opts         Options
+	conversation conversation
+}
+
+func NewCodeGenerator(ai *ai.AIClient, opts Options) *CodeGenerator {
+	return &CodeGenerator{ai: ai, opts: opts}
+}
+
+// completeCode requests a completion and strips any code fences, retrying
+// once when the result is empty or whitespace-only.
+func completeCode(ctx context.Context, client *ai.AIClient, phase, prompt string) (string, error) {
+	return completeChatCode(ctx, client, phase, []ai.Message{ai.UserMessage(prompt)})
 }
 
-func NewCodeGenerator(ai *ai.AIClient) *CodeGenerator {
-	return &CodeGenerator{ai: ai}
+// completeChatCode is completeCode for a conversation.
+func completeChatCode(ctx context.Context, client *ai.AIClient, phase string, messages []ai.Message) (string, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		response, err := client.GenerateChat(ctx, phase, messages)
+		if err != nil {
+			return "", err
+		}
+		if code := stripCodeBlock(response); code != "" {
+			return code, nil
+		}
+	}
+	return "", ErrEmptyCompletion
 }
 
 func stripCodeBlock(code string) string {
@@ -43,7 +86,19 @@ func stripCodeBlock(code string) string {
 	return strings.TrimSpace(code)
 }
 
-func (g *CodeGenerator) GenerateImplementation(description string, testCode string, language string) (string, error) {
+func (g *CodeGenerator) GenerateImplementation(ctx context.Context, description string, testCode string, language string) (string, error) {
+	prompt, err := g.ImplementationPrompt(testCode, language)
+	if err != nil {
+		return "", err
+	}
+	return g.completeImplementation(ctx, PhaseImplementation, prompt)
+}
+
+// ImplementationPrompt returns the prompt GenerateImplementation sends for testCode.
+func (g *CodeGenerator) ImplementationPrompt(testCode string, language string) (string, error) {
+	if err := requireLanguage(language); err != nil {
+		return "", err
+	}
 	var prompt string
 	switch language {
 	case "go":
@@ -51,14 +106,14 @@ func (g *CodeGenerator) GenerateImplementation(description string, testCode stri
 %s
 
 Generate a Go implementation that passes all tests. The implementation should:
-1. Include package declaration ("package main" for single file programs)
+1. %s
 2. Include all necessary imports
 3. Handle all test cases including edge cases
 4. Follow Go best practices
 5. Include error handling
-6. Include comments for exported functions
+6. Include comments for exported functions%s
 
-Return ONLY the implementation code without any explanation.`, testCode)
+Return ONLY the implementation code without any explanation.`, testCode, g.opts.goPackageInstruction(), g.opts.goInterfaceInstruction()+g.opts.goGenericsInstruction()+g.opts.goVersionInstruction()+g.opts.goConcurrencyInstruction()+g.opts.goHTTPInstruction())
 	case "python":
 		prompt = fmt.Sprintf(`Given these Python tests:
 %s
@@ -72,6 +127,104 @@ Generate a Python implementation that passes all tests. The implementation shoul
 6. Include error handling
 7. Use modern Python features (f-strings, walrus operator where appropriate)
 
+Return ONLY the implementation code without any explanation.`, testCode)
+	case "php":
+		prompt = fmt.Sprintf(`Given these PHPUnit tests:
+%s
+
+Generate a PHP implementation that passes all tests. The implementation should:
+1. Start with <?php and declare(strict_types=1);
+2. Be a single file (Main.php) without a namespace, since the tests include it with require_once
+3. Use type declarations for parameters and return values
+4. Handle all test cases including edge cases
+5. Follow PSR-12 coding style
+6. Throw appropriate exceptions for error conditions
+7. Include PHPDoc comments for functions and classes
+8. Produce no output when included
+
+Return ONLY the implementation code without any explanation.`, testCode)
+	case "csharp":
+		prompt = fmt.Sprintf(`Given these xUnit tests:
+%s
+
+Generate a C# implementation that passes all tests. The implementation should:
+1. Be a single file (Main.cs) compiled in the same project as the tests
+2. Not declare a namespace, matching the tests
+3. Not contain a Main method or top-level statements
+4. Include all necessary using directives
+5. Handle all test cases including edge cases
+6. Follow .NET naming conventions and best practices
+7. Throw appropriate exceptions for error conditions
+8. Include XML doc comments for public members
+
+Return ONLY the implementation code without any explanation.`, testCode)
+	case "kotlin":
+		prompt = fmt.Sprintf(`Given these Kotlin tests:
+%s
+
+Generate a Kotlin implementation that passes all tests. The implementation should:
+1. Be a single file (Main.kt) in the default package (no package declaration)
+2. Include all necessary imports
+3. Handle all test cases including edge cases
+4. Follow Kotlin coding conventions and prefer idiomatic constructs (data classes, null safety, extension functions where appropriate)
+5. Throw appropriate exceptions for error conditions
+6. Include KDoc comments for public declarations
+
+Return ONLY the implementation code without any explanation.`, testCode)
+	case "bash":
+		prompt = fmt.Sprintf(`Given these bats tests:
+%s
+
+Generate a Bash implementation that passes all tests. The implementation should:
+1. Be a single file (main.sh) starting with #!/usr/bin/env bash
+2. Define the tested functions and run nothing at the top level when sourced
+3. Only use commands available in a standard POSIX environment
+4. Quote variables and handle all test cases including edge cases
+5. Report errors on stderr and return a non-zero exit status for error conditions
+6. Include a comment describing each function
+
+Return ONLY the implementation code without any explanation.`, testCode)
+	case "swift":
+		prompt = fmt.Sprintf(`Given these XCTest tests:
+%s
+
+Generate a Swift implementation that passes all tests. The implementation should:
+1. Be a single file (Solution.swift) in the Solution module that the tests import with @testable import
+2. Not contain top-level executable statements or an @main entry point
+3. Include all necessary imports (e.g. Foundation)
+4. Handle all test cases including edge cases
+5. Follow the Swift API Design Guidelines
+6. Throw appropriate errors for error conditions
+7. Include documentation comments (///) for public declarations
+
+Return ONLY the implementation code without any explanation.`, testCode)
+	case "elixir":
+		prompt = fmt.Sprintf(`Given these ExUnit tests:
+%s
+
+Generate an Elixir implementation that passes all tests. The implementation should:
+1. Be a single file (lib/main.ex) defining the module Main that the tests call
+2. Not run any code at the top level, outside the module
+3. Compile without warnings (no unused variables, aliases or imports)
+4. Handle all test cases including edge cases, using pattern matching and guards where appropriate
+5. Follow the Elixir style guide
+6. Raise appropriate errors (e.g. ArgumentError) for error conditions
+7. Include @moduledoc and @doc documentation
+
+Return ONLY the implementation code without any explanation.`, testCode)
+	case "dart":
+		prompt = fmt.Sprintf(`Given these Dart tests:
+%s
+
+Generate a Dart implementation that passes all tests. The implementation should:
+1. Be a single library file (lib/main.dart) that the tests import as package:main/main.dart
+2. Not contain a main function
+3. Only import dart: core libraries, since the package has no other dependencies
+4. Compile without analyzer errors, with sound null safety
+5. Handle all test cases including edge cases
+6. Follow Effective Dart style and throw appropriate errors (e.g. ArgumentError) for error conditions
+7. Include /// documentation comments for public declarations
+
 Return ONLY the implementation code without any explanation.`, testCode)
 	default:
 		prompt = fmt.Sprintf(`Given these %s tests:
@@ -86,16 +239,16 @@ Generate an implementation that passes all tests. The implementation should:
 Return ONLY the implementation code without any explanation.`, language, testCode)
 	}
 
-	code, err := g.ai.GenerateCompletion(prompt)
-	if err != nil {
+	return prompt + g.opts.signatureInstruction() + g.opts.allowedImportsInstruction() + g.opts.fixturesInstruction(), nil
+}
+
+func (g *CodeGenerator) FixImplementation(ctx context.Context, description, currentCode string, testCode string, testOutput, hint string, language string) (string, error) {
+	if err := requireLanguage(language); err != nil {
 		return "", err
 	}
+	prompt := fmt.Sprintf(`The following %s code failed some tests.
 
-	return stripCodeBlock(code), nil
-}
-
-func (g *CodeGenerator) FixImplementation(currentCode string, testCode string, testOutput string, language string) (string, error) {
-	prompt := fmt.Sprintf(`The following %s code failed some tests:
+%s
 
 Current Implementation:
 %s
@@ -105,18 +258,19 @@ Test Code:
 
 Test Output (errors):
 %s
+%s
+Fix the implementation to make all tests pass. Return ONLY the fixed implementation code without any explanation.`, language, originalGoal(description)+g.opts.goInterfaceInstruction()+g.opts.goGenericsInstruction()+g.opts.goVersionInstruction()+g.opts.goConcurrencyInstruction()+g.opts.goHTTPInstruction()+g.opts.signatureInstruction()+g.opts.allowedImportsInstruction()+g.opts.fixturesInstruction(), currentCode, testCode, testOutput, guidance(hint))
 
-Fix the implementation to make all tests pass. Return ONLY the fixed implementation code without any explanation.`, language, currentCode, testCode, testOutput)
-
-	code, err := g.ai.GenerateCompletion(prompt)
+	messages := g.fixMessages(fixImplementationFormat, prompt, currentCode, testCode, testOutput, hint)
+	code, sent, err := g.completeChatImplementation(ctx, PhaseFixCode, messages)
 	if err != nil {
 		return "", err
 	}
-
-	return stripCodeBlock(code), nil
+	g.rememberFix(fixImplementationFormat, sent, code, code, testCode)
+	return code, nil
 }
 
-func (g *CodeGenerator) GenerateDirectoryName(description string) (string, error) {
+func (g *CodeGenerator) GenerateDirectoryName(ctx context.Context, description string) (string, error) {
 	prompt := fmt.Sprintf(`Given this function description:
 "%s"
 
@@ -128,7 +282,7 @@ Generate a short, descriptive directory name that:
 
 Return ONLY the directory name, nothing else.`, description)
 
-	name, err := g.ai.GenerateCompletion(prompt)
+	name, err := g.ai.GenerateCompletion(ctx, PhaseDirectoryName, prompt)
 	if err != nil {
 		return "", err
 	}
@@ -152,6 +306,9 @@ Return ONLY the directory name, nothing else.`, description)
 	
 	// Trim hyphens from ends
 	name = strings.Trim(name, "-")
+	if name == "" {
+		return "", ErrEmptyCompletion
+	}
 	
 	// Ensure it starts with a letter
 	if len(name) > 0 && !((name[0] >= 'a' && name[0] <= 'z')) {
@@ -161,13 +318,167 @@ Return ONLY the directory name, nothing else.`, description)
 	return name, nil
 }
 
+// Rename is a new name for an identifier in the generated code.
+type Rename struct {
+	From string
+	To   string
+}
+
+func (r Rename) String() string {
+	return r.From + " -> " + r.To
+}
+
+// identifierPattern matches names that are valid identifiers in every
+// supported language
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ProposeNames asks the AI whether the public functions, types and
+// parameters in the final code have names matching the description, the
+// way GenerateDirectoryName names the output, and returns the renames it
+// suggests; none when the names are fine.
+func (g *CodeGenerator) ProposeNames(ctx context.Context, description, code, language string) ([]Rename, error) {
+	if err := requireLanguage(language); err != nil {
+		return nil, err
+	}
+	prompt := fmt.Sprintf(`Given this functionality description:
+"%s"
+
+and this %s implementation:
+%s
+
+Suggest better names for the public functions, types and their parameters where the current name doesn't
+describe what they do, the way a caller reading the description would expect to call them. Keep names that are
+already clear. New names must:
+1. Follow the naming conventions of %s (keep exported Go names capitalized)
+2. Use only letters, digits and underscores, starting with a letter
+3. Not clash with another name in the code
+
+Return one rename per line in the form "oldName -> newName", or NONE if the names are fine. Return nothing else.`, description, language, code, language)
+
+	response, err := g.ai.GenerateCompletion(ctx, PhaseNames, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	var renames []Rename
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(stripCodeBlock(response), "\n") {
+		from, to, ok := strings.Cut(strings.Trim(strings.TrimSpace(line), "-*` "), "->")
+		if !ok {
+			continue
+		}
+		from, to = strings.Trim(strings.TrimSpace(from), "`"), strings.Trim(strings.TrimSpace(to), "`")
+		if from == to || seen[from] || !identifierPattern.MatchString(from) || !identifierPattern.MatchString(to) {
+			continue
+		}
+		seen[from] = true
+		renames = append(renames, Rename{From: from, To: to})
+	}
+	return renames, nil
+}
+
+// ExplainCode returns a plain-English explanation of how the implementation
+// works and what the tests cover.
+func (g *CodeGenerator) ExplainCode(ctx context.Context, code, testCode, language string) (string, error) {
+	if err := requireLanguage(language); err != nil {
+		return "", err
+	}
+	prompt := fmt.Sprintf(`Explain the following %s implementation and its tests to a developer reading them for the first time.
+
+Implementation:
+%s
+
+Tests:
+%s
+
+Write a short explanation in Markdown that covers:
+1. How the implementation works, step by step
+2. Any notable design decisions or edge-case handling
+3. What behavior the tests cover, and why those cases matter
+
+Use plain English and keep it under 400 words. Do not repeat the code in full.`, language, code, testCode)
+
+	explanation, err := g.ai.GenerateCompletion(ctx, PhaseExplanation, prompt)
+	if err != nil {
+		return "", err
+	}
+	explanation = strings.TrimSpace(explanation)
+	if explanation == "" {
+		return "", invalidResponse("AI returned an empty explanation")
+	}
+	return explanation, nil
+}
+
+// GenerateCommitMessage returns a conventional-commit style message
+// describing the generated code.
+func (g *CodeGenerator) GenerateCommitMessage(ctx context.Context, description, code, testCode, language string) (string, error) {
+	if err := requireLanguage(language); err != nil {
+		return "", err
+	}
+	prompt := fmt.Sprintf(`Write a git commit message for adding the following %s code and its tests.
+
+The code implements:
+%s
+
+Implementation:
+%s
+
+Tests:
+%s
+
+Follow the Conventional Commits format:
+1. A subject line of the form "feat: <summary>", in the imperative mood and under 72 characters
+2. A blank line
+3. A short body, wrapped at 72 characters, describing what the code does and what the tests cover
+
+Return ONLY the commit message, without code fences or any other text.`, language, description, code, testCode)
+
+	message, err := g.ai.GenerateCompletion(ctx, PhaseCommitMessage, prompt)
+	if err != nil {
+		return "", err
+	}
+	message = stripCodeBlock(message)
+	if message == "" {
+		return "", invalidResponse("AI returned an empty commit message")
+	}
+	return message, nil
+}
+
+// GenerateStub asks for an implementation that only declares what the tests
+// use, with bodies that do nothing, to check that the tests fail before
+// there's a real implementation.
+func (g *CodeGenerator) GenerateStub(ctx context.Context, testCode, language string) (string, error) {
+	if err := requireLanguage(language); err != nil {
+		return "", err
+	}
+	prompt := fmt.Sprintf(`Given these %s tests:
+%s
+
+Generate a stub implementation for them. The stub must:
+1. %s
+2. Declare every function, type, method and constant the tests use, with the exact names and signatures they expect, so the tests compile and run
+3. Implement nothing: every function returns the zero or empty value of its return type (0, "", false, nil, an empty list) and has no other effect
+4. Not raise or throw errors, and not look at its arguments
+
+Return ONLY the stub code without any explanation.`, language, testCode, g.opts.stubPackageInstruction(language))
+
+	return completeCode(ctx, g.ai, PhaseStub, prompt)
+}
+
 type FixResult struct {
 	TestCode string
 	Code     string
 }
 
-func (g *CodeGenerator) FixBoth(currentCode, currentTestCode string, testOutput string, language string) (*FixResult, error) {
-	prompt := fmt.Sprintf(`The following %s code and tests failed:
+// FixBoth asks the AI to fix the implementation and tests given the failing
+// output. hint is optional guidance from the user for this fix.
+func (g *CodeGenerator) FixBoth(ctx context.Context, description, currentCode, currentTestCode string, testOutput, hint string, language string) (*FixResult, error) {
+	if err := requireLanguage(language); err != nil {
+		return nil, err
+	}
+	prompt := fmt.Sprintf(`The following %s code and tests failed.
+
+%s
 
 Current Implementation:
 %s
@@ -177,43 +488,191 @@ Current Test Code:
 
 Test Output (errors):
 %s
-
+%s
 Fix BOTH the implementation and test code to make all tests pass. Return the fixed code in this exact format:
 
 ---IMPLEMENTATION---
 [Your fixed implementation code here]
 ---TESTS---
 [Your fixed test code here]
----END---`, language, currentCode, currentTestCode, testOutput)
+---END---`, language, originalGoal(description)+g.opts.goInterfaceInstruction()+g.opts.goGenericsInstruction()+g.opts.goVersionInstruction()+g.opts.goConcurrencyInstruction()+g.opts.goHTTPInstruction()+g.opts.signatureInstruction()+g.opts.allowedImportsInstruction()+g.opts.fixturesInstruction()+g.opts.testFilesFixInstruction(), currentCode, currentTestCode, testOutput, guidance(hint))
+
+	messages := g.fixMessages(fixBothFormat, prompt, currentCode, currentTestCode, testOutput, hint)
+	var parseErr error
+	var reminder string
+	for attempt := 0; attempt <= maxFormatRetries; attempt++ {
+		sent := withReminder(messages, reminder)
+		response, err := g.ai.GenerateChat(ctx, PhaseFix, sent)
+		if err != nil {
+			return nil, err
+		}
 
-	response, err := g.ai.GenerateCompletion(prompt)
-	if err != nil {
-		return nil, err
+		result, err := parseFixResponse(response)
+		if err != nil {
+			reminder = formatReminder(attempt + 1)
+			parseErr = err
+			continue
+		}
+		if g.opts.MultiFileTests {
+			files, err := SplitTestFiles(result.TestCode, language, g.opts.MaxFiles)
+			if err == nil {
+				files, err = repairFiles(ctx, g.ai, prompt, files, language)
+			}
+			if err != nil {
+				if !errors.Is(err, ErrInvalidAIResponse) {
+					return nil, err
+				}
+				reminder = testFilesReminder(err)
+				parseErr = err
+				continue
+			}
+			result.TestCode = FormatFiles(files)
+		}
+		if err = g.checkSize(result.Code); err == nil {
+			g.rememberFix(fixBothFormat, sent, response, result.Code, result.TestCode)
+			return result, nil
+		}
+		reminder = sizeReminder(len(result.Code), g.opts.maxFileSize())
+		parseErr = err
+	}
+	return nil, fmt.Errorf("%w (after %d attempts)", parseErr, maxFormatRetries+1)
+}
+
+// completeImplementation requests an implementation, retrying with a
+// reminder to stay focused when it is over the size limit.
+func (g *CodeGenerator) completeImplementation(ctx context.Context, phase, prompt string) (string, error) {
+	code, _, err := g.completeChatImplementation(ctx, phase, []ai.Message{ai.UserMessage(prompt)})
+	return code, err
+}
+
+// completeChatImplementation is completeImplementation for a conversation.
+// It also returns the messages of the accepted request.
+func (g *CodeGenerator) completeChatImplementation(ctx context.Context, phase string, messages []ai.Message) (string, []ai.Message, error) {
+	var reminder string
+	var sizeErr error
+	for attempt := 0; attempt <= maxSizeRetries; attempt++ {
+		sent := withReminder(messages, reminder)
+		code, err := completeChatCode(ctx, g.ai, phase, sent)
+		if err != nil {
+			return "", nil, err
+		}
+		if sizeErr = g.checkSize(code); sizeErr == nil {
+			return code, sent, nil
+		}
+		reminder = sizeReminder(len(code), g.opts.maxFileSize())
 	}
+	return "", nil, sizeErr
+}
 
-	// Parse the response to get both implementation and test code
-	parts := strings.Split(response, "---")
-	if len(parts) < 5 {
-		return nil, fmt.Errorf("invalid response format from AI")
+// withReminder returns messages with reminder appended to the last one.
+func withReminder(messages []ai.Message, reminder string) []ai.Message {
+	if reminder == "" {
+		return messages
 	}
+	sent := append([]ai.Message(nil), messages...)
+	sent[len(sent)-1].Content += reminder
+	return sent
+}
 
-	var implementation, tests string
-	for i, part := range parts {
-		part = strings.TrimSpace(part)
-		switch part {
-		case "IMPLEMENTATION":
-			if i+1 < len(parts) {
-				implementation = stripCodeBlock(parts[i+1])
-			}
-		case "TESTS":
-			if i+1 < len(parts) {
-				tests = stripCodeBlock(parts[i+1])
-			}
-		}
+// maxSizeRetries is how often an oversized implementation is re-requested
+const maxSizeRetries = 1
+
+// checkSize rejects implementations over Options.MaxFileSize, which are
+// almost always degenerate (the tests copied in, or repeated code).
+func (g *CodeGenerator) checkSize(code string) error {
+	if max := g.opts.maxFileSize(); max > 0 && len(code) > max {
+		return invalidResponse("implementation is %d bytes, over the limit of %d", len(code), max)
 	}
+	return nil
+}
+
+// sizeReminder returns text appended to the prompt after an oversized implementation.
+func sizeReminder(size, max int) string {
+	return fmt.Sprintf(`
+
+IMPORTANT: Your previous implementation was %d bytes, over the limit of %d. Keep the implementation focused on
+what the tests need: do not copy the tests into it, repeat code, or add unrelated functionality.`, size, max)
+}
+
+// originalGoal reminds the fix prompts of what the code is meant to do, so
+// fixes don't drift from the requirement or just weaken the tests.
+func originalGoal(description string) string {
+	return fmt.Sprintf(`The code must implement this functionality:
+%s
+
+Keep the fix faithful to this requirement. Do not change or remove test expectations just to make them pass
+unless they contradict the requirement.`, description)
+}
+
+// guidance returns the user's hint as a prompt section, or nothing when
+// there's none.
+func guidance(hint string) string {
+	hint = strings.TrimSpace(hint)
+	if hint == "" {
+		return ""
+	}
+	return fmt.Sprintf(`
+Guidance from the developer for this fix (follow it):
+%s
+`, hint)
+}
+
+// maxFormatRetries is how often a FixBoth response that ignores the
+// required format is re-requested
+const maxFormatRetries = 2
+
+// testFilesReminder returns text appended to the FixBoth prompt when the
+// tests of a response can't be split into files or one is still malformed
+// after repairing it, naming the problem so the AI knows what to fix.
+func testFilesReminder(err error) string {
+	problem := strings.TrimPrefix(err.Error(), ErrInvalidAIResponse.Error()+": ")
+	return fmt.Sprintf(`
+
+IMPORTANT: The tests in your previous response could not be used: %s.
+Return every test file complete and well-formed, each between its own ---FILE: name--- and ---END--- lines in the ---TESTS--- section.`, problem)
+}
+
+// formatReminder returns text appended to the FixBoth prompt on retries,
+// growing more explicit with each attempt.
+func formatReminder(attempt int) string {
+	switch attempt {
+	case 0:
+		return ""
+	case 1:
+		return `
+
+IMPORTANT: Your previous response did not follow the required format. Respond with ONLY the three markers
+---IMPLEMENTATION---, ---TESTS--- and ---END---, each on its own line, with the code between them. Do not add any other text.`
+	default:
+		return `
+
+IMPORTANT: Your previous responses did not follow the required format. Respond with ONLY the three markers
+---IMPLEMENTATION---, ---TESTS--- and ---END---, each on its own line, with the code between them. Do not add any other text.
+For example, a response for a Python function looks exactly like this:
+
+---IMPLEMENTATION---
+def add(a, b):
+    return a + b
+---TESTS---
+from main import add
+
+def test_add():
+    assert add(1, 2) == 3
+---END---`
+	}
+}
+
+// parseFixResponse extracts the implementation and tests from a FixBoth response.
+func parseFixResponse(response string) (*FixResult, error) {
+	implementation, foundImpl := fixSection(response, "---IMPLEMENTATION---")
+	tests, foundTests := fixSection(response, "---TESTS---")
+	if !foundImpl || !foundTests {
+		return nil, invalidResponse("FixBoth response is missing the ---IMPLEMENTATION--- and ---TESTS--- sections")
+	}
+	implementation, tests = stripCodeBlock(implementation), stripCodeBlock(tests)
 
 	if implementation == "" || tests == "" {
-		return nil, fmt.Errorf("failed to extract implementation or test code")
+		return nil, invalidResponse("failed to extract implementation or test code")
 	}
 
 	return &FixResult{
@@ -221,3 +680,24 @@ Fix BOTH the implementation and test code to make all tests pass. Return the fix
 		Code:     implementation,
 	}, nil
 }
+
+// fixSection returns the text of a FixBoth response section, from its
+// marker line to the next section marker or the last ---END---. Tests split
+// into files contain ---FILE--- and ---END--- lines of their own, so only
+// the last ---END--- closes the response.
+func fixSection(response, marker string) (string, bool) {
+	start := strings.Index(response, marker)
+	if start < 0 {
+		return "", false
+	}
+	section := response[start+len(marker):]
+	for _, next := range []string{"---IMPLEMENTATION---", "---TESTS---"} {
+		if i := strings.Index(section, next); i >= 0 {
+			section = section[:i]
+		}
+	}
+	if i := strings.LastIndex(section, fileEndMarker); i >= 0 {
+		section = section[:i]
+	}
+	return section, true
+}
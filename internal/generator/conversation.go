@@ -0,0 +1,77 @@
+package generator
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/prathyushnallamothu/aiterate/internal/ai"
+)
+
+// maxConversationFixes is how many fix exchanges a conversation keeps: the
+// first, which holds the full context, and the latest ones
+const maxConversationFixes = 4
+
+// Response formats of fix conversations; a history is only continued in
+// the format it was started in
+const (
+	fixImplementationFormat = "implementation"
+	fixBothFormat           = "both"
+)
+
+// conversation is the fix history kept with Options.Conversational.
+type conversation struct {
+	mu       sync.Mutex
+	format   string
+	messages []ai.Message
+	// code and testCode are what the last response produced
+	code     string
+	testCode string
+}
+
+// fixMessages returns the messages for a fix: the full prompt when there's
+// no history in format yet, or else the history followed by the new test
+// output. The code is only repeated when it changed since the last response,
+// e.g. when a check rejected it.
+func (g *CodeGenerator) fixMessages(format, prompt, code, testCode, testOutput, hint string) []ai.Message {
+	c := &g.conversation
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !g.opts.Conversational || c.format != format || len(c.messages) == 0 {
+		return []ai.Message{ai.UserMessage(prompt)}
+	}
+
+	followUp := "Your last fix was applied, but it still needs work."
+	if code != c.code {
+		followUp += fmt.Sprintf("\n\nThe implementation is now:\n%s", code)
+	}
+	if testCode != c.testCode {
+		followUp += fmt.Sprintf("\n\nThe test code is now:\n%s", testCode)
+	}
+	followUp += fmt.Sprintf(`
+
+Test Output (errors):
+%s
+%s
+Fix the remaining problems without repeating earlier mistakes. Respond in the same format as before.`, testOutput, guidance(hint))
+
+	return append(append([]ai.Message(nil), c.messages...), ai.UserMessage(followUp))
+}
+
+// rememberFix records a fix exchange and the code it produced for the next
+// fix prompt, keeping the first and the latest exchanges.
+func (g *CodeGenerator) rememberFix(format string, messages []ai.Message, response, code, testCode string) {
+	if !g.opts.Conversational {
+		return
+	}
+	c := &g.conversation
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	history := append(append([]ai.Message(nil), messages...), ai.Message{Role: ai.RoleAssistant, Content: response})
+	if max := 2 * maxConversationFixes; len(history) > max {
+		history = append(history[:2:2], history[len(history)-max+2:]...)
+	}
+	c.format = format
+	c.messages = history
+	c.code = code
+	c.testCode = testCode
+}
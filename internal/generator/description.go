@@ -0,0 +1,41 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultMaxDescriptionLength is the default cap, in characters, on descriptions fed into prompts
+const DefaultMaxDescriptionLength = 4000
+
+// responseDelimiter separates the sections of structured AI responses
+const responseDelimiter = "---"
+
+// PrepareDescription validates a description before it's built into
+// prompts. Descriptions longer than maxLen are rejected, or cut to maxLen
+// when truncate is set. Delimiters the response parsers rely on are
+// neutralized. The returned warnings describe any changes made.
+func PrepareDescription(description string, maxLen int, truncate bool) (string, []string, error) {
+	var warnings []string
+
+	description = strings.TrimSpace(description)
+	if description == "" {
+		return "", nil, fmt.Errorf("description is required")
+	}
+
+	if length := utf8.RuneCountInString(description); maxLen > 0 && length > maxLen {
+		if !truncate {
+			return "", nil, fmt.Errorf("description is %d characters, exceeding the limit of %d", length, maxLen)
+		}
+		description = string([]rune(description)[:maxLen])
+		warnings = append(warnings, fmt.Sprintf("Description truncated from %d to %d characters", length, maxLen))
+	}
+
+	if strings.Contains(description, responseDelimiter) {
+		description = strings.ReplaceAll(description, responseDelimiter, "- - -")
+		warnings = append(warnings, `Description contains "---", which is reserved for response markers; replaced with "- - -"`)
+	}
+
+	return description, warnings, nil
+}
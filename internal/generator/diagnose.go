@@ -0,0 +1,77 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Failure causes reported by DiagnoseFailure
+const (
+	CauseTests          = "tests"
+	CauseImplementation = "implementation"
+)
+
+// Diagnosis attributes a test failure to the tests or the implementation.
+type Diagnosis struct {
+	// Cause is CauseTests or CauseImplementation
+	Cause       string
+	Explanation string
+}
+
+var (
+	causePattern       = regexp.MustCompile(`(?im)^\s*CAUSE:\s*(tests?|implementation)\b`)
+	explanationPattern = regexp.MustCompile(`(?is)EXPLANATION:\s*(.+)`)
+)
+
+// DiagnoseFailure asks the AI whether failing tests are wrong or have found
+// a real bug in the implementation, judged against the description.
+func (g *TestGenerator) DiagnoseFailure(ctx context.Context, description, code, testCode, testOutput, language string) (*Diagnosis, error) {
+	if err := requireLanguage(language); err != nil {
+		return nil, err
+	}
+	prompt := fmt.Sprintf(`The following %s tests were written for an existing implementation, and some of them fail.
+
+The code is meant to implement:
+%s
+
+Implementation:
+%s
+
+Tests:
+%s
+
+Test Output (errors):
+%s
+
+Decide whether the failures are caused by mistakes in the tests (wrong expectations, wrong API usage,
+compile errors in the test code) or by real bugs in the implementation (behavior that contradicts the
+described functionality). Judge the expected behavior by the description, not by what the implementation does.
+
+Reply in this exact format:
+CAUSE: tests or implementation
+EXPLANATION: one or two sentences naming the failing tests and why`, language, description, code, testCode, testOutput)
+
+	response, err := g.ai.GenerateCompletion(ctx, PhaseDiagnosis, prompt)
+	if err != nil {
+		return nil, err
+	}
+	return parseDiagnosis(response)
+}
+
+// parseDiagnosis extracts the cause and explanation from a DiagnoseFailure response.
+func parseDiagnosis(response string) (*Diagnosis, error) {
+	match := causePattern.FindStringSubmatch(response)
+	if match == nil {
+		return nil, invalidResponse("diagnosis response is missing the CAUSE: line")
+	}
+	diagnosis := &Diagnosis{Cause: CauseImplementation}
+	if strings.HasPrefix(strings.ToLower(match[1]), "test") {
+		diagnosis.Cause = CauseTests
+	}
+	if match := explanationPattern.FindStringSubmatch(response); match != nil {
+		diagnosis.Explanation = strings.TrimSpace(match[1])
+	}
+	return diagnosis, nil
+}
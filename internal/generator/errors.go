@@ -0,0 +1,26 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidAIResponse is returned when an AI response can't be used, e.g.
+// it is empty or doesn't follow the requested format.
+var ErrInvalidAIResponse = errors.New("invalid AI response")
+
+// ErrEmptyCompletion is returned when the AI response contains no code.
+var ErrEmptyCompletion = fmt.Errorf("%w: AI returned no code", ErrInvalidAIResponse)
+
+// invalidResponse returns an error wrapping ErrInvalidAIResponse.
+func invalidResponse(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidAIResponse, fmt.Sprintf(format, args...))
+}
+
+// requireLanguage rejects generator calls that don't name the target language.
+func requireLanguage(language string) error {
+	if language == "" {
+		return fmt.Errorf("language is required")
+	}
+	return nil
+}
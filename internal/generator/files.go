@@ -0,0 +1,147 @@
+package generator
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultMaxFiles is the default cap on files accepted from a multi-file response
+const DefaultMaxFiles = 10
+
+const (
+	fileMarkerPrefix = "---FILE:"
+	fileEndMarker    = "---END---"
+)
+
+// GeneratedFile is a single file emitted in a multi-file AI response.
+type GeneratedFile struct {
+	Name    string
+	Content string
+}
+
+// ParseFiles extracts files from a response in the format
+//
+//	---FILE: name---
+//	[file content]
+//	---END---
+//
+// Every filename is validated so the output can't escape the workspace, and
+// responses with more than maxFiles files are rejected.
+func ParseFiles(response string, maxFiles int) ([]GeneratedFile, error) {
+	var files []GeneratedFile
+	var current *GeneratedFile
+	var content []string
+
+	flush := func() {
+		if current != nil {
+			current.Content = stripCodeBlock(strings.Join(content, "\n"))
+			files = append(files, *current)
+			current = nil
+			content = nil
+		}
+	}
+
+	for _, line := range strings.Split(response, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, fileMarkerPrefix) && strings.HasSuffix(trimmed, "---"):
+			flush()
+			name := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(trimmed, fileMarkerPrefix), "---"))
+			if err := ValidateFileName(name); err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrInvalidAIResponse, err)
+			}
+			current = &GeneratedFile{Name: filepath.ToSlash(filepath.Clean(name))}
+		case trimmed == fileEndMarker:
+			flush()
+		case current != nil:
+			content = append(content, line)
+		}
+	}
+	flush()
+
+	if len(files) == 0 {
+		return nil, invalidResponse("no files found")
+	}
+	if maxFiles > 0 && len(files) > maxFiles {
+		return nil, invalidResponse("response contains %d files, exceeding the limit of %d", len(files), maxFiles)
+	}
+
+	seen := make(map[string]bool)
+	for _, file := range files {
+		if seen[file.Name] {
+			return nil, invalidResponse("response contains duplicate file: %s", file.Name)
+		}
+		seen[file.Name] = true
+	}
+
+	return files, nil
+}
+
+// FormatFiles renders files in the format read by ParseFiles.
+func FormatFiles(files []GeneratedFile) string {
+	var b strings.Builder
+	for _, file := range files {
+		fmt.Fprintf(&b, "%s %s---\n%s\n%s\n", fileMarkerPrefix, file.Name, strings.TrimRight(file.Content, "\n"), fileEndMarker)
+	}
+	return b.String()
+}
+
+// IsTestFileName reports whether name is a test file that the runner picks
+// up for tests split into files: name_test.go for Go and name_test.py for
+// Python, at the top of the workspace.
+func IsTestFileName(name, language string) bool {
+	if strings.ContainsAny(name, `/\`) {
+		return false
+	}
+	switch language {
+	case "go":
+		return strings.HasSuffix(name, "_test.go") && name != "_test.go"
+	case "python":
+		return strings.HasSuffix(name, "_test.py") && name != "_test.py"
+	default:
+		return false
+	}
+}
+
+// SplitTestFiles parses tests generated with Options.MultiFileTests into
+// their files, checking that each is named as a test file and that there
+// are at most maxFiles of them (DefaultMaxFiles when zero).
+func SplitTestFiles(testCode, language string, maxFiles int) ([]GeneratedFile, error) {
+	if maxFiles == 0 {
+		maxFiles = DefaultMaxFiles
+	}
+	files, err := ParseFiles(testCode, maxFiles)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if !IsTestFileName(file.Name, language) {
+			return nil, invalidResponse("%s is not a %s test file name", file.Name, language)
+		}
+		if strings.TrimSpace(file.Content) == "" {
+			return nil, invalidResponse("test file %s is empty", file.Name)
+		}
+	}
+	return files, nil
+}
+
+// ValidateFileName rejects filenames that are empty, absolute, or would
+// resolve outside the workspace directory.
+func ValidateFileName(name string) error {
+	if name == "" {
+		return fmt.Errorf("invalid filename: empty name")
+	}
+	if filepath.IsAbs(name) || strings.HasPrefix(name, "/") || strings.HasPrefix(name, `\`) || filepath.VolumeName(name) != "" {
+		return fmt.Errorf("invalid filename %q: absolute paths are not allowed", name)
+	}
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if part == ".." {
+			return fmt.Errorf("invalid filename %q: parent directory references are not allowed", name)
+		}
+	}
+	if clean := filepath.Clean(name); clean == "." || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("invalid filename %q: path must stay within the workspace", name)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package generator
+
+import (
+	"strings"
+	"unicode"
+)
+
+// proseKeywords start lines that look like plain words but are code
+var proseKeywords = map[string]bool{
+	"if": true, "else": true, "elif": true, "try": true, "except": true, "finally": true,
+	"for": true, "while": true, "with": true, "def": true, "class": true, "return": true,
+	"import": true, "from": true, "package": true, "func": true, "var": true, "const": true,
+	"type": true, "fun": true, "val": true, "public": true, "private": true, "namespace": true,
+	"using": true, "echo": true, "fi": true, "done": true, "esac": true, "main": true,
+}
+
+// StripMarkers removes formatting artifacts the AI can leave in code:
+// lines holding section markers or code fences, and prose after the end of
+// the code, i.e. after the last closing brace or, for Python, the last
+// indented block. Prose is only removed when the first line after the end
+// looks like a sentence or a heading such as "Explanation:".
+func StripMarkers(code, language string) string {
+	var lines []string
+	for _, line := range strings.Split(code, "\n") {
+		if isMarkerLine(strings.TrimSpace(line)) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	end := codeEnd(lines, language)
+	if end >= 0 {
+		for i := end + 1; i < len(lines); i++ {
+			if strings.TrimSpace(lines[i]) == "" {
+				continue
+			}
+			if isProse(lines[i]) {
+				lines = lines[:i]
+			}
+			break
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")) + "\n"
+}
+
+// isMarkerLine reports whether a trimmed line is a response section
+// marker or a code fence, which are never valid code on their own.
+func isMarkerLine(line string) bool {
+	switch {
+	case line == "---IMPLEMENTATION---", line == "---TESTS---", line == fileEndMarker:
+		return true
+	case strings.HasPrefix(line, fileMarkerPrefix) && strings.HasSuffix(line, "---"):
+		return true
+	default:
+		return strings.HasPrefix(line, "```")
+	}
+}
+
+// codeEnd returns the index of the last line of code, or -1 when it can't
+// be told apart from what follows.
+func codeEnd(lines []string, language string) int {
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch language {
+		case "python":
+			if trimmed != "" && (line[0] == ' ' || line[0] == '\t') {
+				return i
+			}
+		case "php":
+			if strings.HasSuffix(trimmed, "}") || trimmed == "?>" {
+				return i
+			}
+		case "elixir":
+			if trimmed == "end" {
+				return i
+			}
+		default:
+			if strings.HasSuffix(trimmed, "}") {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// isProse reports whether an unindented line reads as a sentence or a
+// heading rather than code.
+func isProse(line string) bool {
+	if line == "" || line[0] == ' ' || line[0] == '\t' {
+		return false
+	}
+	trimmed := strings.TrimSpace(line)
+	if !unicode.IsLetter([]rune(trimmed)[0]) {
+		// Markdown bullets, headings and emphasis
+		return strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "**") || strings.HasPrefix(trimmed, "### ")
+	}
+	words := strings.Fields(trimmed)
+	if proseKeywords[strings.TrimSuffix(words[0], ":")] {
+		return false
+	}
+	if strings.ContainsAny(trimmed, "{}()[]=;$\"`<>") {
+		return false
+	}
+	return len(words) >= 3 || strings.HasSuffix(trimmed, ":")
+}
@@ -0,0 +1,18 @@
+package generator
+
+// mockCode stands in for code in mock responses
+const mockCode = "// mock response"
+
+// MockTemplates returns a well-formed response for each phase whose
+// responses have a format of their own, for ai.Config.MockTemplates, so an
+// offline run without canned responses gets through every step. Phases
+// answered with code are left to ai.MockTemplate.
+func MockTemplates() map[string]string {
+	return map[string]string{
+		PhaseDirectoryName: "mock-response\n",
+		PhaseFix:           "---IMPLEMENTATION---\n" + mockCode + "\n---TESTS---\n" + mockCode + "\n---END---\n",
+		PhaseReview:        "NO ISSUES\n",
+		PhaseDiagnosis:     "CAUSE: implementation\nEXPLANATION: mock response\n",
+		PhaseNames:         "NONE\n",
+	}
+}
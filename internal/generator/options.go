@@ -0,0 +1,313 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Test styles supported by the test generator
+const (
+	StyleDefault = "default"
+	StyleTable   = "table"
+)
+
+// Python test frameworks supported by the test generator
+const (
+	FrameworkPytest   = "pytest"
+	FrameworkUnittest = "unittest"
+)
+
+// DefaultMaxFileSize is the size cap, in bytes, on generated
+// implementations when Options.MaxFileSize is zero
+const DefaultMaxFileSize = 64 * 1024
+
+// Options controls how prompts are built by the generators.
+type Options struct {
+	// TestStyle selects the structure of generated tests (StyleDefault or StyleTable)
+	TestStyle string
+	// PythonFramework is FrameworkPytest or FrameworkUnittest; pytest when empty
+	PythonFramework string
+	// GoPackage is the package Go code is generated in; "main" when empty
+	GoPackage string
+	// Fuzz asks for Go fuzz targets alongside the regular tests
+	Fuzz bool
+	// Examples asks for Go Example functions with // Output: comments
+	// alongside the regular tests, which go test runs and verifies
+	Examples bool
+	// GoInterface is a Go interface definition the generated code must
+	// implement, already declared in a separate file of the package
+	GoInterface string
+	// Generics asks for Go type parameters where a function works over
+	// several types, with tests for more than one instantiation
+	Generics bool
+	// HTTP steers Go code toward HTTP handlers tested end to end against
+	// an httptest.Server
+	HTTP bool
+	// Concurrent steers Go code toward types safe for concurrent use, with
+	// tests accessing them from many goroutines
+	Concurrent bool
+	// MultiFileTests asks for tests split into several named files, in the
+	// ParseFiles format (Go and Python)
+	MultiFileTests bool
+	// MaxFiles caps the test files accepted with MultiFileTests;
+	// DefaultMaxFiles when zero
+	MaxFiles int
+	// Signature is the declaration, e.g. a Go func signature, that the
+	// generated code must match exactly for the caller's call sites
+	Signature string
+	// AllowedImports are the Go packages the implementation may import,
+	// where "std" stands for the standard library; any package when empty
+	AllowedImports []string
+	// MaxFileSize caps generated implementations in bytes;
+	// DefaultMaxFileSize when zero, unlimited when negative
+	MaxFileSize int
+	// Conversational keeps the fix requests and responses as a chat
+	// history, so later fixes follow up on earlier attempts instead of
+	// starting over
+	Conversational bool
+	// GoVersion is the Go version, e.g. "1.20", the code must build with;
+	// features of later versions are avoided. Any version when empty
+	GoVersion string
+	// Fixtures are test data files available to the tests in their
+	// working directory
+	Fixtures []Fixture
+}
+
+// Fixture is a test data file the tests can load.
+type Fixture struct {
+	// Path is the file's path relative to the tests' working directory
+	Path string
+	// Preview is the start of the file's contents, or a note about them
+	// for binary files
+	Preview string
+}
+
+// maxFileSize returns the effective size cap, or zero for unlimited.
+func (o Options) maxFileSize() int {
+	switch {
+	case o.MaxFileSize == 0:
+		return DefaultMaxFileSize
+	case o.MaxFileSize < 0:
+		return 0
+	}
+	return o.MaxFileSize
+}
+
+// goPackageInstruction tells the AI which package clause Go files should use.
+func (o Options) goPackageInstruction() string {
+	if o.GoPackage == "" {
+		return `Include package declaration ("package main" for single file programs)`
+	}
+	return fmt.Sprintf(`Use the package declaration "package %s"; the code is added to an existing package of that name`, o.GoPackage)
+}
+
+// stubPackageInstruction tells the AI how a stub is laid out so the tests
+// can load it like the real implementation.
+func (o Options) stubPackageInstruction(language string) string {
+	if language == "go" {
+		return o.goPackageInstruction()
+	}
+	return "Be laid out exactly as the tests expect to load the implementation (file, module, class or namespace)"
+}
+
+// goInterfaceInstruction asks for code implementing the required interface,
+// or returns nothing when there's none.
+func (o Options) goInterfaceInstruction() string {
+	if o.GoInterface == "" {
+		return ""
+	}
+	return fmt.Sprintf(`
+
+The code must implement this Go interface. It is already declared in a separate file of the same package, so do not declare it again:
+%s
+
+The implementation must define a type with every method of the interface exactly as declared, followed by a compile-time assertion such as var _ InterfaceName = (*TypeName)(nil). The tests should exercise the type through the interface.`, o.GoInterface)
+}
+
+// goGenericsInstruction asks for type parameters where they fit, or
+// returns nothing when generics aren't requested.
+func (o Options) goGenericsInstruction() string {
+	if !o.Generics {
+		return ""
+	}
+	constraints := "any, comparable or cmp.Ordered"
+	if minor := GoMinorVersion(o.GoVersion); minor > 0 && minor < 21 {
+		constraints = "any, comparable or constraint interfaces of your own"
+	}
+	return fmt.Sprintf(`
+
+Use Go generics (type parameters, Go 1.18+) where a function naturally works over several types, e.g. generic
+Map, Filter or container functions with constraints such as %s. Keep non-generic
+signatures for functions that only make sense for one type.`, constraints)
+}
+
+// goFeatures are notable Go features by the minor version adding them
+var goFeatures = []struct {
+	minor   int
+	feature string
+}{
+	{18, "generics (type parameters, any, comparable) and strings.Cut"},
+	{19, "atomic types such as atomic.Int64"},
+	{20, "errors.Join and multiple %w verbs in fmt.Errorf"},
+	{21, "the min, max and clear builtins and the slices, maps, cmp and log/slog packages"},
+	{22, "range over integers, per-iteration loop variables and math/rand/v2"},
+	{23, "range over functions and the iter package"},
+	{24, "generic type aliases"},
+}
+
+// GoMinorVersion returns the minor version of a Go version such as "1.20"
+// or "1.20.3", or zero when version isn't one.
+func GoMinorVersion(version string) int {
+	rest, ok := strings.CutPrefix(version, "1.")
+	if !ok {
+		return 0
+	}
+	minor, _, _ := strings.Cut(rest, ".")
+	n, err := strconv.Atoi(minor)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// goVersionInstruction asks for code building with Options.GoVersion, or
+// returns nothing when any version may be used.
+func (o Options) goVersionInstruction() string {
+	minor := GoMinorVersion(o.GoVersion)
+	if minor == 0 {
+		return ""
+	}
+	var newer []string
+	for _, f := range goFeatures {
+		if f.minor > minor {
+			newer = append(newer, fmt.Sprintf("%s (Go 1.%d)", f.feature, f.minor))
+		}
+	}
+	if len(newer) == 0 {
+		return fmt.Sprintf(`
+
+The code must build with Go %s.`, o.GoVersion)
+	}
+	return fmt.Sprintf(`
+
+The code must build with Go %s, so do not use any language feature or standard library API added in a later version.
+In particular, do not use: %s.`, o.GoVersion, strings.Join(newer, "; "))
+}
+
+// goConcurrencyInstruction asks for code safe for concurrent use, or
+// returns nothing outside concurrent mode.
+func (o Options) goConcurrencyInstruction() string {
+	if !o.Concurrent {
+		return ""
+	}
+	return `
+
+The code must be safe for concurrent use from many goroutines: guard shared state with sync.Mutex or
+sync.RWMutex, sync/atomic or channels, never copy values holding locks, and document which methods are safe to
+call concurrently. The tests are run with the race detector (go test -race), so any data race fails them.`
+}
+
+// goHTTPInstruction asks for handlers the tests can serve with httptest, or
+// returns nothing outside HTTP mode.
+func (o Options) goHTTPInstruction() string {
+	if !o.HTTP {
+		return ""
+	}
+	return `
+
+The code implements HTTP handlers with net/http. Expose them as http.Handler or http.HandlerFunc values (or a
+constructor returning an http.Handler, e.g. a configured *http.ServeMux) exactly as the tests use them. Do not start
+a server or listen on a port; the tests serve the handlers with net/http/httptest.`
+}
+
+// signatureInstruction asks for code declaring the required signature, or
+// returns nothing when there's none.
+func (o Options) signatureInstruction() string {
+	if o.Signature == "" {
+		return ""
+	}
+	return fmt.Sprintf(`
+
+The code must declare this function with exactly this signature (name, parameter types and return types),
+because existing code already calls it this way, and the tests must call it through this signature:
+%s`, o.Signature)
+}
+
+// allowedImportsInstruction restricts the imports of the implementation,
+// or returns nothing when any package may be used.
+func (o Options) allowedImportsInstruction() string {
+	if len(o.AllowedImports) == 0 {
+		return ""
+	}
+	var allowed []string
+	for _, pkg := range o.AllowedImports {
+		if pkg == "std" {
+			pkg = "the standard library"
+		}
+		allowed = append(allowed, pkg)
+	}
+	return fmt.Sprintf(`
+
+The implementation may only import these packages (and packages below them): %s.
+Do not import anything else, even if it would be more convenient; the tests may import what they need.`, strings.Join(allowed, ", "))
+}
+
+// fixturesInstruction tells the AI which test data files the tests can
+// load, or returns nothing when there are none.
+func (o Options) fixturesInstruction() string {
+	if len(o.Fixtures) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(`
+
+These test data files are available to the tests, at these paths relative to the directory the tests run in.
+Tests that need this data should open the files by these paths instead of copying the data into the tests:`)
+	for _, fixture := range o.Fixtures {
+		fmt.Fprintf(&b, "\n\n%s:\n%s", fixture.Path, strings.TrimRight(fixture.Preview, "\n"))
+	}
+	return b.String()
+}
+
+// testFilesInstruction asks for tests split into named files, or returns
+// nothing for single-file tests.
+func (o Options) testFilesInstruction(language string) string {
+	if !o.MultiFileTests {
+		return ""
+	}
+	ext := "go"
+	note := "Every file starts with the same package clause and has its own imports."
+	if language == "python" {
+		ext = "py"
+		note = "Every file imports what it tests from main."
+	}
+	maxFiles := o.MaxFiles
+	if maxFiles == 0 {
+		maxFiles = DefaultMaxFiles
+	}
+	return fmt.Sprintf(`
+
+Instead of a single file, split the tests across several files by concern, e.g. happy_path_test.%[1]s and
+edge_cases_test.%[1]s, using at most %[3]d files. File names must end in _test.%[1]s and have no directory. %[2]s
+Return every file in this exact format, with no other text:
+
+---FILE: happy_path_test.%[1]s---
+[test code]
+---END---
+---FILE: edge_cases_test.%[1]s---
+[test code]
+---END---`, ext, note, maxFiles)
+}
+
+// testFilesFixInstruction asks fixes to keep tests split into files, or
+// returns nothing for single-file tests.
+func (o Options) testFilesFixInstruction() string {
+	if !o.MultiFileTests {
+		return ""
+	}
+	return `
+
+The test code is split into files, each starting with a ---FILE: name--- line and ending with a ---END--- line.
+Keep that format for the fixed tests in the TESTS section, with every file, before the final ---END---.`
+}
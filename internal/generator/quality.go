@@ -0,0 +1,67 @@
+package generator
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Patterns matching test declarations and assertions per language
+var (
+	testFuncPatterns = map[string]*regexp.Regexp{
+		"go":     regexp.MustCompile(`(?m)^func Test\w*\(`),
+		"python": regexp.MustCompile(`(?m)^\s*def test_\w*\(`),
+		"php":    regexp.MustCompile(`(?m)function test\w*\(`),
+		"csharp": regexp.MustCompile(`\[(Fact|Theory)\]`),
+		"kotlin": regexp.MustCompile(`@Test\b`),
+		"swift":  regexp.MustCompile(`(?m)^\s*func test\w*\(`),
+		"bash":   regexp.MustCompile(`(?m)^\s*@test\s`),
+		"elixir": regexp.MustCompile(`(?m)^\s*test\s+"`),
+		"dart":   regexp.MustCompile(`(?m)^\s*test\(`),
+	}
+	assertionPatterns = map[string]*regexp.Regexp{
+		"go":     regexp.MustCompile(`\bt\.(Error|Errorf|Fatal|Fatalf|Fail|FailNow)\(|\b(assert|require)\.\w+\(`),
+		"python": regexp.MustCompile(`(?m)^\s*assert\b|pytest\.raises\(|self\.assert\w+\(`),
+		"php":    regexp.MustCompile(`(\$this->|self::|static::)(assert\w*|expectException\w*)\(`),
+		"csharp": regexp.MustCompile(`\bAssert\.\w+`),
+		"kotlin": regexp.MustCompile(`\b(assert\w*|fail)\(`),
+		"swift":  regexp.MustCompile(`\bXCT(Assert\w*|Fail|Unwrap)\(`),
+		"bash":   regexp.MustCompile(`(?m)^\s*(\[\[?\s|(assert|refute)_\w+)`),
+		"elixir": regexp.MustCompile(`\b(assert|refute)(_\w+)?\b`),
+		"dart":   regexp.MustCompile(`\b(expect|expectLater|fail)\(`),
+	}
+)
+
+// TestQuality summarizes how thoroughly tests assert behavior.
+type TestQuality struct {
+	Tests      int
+	Assertions int
+}
+
+// AnalyzeTests counts test functions and assertions in test code. ok is
+// false when the language has no known patterns.
+func AnalyzeTests(testCode, language string) (quality TestQuality, ok bool) {
+	testPattern, ok1 := testFuncPatterns[language]
+	assertPattern, ok2 := assertionPatterns[language]
+	if !ok1 || !ok2 {
+		return TestQuality{}, false
+	}
+	return TestQuality{
+		Tests:      len(testPattern.FindAllStringIndex(testCode, -1)),
+		Assertions: len(assertPattern.FindAllStringIndex(testCode, -1)),
+	}, true
+}
+
+// Weakness describes why the tests are too weak, or returns "" when they
+// have at least one assertion per test.
+func (q TestQuality) Weakness() string {
+	switch {
+	case q.Tests == 0:
+		return "no test functions were found"
+	case q.Assertions == 0:
+		return fmt.Sprintf("the %d tests contain no assertions", q.Tests)
+	case q.Assertions < q.Tests:
+		return fmt.Sprintf("only %d assertions across %d tests; every test must assert on results", q.Assertions, q.Tests)
+	default:
+		return ""
+	}
+}
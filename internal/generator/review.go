@@ -0,0 +1,112 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Review severities, most serious first
+const (
+	SeverityCritical = "critical"
+	SeverityMajor    = "major"
+	SeverityMinor    = "minor"
+)
+
+// Finding is a single issue raised by a code review.
+type Finding struct {
+	Severity string
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("[%s] %s", f.Severity, f.Message)
+}
+
+// Review is the outcome of ReviewCode.
+type Review struct {
+	Findings []Finding
+}
+
+// Serious reports whether any finding is critical or major.
+func (r *Review) Serious() bool {
+	for _, finding := range r.Findings {
+		if finding.Severity != SeverityMinor {
+			return true
+		}
+	}
+	return false
+}
+
+// FixRequest describes the serious findings for the fix prompt.
+func (r *Review) FixRequest() string {
+	var b strings.Builder
+	b.WriteString("The tests pass, but a code review found these issues. Fix the critical and major ones, adding tests that cover them:\n")
+	for _, finding := range r.Findings {
+		if finding.Severity != SeverityMinor {
+			fmt.Fprintf(&b, "- %s\n", finding)
+		}
+	}
+	return b.String()
+}
+
+// findingPattern matches a review line like "- [major] message"
+var findingPattern = regexp.MustCompile(`(?i)^\s*(?:[-*]|\d+\.)?\s*\[(critical|major|minor)\]\s*(.+)$`)
+
+// ReviewCode asks the AI to review passing code for bugs, security issues
+// and style problems the tests don't catch.
+func (g *CodeGenerator) ReviewCode(ctx context.Context, description, code, testCode, language string) (*Review, error) {
+	if err := requireLanguage(language); err != nil {
+		return nil, err
+	}
+	prompt := fmt.Sprintf(`Review the following %s implementation as a senior engineer. Its tests already pass.
+
+The code implements:
+%s
+
+Implementation:
+%s
+
+Tests:
+%s
+
+Look for bugs the tests miss (unhandled edge cases, incorrect logic, resource leaks, concurrency problems),
+security issues (injection, unsafe input handling, panics on untrusted input) and significant style problems.
+
+List each finding on its own line in this exact format:
+- [critical] description of the issue
+- [major] description of the issue
+- [minor] description of the issue
+
+Use critical for incorrect behavior or security issues, major for likely bugs, and minor for style or readability.
+If there are no issues, reply with exactly: NO ISSUES`, language, description, code, testCode)
+
+	response, err := g.ai.GenerateCompletion(ctx, PhaseReview, prompt)
+	if err != nil {
+		return nil, err
+	}
+	return parseReview(response)
+}
+
+// parseReview extracts the findings from a ReviewCode response.
+func parseReview(response string) (*Review, error) {
+	response = strings.TrimSpace(response)
+	if response == "" {
+		return nil, invalidResponse("AI returned an empty review")
+	}
+
+	review := &Review{}
+	for _, line := range strings.Split(response, "\n") {
+		if match := findingPattern.FindStringSubmatch(line); match != nil {
+			review.Findings = append(review.Findings, Finding{
+				Severity: strings.ToLower(match[1]),
+				Message:  strings.TrimSpace(match[2]),
+			})
+		}
+	}
+	if len(review.Findings) == 0 && !strings.Contains(strings.ToUpper(response), "NO ISSUES") {
+		return nil, invalidResponse("review response contains no findings in the [severity] format")
+	}
+	return review, nil
+}
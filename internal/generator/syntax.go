@@ -0,0 +1,101 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os/exec"
+	"strings"
+
+	"github.com/prathyushnallamothu/aiterate/internal/ai"
+)
+
+// PhaseRepairFile names requests regenerating a single malformed file
+const PhaseRepairFile = "file repair"
+
+// pythonSyntaxCheck parses Python source from stdin, reporting the first
+// syntax error on stderr as file:line: message
+const pythonSyntaxCheck = `import ast, sys
+try:
+    ast.parse(sys.stdin.read(), sys.argv[1])
+except SyntaxError as e:
+    sys.exit(f"{e.filename}:{e.lineno}: {e.msg}")`
+
+// ValidateSyntax reports a syntax error in a file of code, e.g. from a
+// truncated response. Go is parsed in process and Python with the installed
+// interpreter, which is stopped when ctx is cancelled; other languages, or
+// Python without an interpreter, aren't checked.
+func ValidateSyntax(ctx context.Context, name, code, language string) error {
+	switch language {
+	case "go":
+		if _, err := parser.ParseFile(token.NewFileSet(), name, code, 0); err != nil {
+			return err
+		}
+	case "python":
+		python, err := exec.LookPath("python3")
+		if err != nil {
+			if python, err = exec.LookPath("python"); err != nil {
+				return nil
+			}
+		}
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, python, "-c", pythonSyntaxCheck, name)
+		cmd.Stdin = strings.NewReader(code)
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+			return fmt.Errorf("%s", lines[len(lines)-1])
+		}
+	}
+	return nil
+}
+
+// repairFiles checks the syntax of each file of a multi-file response to
+// request and asks the AI to regenerate just the malformed ones, instead of
+// the whole set. It fails when a regenerated file is still malformed.
+func repairFiles(ctx context.Context, client *ai.AIClient, request string, files []GeneratedFile, language string) ([]GeneratedFile, error) {
+	repaired := append([]GeneratedFile(nil), files...)
+	for i, file := range repaired {
+		syntaxErr := ValidateSyntax(ctx, file.Name, file.Content, language)
+		if syntaxErr == nil {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var others []string
+		for _, other := range files {
+			if other.Name != file.Name {
+				others = append(others, other.Name)
+			}
+		}
+		prompt := fmt.Sprintf(`%s
+
+Your response to the request above contained several files, but %s is malformed, e.g. truncated:
+%v
+
+Its content was:
+%s
+
+The other files (%s) are fine and are kept as they are. Return ONLY the complete, corrected content of %s,
+without ---FILE--- or ---END--- lines or any explanation.`, request, file.Name, syntaxErr, file.Content, strings.Join(others, ", "), file.Name)
+
+		content, err := completeCode(ctx, client, PhaseRepairFile, prompt)
+		if err != nil {
+			return nil, err
+		}
+		if err := ValidateSyntax(ctx, file.Name, content, language); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, invalidResponse("%s is still malformed after regenerating it: %v", file.Name, err)
+		}
+		repaired[i].Content = content
+	}
+	return repaired, nil
+}
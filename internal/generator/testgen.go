@@ -1,20 +1,51 @@
 package generator
 
 import (
+	"context"
 	"fmt"
+	"strings"
 
 	"github.com/prathyushnallamothu/aiterate/internal/ai"
 )
 
 type TestGenerator struct {
-	ai *ai.AIClient
+	ai   *ai.AIClient
+	opts Options
 }
 
-func NewTestGenerator(ai *ai.AIClient) *TestGenerator {
-	return &TestGenerator{ai: ai}
+func NewTestGenerator(ai *ai.AIClient, opts Options) *TestGenerator {
+	return &TestGenerator{ai: ai, opts: opts}
 }
 
-func (g *TestGenerator) GenerateTests(description string, language string) (string, error) {
+func (g *TestGenerator) GenerateTests(ctx context.Context, description string, language string) (string, error) {
+	prompt, err := g.TestsPrompt(description, language)
+	if err != nil {
+		return "", err
+	}
+	if g.opts.MultiFileTests {
+		response, err := g.ai.GenerateCompletion(ctx, PhaseTests, prompt)
+		if err != nil {
+			return "", err
+		}
+		files, err := SplitTestFiles(response, language, g.opts.MaxFiles)
+		if err != nil {
+			return "", err
+		}
+		files, err = repairFiles(ctx, g.ai, prompt, files, language)
+		if err != nil {
+			return "", err
+		}
+		return FormatFiles(files), nil
+	}
+	return completeCode(ctx, g.ai, PhaseTests, prompt)
+}
+
+// TestsPrompt returns the prompt GenerateTests sends for description.
+func (g *TestGenerator) TestsPrompt(description string, language string) (string, error) {
+	if err := requireLanguage(language); err != nil {
+		return "", err
+	}
+	description += g.opts.signatureInstruction() + g.opts.fixturesInstruction()
 	var prompt string
 	switch language {
 	case "go":
@@ -23,15 +54,30 @@ func (g *TestGenerator) GenerateTests(description string, language string) (stri
 
 The tests should:
 1. Use the "testing" package
-2. Include package declaration ("package main" for single file programs)
+2. %s
 3. Include all necessary imports
 4. Cover normal cases, edge cases, and error conditions
 5. Follow Go testing best practices
-6. Use descriptive test names (e.g., TestAdd_PositiveNumbers)
+6. Use descriptive test names (e.g., TestAdd_PositiveNumbers)%s
 
-Return ONLY the test code without any explanation.`, description)
+Return ONLY the test code without any explanation.`, description+g.opts.goInterfaceInstruction(), g.opts.goPackageInstruction(), g.goGuidelines())
 	case "python":
-		prompt = fmt.Sprintf(`Generate comprehensive test cases in Python for the following functionality:
+		if g.opts.PythonFramework == FrameworkUnittest {
+			prompt = fmt.Sprintf(`Generate comprehensive test cases in Python for the following functionality:
+%s
+
+The tests should:
+1. Use the standard library unittest module, with test classes that subclass unittest.TestCase
+2. Include necessary imports (unittest, the code under test from main, etc.) and no third-party test libraries
+3. Cover normal cases, edge cases, and error conditions (use self.assertRaises for exceptions)
+4. Use unittest assertion methods such as self.assertEqual and self.assertTrue
+5. Use descriptive test method names (e.g., test_add_positive_numbers)
+6. Use setUp if needed, and end with: if __name__ == "__main__": unittest.main()
+7. Include type hints and docstrings
+
+Return ONLY the test code without any explanation.`, description)
+		} else {
+			prompt = fmt.Sprintf(`Generate comprehensive test cases in Python for the following functionality:
 %s
 
 The tests should:
@@ -43,6 +89,98 @@ The tests should:
 6. Use pytest fixtures if needed
 7. Include type hints and docstrings
 
+Return ONLY the test code without any explanation.`, description)
+		}
+	case "php":
+		prompt = fmt.Sprintf(`Generate comprehensive test cases in PHP for the following functionality:
+%s
+
+The tests should:
+1. Use PHPUnit 10 with a single test class named MainTest that extends PHPUnit\Framework\TestCase
+2. Start with <?php and include the implementation with require_once __DIR__ . '/Main.php';
+3. Not declare a namespace
+4. Cover normal cases, edge cases, and error conditions (use expectException for exceptions)
+5. Follow PHPUnit best practices
+6. Use descriptive test method names prefixed with "test" (e.g., testAddPositiveNumbers)
+
+Return ONLY the test code without any explanation.`, description)
+	case "csharp":
+		prompt = fmt.Sprintf(`Generate comprehensive test cases in C# for the following functionality:
+%s
+
+The tests should:
+1. Use xUnit with [Fact] methods (and [Theory]/[InlineData] for parameterized cases)
+2. Be a single public class named MainTests in the file MainTests.cs
+3. Include "using Xunit;" and any other necessary using directives
+4. Not declare a namespace, so the implementation types are visible without qualification
+5. Cover normal cases, edge cases, and error conditions (use Assert.Throws for exceptions)
+6. Use descriptive test method names (e.g., Add_PositiveNumbers_ReturnsSum)
+
+Return ONLY the test code without any explanation.`, description)
+	case "kotlin":
+		prompt = fmt.Sprintf(`Generate comprehensive test cases in Kotlin for the following functionality:
+%s
+
+The tests should:
+1. Use JUnit 5 (org.junit.jupiter.api.Test and org.junit.jupiter.api.Assertions) or kotlin.test assertions
+2. Be a single class named MainTest in the default package (no package declaration)
+3. Include all necessary imports
+4. Cover normal cases, edge cases, and error conditions (use assertThrows for exceptions)
+5. Follow Kotlin testing best practices
+6. Use descriptive test names with backticks (e.g., `+"`adds positive numbers`"+`)
+
+Return ONLY the test code without any explanation.`, description)
+	case "swift":
+		prompt = fmt.Sprintf(`Generate comprehensive test cases in Swift for the following functionality:
+%s
+
+The tests should:
+1. Use XCTest, with a single class named SolutionTests that subclasses XCTestCase
+2. Import the code under test with "@testable import Solution"
+3. Include all necessary imports (import XCTest)
+4. Cover normal cases, edge cases, and error conditions (use XCTAssertThrowsError for thrown errors)
+5. Follow Swift testing best practices
+6. Use descriptive test method names starting with "test" (e.g., testAddPositiveNumbers)
+
+Return ONLY the test code without any explanation.`, description)
+	case "bash":
+		prompt = fmt.Sprintf(`Generate comprehensive test cases in bats (Bash Automated Testing System) for the following functionality:
+%s
+
+The tests should:
+1. Be a bats file (main_test.bats) whose setup function sources the implementation with: source "$BATS_TEST_DIRNAME/main.sh"
+2. Call the functions under test with bats' "run" helper
+3. Assert on both the exit status ([ "$status" -eq 0 ]) and the output ([ "$output" = "expected" ]) using plain test expressions, without bats-assert or other libraries
+4. Cover normal cases, edge cases, and error conditions (non-zero exit status and error messages on stderr)
+5. Follow shell testing best practices
+6. Use descriptive test names (e.g., @test "add: sums two positive numbers")
+
+Return ONLY the test code without any explanation.`, description)
+	case "elixir":
+		prompt = fmt.Sprintf(`Generate comprehensive test cases in Elixir for the following functionality:
+%s
+
+The tests should:
+1. Use ExUnit, with a single module named MainTest that has "use ExUnit.Case, async: true"
+2. Test functions of the module Main, calling them fully qualified (e.g. Main.add(1, 2))
+3. Not call ExUnit.start(); test/test_helper.exs already does
+4. Cover normal cases, edge cases, and error conditions (use assert_raise for raised errors)
+5. Follow ExUnit best practices, with describe blocks grouping tests per function
+6. Use descriptive test names (e.g., test "add/2 sums two positive numbers")
+
+Return ONLY the test code without any explanation.`, description)
+	case "dart":
+		prompt = fmt.Sprintf(`Generate comprehensive test cases in Dart for the following functionality:
+%s
+
+The tests should:
+1. Use package:test, importing it with "import 'package:test/test.dart';"
+2. Import the code under test with "import 'package:main/main.dart';"
+3. Define a main function holding the test and group calls
+4. Cover normal cases, edge cases, and error conditions (use throwsA or throwsArgumentError for thrown errors)
+5. Follow Dart testing best practices, with sound null safety
+6. Use descriptive test names (e.g., test('add sums two positive numbers', ...))
+
 Return ONLY the test code without any explanation.`, description)
 	default:
 		prompt = fmt.Sprintf(`Generate comprehensive test cases in %s for the following functionality:
@@ -57,10 +195,134 @@ The tests should:
 Return ONLY the test code without any explanation.`, language, description, language)
 	}
 
-	code, err := g.ai.GenerateCompletion(prompt)
-	if err != nil {
+	if g.opts.MultiFileTests {
+		prompt += g.opts.testFilesInstruction(language)
+	}
+	return prompt, nil
+}
+
+// StrengthenTests asks the AI to rewrite tests that assert too little.
+func (g *TestGenerator) StrengthenTests(ctx context.Context, description, testCode, language, weakness string) (string, error) {
+	if err := requireLanguage(language); err != nil {
+		return "", err
+	}
+	prompt := fmt.Sprintf(`The following %s tests were written for this functionality:
+%s
+
+Tests:
+%s
+
+These tests are too weak: %s.
+
+Rewrite the tests so they meaningfully verify the described behavior:
+1. Every test must assert on concrete expected results, not just that the code runs without error
+2. Cover normal cases, edge cases, and error conditions
+3. Keep the same test framework, file structure, and imports
+
+Return ONLY the test code without any explanation.`, language, description, testCode, weakness)
+
+	return completeCode(ctx, g.ai, PhaseStrengthen, prompt)
+}
+
+// ConsolidateTests asks the AI to shrink tests of lines lines below max
+// lines by merging them into fewer, representative cases.
+func (g *TestGenerator) ConsolidateTests(ctx context.Context, description, testCode, language string, lines, max int) (string, error) {
+	if err := requireLanguage(language); err != nil {
+		return "", err
+	}
+	prompt := fmt.Sprintf(`The following %s tests were written for this functionality:
+%s
+
+Tests:
+%s
+
+These tests are %d lines long, more than the limit of %d lines, which slows down every test run and makes them hard to read.
+
+Consolidate the tests into fewer than %d lines of focused, representative cases:
+1. Keep one case per distinct behavior, edge case, and error condition; drop cases that repeat the same behavior with different values
+2. Merge similar cases into compact table-driven tests or loops where the framework allows it
+3. Keep the same test framework, file structure, imports, and the functions and signatures the tests call
+
+Return ONLY the test code without any explanation.`, language, description, testCode, lines, max, max)
+
+	return completeCode(ctx, g.ai, PhaseConsolidate, prompt)
+}
+
+// FixTests asks the AI to fix failing tests without changing the
+// implementation they test.
+func (g *TestGenerator) FixTests(ctx context.Context, description, code, testCode, testOutput, language string) (string, error) {
+	if err := requireLanguage(language); err != nil {
 		return "", err
 	}
+	prompt := fmt.Sprintf(`The following %s tests fail against an existing implementation because of mistakes in the tests.
+
+The code implements:
+%s
 
-	return stripCodeBlock(code), nil
+Implementation (do not change it):
+%s
+
+Tests:
+%s
+
+Test Output (errors):
+%s
+
+Fix the tests so they correctly verify the described behavior of this implementation:
+1. Keep every test that checks described behavior; only correct wrong expectations, API usage or compile errors
+2. Keep the same test framework, file structure, and imports
+
+Return ONLY the test code without any explanation.`, language, description+g.opts.fixturesInstruction(), code, testCode, testOutput)
+
+	return completeCode(ctx, g.ai, PhaseFixTests, prompt)
+}
+
+// goGuidelines returns extra numbered Go test instructions for the
+// configured style, fuzzing, examples, generics, concurrent and HTTP mode, continuing the
+// prompt's list at 7.
+func (g *TestGenerator) goGuidelines() string {
+	var lines []string
+	if g.opts.TestStyle == StyleTable {
+		lines = append(lines,
+			"Write a single table-driven test per function: a slice of named cases iterated with subtests via t.Run(tc.name, ...)",
+			"Put normal, edge, and error cases as rows in the table rather than separate test functions")
+	}
+	if g.opts.Fuzz {
+		lines = append(lines,
+			"Also write at least one fuzz target per function, func FuzzXxx(f *testing.F), that seeds the corpus with f.Add and calls f.Fuzz with arguments of types the fuzzer supports (string, []byte, integers, floats, bool)",
+			"In fuzz targets, only check properties that hold for every input (no panics, round trips, invariants), never exact expected values")
+	}
+
+	if g.opts.Examples {
+		lines = append(lines,
+			"Also write an example per exported function, func ExampleXxx() (ExampleXxx_suffix for more than one), that calls it, prints the results with fmt.Println and ends with an // Output: comment holding the exact expected output, so it documents the usage and go test verifies it",
+			"Only print deterministic values in examples: no maps, pointers, times or random numbers")
+	}
+
+	if g.opts.Generics {
+		lines = append(lines,
+			"The functions may be generic (type parameters); call each generic function with at least two different type instantiations, e.g. int and string")
+	}
+	if minor := GoMinorVersion(g.opts.GoVersion); minor > 0 {
+		lines = append(lines,
+			fmt.Sprintf("The tests must build with Go %s; only use language features and standard library APIs available in Go 1.%d", g.opts.GoVersion, minor))
+	}
+	if g.opts.Concurrent {
+		lines = append(lines,
+			"Besides the sequential tests, test concurrent access: start many goroutines (e.g. 50) that call the code on shared state at the same time, wait for them with sync.WaitGroup, then check the final state is exactly what the calls should produce",
+			"Mix reads and writes across the goroutines; the tests run with the race detector, so unsynchronized access fails them",
+			"Never rely on timing (no time.Sleep to order goroutines) and make sure every goroutine finishes, so the tests can't hang")
+	}
+	if g.opts.HTTP {
+		lines = append(lines,
+			"Test the HTTP handlers end to end: start each with httptest.NewServer (defer server.Close()) and make real requests through server.Client() to server.URL",
+			"Check status codes, headers and response bodies, covering each route and method, malformed input and error responses",
+			"Only talk to the test server; never depend on external network access or fixed ports")
+	}
+
+	var b strings.Builder
+	for i, line := range lines {
+		fmt.Fprintf(&b, "\n%d. %s", i+7, line)
+	}
+	return b.String()
 }
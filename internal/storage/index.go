@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// indexFile holds a summary of every session, so listing doesn't have to
+// read each session.json
+const indexFile = "index.json"
+
+// indexMu serializes index updates within the process. Updates from other
+// processes can still race; the index detects and repairs what they miss.
+var indexMu sync.Mutex
+
+// SessionSummary holds the fields of a session needed to list it.
+type SessionSummary struct {
+	ID          string    `json:"id"`
+	Description string    `json:"description"`
+	Language    string    `json:"language"`
+	Model       string    `json:"model,omitempty"`
+	Iterations  int       `json:"iterations"`
+	Success     bool      `json:"success"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	// ModTime is the modification time of session.json when the summary
+	// was taken; a different time means the summary is stale
+	ModTime time.Time `json:"mod_time"`
+}
+
+// Status reports whether the session's last iteration passed.
+func (s SessionSummary) Status() string {
+	if s.Success {
+		return StatusPassed
+	}
+	return StatusFailed
+}
+
+// Summary returns the listing fields of the session.
+func (s *Session) Summary() SessionSummary {
+	return SessionSummary{
+		ID:          s.ID,
+		Description: s.Description,
+		Language:    s.Language,
+		Model:       s.Model,
+		Iterations:  len(s.Iterations),
+		Success:     s.Status() == StatusPassed,
+		CreatedAt:   s.CreatedAt,
+		UpdatedAt:   s.UpdatedAt,
+	}
+}
+
+type sessionIndex struct {
+	Sessions map[string]SessionSummary `json:"sessions"`
+}
+
+func (s *Storage) indexPath() string {
+	return filepath.Join(s.baseDir, indexFile)
+}
+
+// readIndex loads the index, returning an empty one when it is missing or
+// unreadable so that it gets rebuilt.
+func (s *Storage) readIndex() *sessionIndex {
+	index := &sessionIndex{}
+	if data, err := os.ReadFile(s.indexPath()); err == nil {
+		json.Unmarshal(data, index)
+	}
+	if index.Sessions == nil {
+		index.Sessions = make(map[string]SessionSummary)
+	}
+	return index
+}
+
+// writeIndex replaces the index atomically, so readers never see a
+// partially written file.
+func (s *Storage) writeIndex(index *sessionIndex) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal session index: %w", err)
+	}
+	tmp, err := os.CreateTemp(s.baseDir, indexFile+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write session index: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write session index: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write session index: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.indexPath()); err != nil {
+		return fmt.Errorf("failed to write session index: %w", err)
+	}
+	return nil
+}
+
+// updateIndex records the summary of a session that was just saved.
+func (s *Storage) updateIndex(session *Session) error {
+	info, err := os.Stat(s.sessionFile(session.ID))
+	if err != nil {
+		return fmt.Errorf("failed to update session index: %w", err)
+	}
+	summary := session.Summary()
+	summary.ModTime = info.ModTime()
+
+	indexMu.Lock()
+	defer indexMu.Unlock()
+	index := s.readIndex()
+	index.Sessions[session.ID] = summary
+	return s.writeIndex(index)
+}
+
+// removeFromIndex drops a deleted session from the index.
+func (s *Storage) removeFromIndex(sessionID string) error {
+	indexMu.Lock()
+	defer indexMu.Unlock()
+	index := s.readIndex()
+	if _, ok := index.Sessions[sessionID]; !ok {
+		return nil
+	}
+	delete(index.Sessions, sessionID)
+	return s.writeIndex(index)
+}
+
+// summaries returns the summary of every stored session from the index,
+// first re-reading the sessions that are missing from it or changed since
+// they were indexed. Directories without readable session data are skipped.
+func (s *Storage) summaries() ([]SessionSummary, error) {
+	entries, err := os.ReadDir(s.baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read storage directory: %w", err)
+	}
+
+	indexMu.Lock()
+	defer indexMu.Unlock()
+	index := s.readIndex()
+	stale := false
+	current := make(map[string]bool)
+	var summaries []SessionSummary
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		id := entry.Name()
+		info, err := os.Stat(s.sessionFile(id))
+		if err != nil {
+			continue
+		}
+		current[id] = true
+		summary, ok := index.Sessions[id]
+		if !ok || !summary.ModTime.Equal(info.ModTime()) {
+			session, err := s.GetSession(id)
+			if err != nil {
+				continue
+			}
+			summary = session.Summary()
+			summary.ModTime = info.ModTime()
+			index.Sessions[id] = summary
+			stale = true
+		}
+		summaries = append(summaries, summary)
+	}
+	for id := range index.Sessions {
+		if !current[id] {
+			delete(index.Sessions, id)
+			stale = true
+		}
+	}
+
+	if stale {
+		// A failed repair only costs speed; the listing itself is complete
+		s.writeIndex(index)
+	}
+	return summaries, nil
+}
+
+// RebuildIndex discards the session index and rebuilds it from the full
+// session data.
+func (s *Storage) RebuildIndex() error {
+	indexMu.Lock()
+	err := os.Remove(s.indexPath())
+	indexMu.Unlock()
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove session index: %w", err)
+	}
+	_, err = s.summaries()
+	return err
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"time"
+)
+
+// RetentionPolicy bounds the stored sessions. Zero fields impose no limit.
+type RetentionPolicy struct {
+	// Keep is the number of most recently created sessions to keep
+	Keep int
+	// MaxAge removes sessions last updated longer ago than this
+	MaxAge time.Duration
+}
+
+// IsZero reports whether the policy keeps every session.
+func (p RetentionPolicy) IsZero() bool {
+	return p.Keep <= 0 && p.MaxAge <= 0
+}
+
+// expired returns the sessions the policy removes from sessions, which are
+// sorted newest first.
+func (p RetentionPolicy) expired(sessions []SessionSummary, now time.Time) []SessionSummary {
+	var expired []SessionSummary
+	for i, session := range sessions {
+		switch {
+		case p.Keep > 0 && i >= p.Keep:
+			expired = append(expired, session)
+		case p.MaxAge > 0 && session.UpdatedAt.Before(now.Add(-p.MaxAge)):
+			expired = append(expired, session)
+		}
+	}
+	return expired
+}
+
+// SetRetention sets the policy applied each time a session is created.
+func (s *Storage) SetRetention(policy RetentionPolicy) {
+	s.retention = policy
+}
+
+// Expired returns the stored sessions that policy would remove, newest first.
+func (s *Storage) Expired(policy RetentionPolicy) ([]SessionSummary, error) {
+	if policy.IsZero() {
+		return nil, nil
+	}
+	sessions, err := s.ListSessions(ListFilter{})
+	if err != nil {
+		return nil, err
+	}
+	return policy.expired(sessions, time.Now()), nil
+}
+
+// Prune deletes the sessions that policy removes and returns them.
+func (s *Storage) Prune(policy RetentionPolicy) ([]SessionSummary, error) {
+	expired, err := s.Expired(policy)
+	if err != nil {
+		return nil, err
+	}
+	for i, session := range expired {
+		if err := s.DeleteSession(session.ID); err != nil {
+			return expired[:i], err
+		}
+	}
+	return expired, nil
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,19 +19,160 @@ type Iteration struct {
 	TestLogs  string    `json:"test_logs"`
 	Success   bool      `json:"success"`
 	Timestamp time.Time `json:"timestamp"`
+	// Model is the model that generated the code, which differs from the
+	// session's model after a fallback
+	Model string `json:"model,omitempty"`
+	// Passed and Failed count the tests in the run, when known
+	Passed int `json:"passed,omitempty"`
+	Failed int `json:"failed,omitempty"`
+}
+
+// FuzzCrash records an input that made a fuzz target fail.
+type FuzzCrash struct {
+	Target    string    `json:"target"`
+	Input     string    `json:"input"`
+	Output    string    `json:"output"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// CandidateSelection records how the initial implementation was chosen
+// among several generated candidates.
+type CandidateSelection struct {
+	// Chosen is the 1-based number of the chosen candidate
+	Chosen     int         `json:"chosen"`
+	Reason     string      `json:"reason"`
+	Candidates []Candidate `json:"candidates"`
+}
+
+// Candidate is the test outcome of one candidate implementation.
+type Candidate struct {
+	Number  int  `json:"number"`
+	Success bool `json:"success"`
+	Passed  int  `json:"passed"`
+	Failed  int  `json:"failed"`
+	// Size is the implementation's length in bytes
+	Size int `json:"size"`
+	// Coverage is the statement coverage percentage, when measured
+	Coverage float64 `json:"coverage,omitempty"`
+}
+
+// ResumePoint is the iteration of a session that another resumed from.
+type ResumePoint struct {
+	Session   string `json:"session"`
+	Iteration int    `json:"iteration"`
+}
+
+// RunOptions are the options a session's run was started with that
+// resuming it needs to continue the same way. Paths are absolute.
+type RunOptions struct {
+	TestStyle     string `json:"test_style,omitempty"`
+	TestFramework string `json:"test_framework,omitempty"`
+	MaxFileSize   int    `json:"max_file_size,omitempty"`
+	Plateau       int    `json:"plateau,omitempty"`
+	// EnvNames are the names of the run's test environment variables;
+	// their values, which can hold secrets, aren't stored
+	EnvNames        []string      `json:"env_names,omitempty"`
+	Deps            []string      `json:"deps,omitempty"`
+	GoTestJSON      bool          `json:"go_test_json,omitempty"`
+	TestCommand     string        `json:"test_command,omitempty"`
+	MultiFileTests  bool          `json:"multi_file_tests,omitempty"`
+	MaxFiles        int           `json:"max_files,omitempty"`
+	StripMarkers    bool          `json:"strip_markers,omitempty"`
+	MutationTest    bool          `json:"mutation_test,omitempty"`
+	AllowedImports  []string      `json:"allowed_imports,omitempty"`
+	Vet             bool          `json:"vet,omitempty"`
+	Fuzz            bool          `json:"fuzz,omitempty"`
+	FuzzTime        time.Duration `json:"fuzz_time,omitempty"`
+	Examples        bool          `json:"examples,omitempty"`
+	Generics        bool          `json:"generics,omitempty"`
+	GoVersion       string        `json:"go_version,omitempty"`
+	Concurrent      bool          `json:"concurrent,omitempty"`
+	HTTP            bool          `json:"http,omitempty"`
+	RunMain         bool          `json:"run_main,omitempty"`
+	MainArgs        []string      `json:"main_args,omitempty"`
+	Review          bool          `json:"review,omitempty"`
+	ReviewModel     string        `json:"review_model,omitempty"`
+	FixStrategy     string        `json:"fix_strategy,omitempty"`
+	GuardTests      bool          `json:"guard_tests,omitempty"`
+	Conversational  bool          `json:"conversational,omitempty"`
+	Signature       string        `json:"signature,omitempty"`
+	Implements      string        `json:"implements,omitempty"`
+	Fixtures        []string      `json:"fixtures,omitempty"`
+	PromptPrefix    string        `json:"prompt_prefix,omitempty"`
+	PromptSuffix    string        `json:"prompt_suffix,omitempty"`
+	PackageDir      string        `json:"package_dir,omitempty"`
+	RegenerateTests bool          `json:"regenerate_tests,omitempty"`
 }
 
 type Session struct {
 	ID          string      `json:"id"`
 	Description string      `json:"description"`
 	Language    string      `json:"language"`
+	Model       string      `json:"model,omitempty"`
 	Iterations  []Iteration `json:"iterations"`
+	FuzzCrashes []FuzzCrash `json:"fuzz_crashes,omitempty"`
 	CreatedAt   time.Time   `json:"created_at"`
 	UpdatedAt   time.Time   `json:"updated_at"`
+
+	// Selection is set when the implementation was chosen among candidates
+	Selection *CandidateSelection `json:"selection,omitempty"`
+	// ResumedFrom is set when the session started from another session's
+	// iteration
+	ResumedFrom *ResumePoint `json:"resumed_from,omitempty"`
+	// Options are the run's options; nil for sessions recorded before
+	// they were stored
+	Options *RunOptions `json:"options,omitempty"`
+	// API lists the public types and functions of the final code of a
+	// successful run
+	API []string `json:"api,omitempty"`
+}
+
+// Session statuses reported by Status
+const (
+	StatusPassed = "passed"
+	StatusFailed = "failed"
+)
+
+// Status reports whether the session's last iteration passed.
+func (s *Session) Status() string {
+	if n := len(s.Iterations); n > 0 && s.Iterations[n-1].Success {
+		return StatusPassed
+	}
+	return StatusFailed
+}
+
+// ListFilter selects sessions in ListSessions. Zero fields match everything.
+type ListFilter struct {
+	// Language matches the session language exactly
+	Language string
+	// Since keeps sessions created at or after this time
+	Since time.Time
+	// Status is StatusPassed or StatusFailed
+	Status string
+	// Grep matches a case-insensitive substring of the description
+	Grep string
+}
+
+func (f ListFilter) matches(session SessionSummary) bool {
+	if f.Language != "" && session.Language != f.Language {
+		return false
+	}
+	if !f.Since.IsZero() && session.CreatedAt.Before(f.Since) {
+		return false
+	}
+	if f.Status != "" && session.Status() != f.Status {
+		return false
+	}
+	if f.Grep != "" && !strings.Contains(strings.ToLower(session.Description), strings.ToLower(f.Grep)) {
+		return false
+	}
+	return true
 }
 
 type Storage struct {
 	baseDir string
+	// retention is applied whenever a session is created
+	retention RetentionPolicy
 }
 
 func NewStorage(baseDir string) (*Storage, error) {
@@ -40,11 +183,12 @@ func NewStorage(baseDir string) (*Storage, error) {
 	return &Storage{baseDir: baseDir}, nil
 }
 
-func (s *Storage) CreateSession(description, language string) (*Session, error) {
+func (s *Storage) CreateSession(description, language, model string) (*Session, error) {
 	session := &Session{
 		ID:          uuid.New().String(),
 		Description: description,
 		Language:    language,
+		Model:       model,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
@@ -60,32 +204,102 @@ func (s *Storage) CreateSession(description, language string) (*Session, error)
 		return nil, err
 	}
 
+	// The new session is the newest, so the policy never removes it. A
+	// failed prune only leaves old sessions for the next one.
+	s.Prune(s.retention)
+
 	return session, nil
 }
 
-func (s *Storage) AddIteration(sessionID string, testCode, code, testLogs string, success bool) error {
+// AddIteration records a test run in the session, numbering and
+// timestamping it.
+func (s *Storage) AddIteration(sessionID string, iteration Iteration) error {
 	session, err := s.GetSession(sessionID)
 	if err != nil {
 		return err
 	}
 
-	iteration := Iteration{
-		Number:    len(session.Iterations) + 1,
-		TestCode:  testCode,
-		Code:      code,
-		TestLogs:  testLogs,
-		Success:   success,
+	iteration.Number = len(session.Iterations) + 1
+	iteration.Timestamp = time.Now()
+
+	session.Iterations = append(session.Iterations, iteration)
+	session.UpdatedAt = time.Now()
+
+	return s.saveSession(session)
+}
+
+// AddFuzzCrash records a failing fuzz input in the session.
+func (s *Storage) AddFuzzCrash(sessionID, target, input, output string) error {
+	session, err := s.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	session.FuzzCrashes = append(session.FuzzCrashes, FuzzCrash{
+		Target:    target,
+		Input:     input,
+		Output:    output,
 		Timestamp: time.Now(),
+	})
+	session.UpdatedAt = time.Now()
+
+	return s.saveSession(session)
+}
+
+// SetCandidateSelection records how the session's implementation was chosen.
+func (s *Storage) SetCandidateSelection(sessionID string, selection CandidateSelection) error {
+	session, err := s.GetSession(sessionID)
+	if err != nil {
+		return err
 	}
 
-	session.Iterations = append(session.Iterations, iteration)
+	session.Selection = &selection
+	session.UpdatedAt = time.Now()
+
+	return s.saveSession(session)
+}
+
+// SetResumedFrom records the iteration the session was resumed from.
+func (s *Storage) SetResumedFrom(sessionID string, point ResumePoint) error {
+	session, err := s.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	session.ResumedFrom = &point
+	session.UpdatedAt = time.Now()
+
+	return s.saveSession(session)
+}
+
+// SetRunOptions records the options the session's run was started with.
+func (s *Storage) SetRunOptions(sessionID string, options RunOptions) error {
+	session, err := s.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	session.Options = &options
+	session.UpdatedAt = time.Now()
+
+	return s.saveSession(session)
+}
+
+// SetAPI records the public API of the session's final code.
+func (s *Storage) SetAPI(sessionID string, api []string) error {
+	session, err := s.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	session.API = api
 	session.UpdatedAt = time.Now()
 
 	return s.saveSession(session)
 }
 
 func (s *Storage) GetSession(sessionID string) (*Session, error) {
-	data, err := os.ReadFile(filepath.Join(s.baseDir, sessionID, "session.json"))
+	data, err := os.ReadFile(s.sessionFile(sessionID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to read session: %w", err)
 	}
@@ -98,16 +312,57 @@ func (s *Storage) GetSession(sessionID string) (*Session, error) {
 	return &session, nil
 }
 
+// ListSessions returns summaries of the stored sessions matching filter,
+// newest first. They come from the session index, which is repaired from
+// the full session data where it is missing or stale.
+func (s *Storage) ListSessions(filter ListFilter) ([]SessionSummary, error) {
+	summaries, err := s.summaries()
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []SessionSummary
+	for _, summary := range summaries {
+		if filter.matches(summary) {
+			sessions = append(sessions, summary)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
+	})
+	return sessions, nil
+}
+
+// DeleteSession removes a session and everything stored with it.
+func (s *Storage) DeleteSession(sessionID string) error {
+	if sessionID == "" || filepath.Base(sessionID) != sessionID {
+		return fmt.Errorf("invalid session ID: %q", sessionID)
+	}
+	if err := os.RemoveAll(filepath.Join(s.baseDir, sessionID)); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return s.removeFromIndex(sessionID)
+}
+
+// SessionDir returns the directory holding a session's data.
+func (s *Storage) SessionDir(sessionID string) string {
+	return filepath.Join(s.baseDir, sessionID)
+}
+
 func (s *Storage) saveSession(session *Session) error {
 	data, err := json.MarshalIndent(session, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal session data: %w", err)
 	}
 
-	filename := filepath.Join(s.baseDir, session.ID, "session.json")
-	if err := os.WriteFile(filename, data, 0644); err != nil {
+	if err := os.WriteFile(s.sessionFile(session.ID), data, 0644); err != nil {
 		return fmt.Errorf("failed to save session data: %w", err)
 	}
 
-	return nil
+	return s.updateIndex(session)
+}
+
+func (s *Storage) sessionFile(sessionID string) string {
+	return filepath.Join(s.baseDir, sessionID, "session.json")
 }
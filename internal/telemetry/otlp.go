@@ -0,0 +1,245 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultEndpoint is the OTLP/HTTP trace endpoint of a local collector
+	DefaultEndpoint = "http://localhost:4318/v1/traces"
+	// DefaultServiceName is reported when OTEL_SERVICE_NAME isn't set
+	DefaultServiceName = "aiterate"
+)
+
+// Config configures an OTLPTracer.
+type Config struct {
+	// Endpoint is the OTLP/HTTP traces URL
+	Endpoint    string
+	ServiceName string
+	// Headers are sent with every export request
+	Headers map[string]string
+}
+
+// ConfigFromEnv reads the standard OTEL_EXPORTER_OTLP_* and
+// OTEL_SERVICE_NAME variables, falling back to a local collector.
+func ConfigFromEnv() Config {
+	config := Config{Endpoint: DefaultEndpoint, ServiceName: DefaultServiceName, Headers: map[string]string{}}
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); endpoint != "" {
+		config.Endpoint = endpoint
+	} else if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+		config.Endpoint = strings.TrimSuffix(endpoint, "/") + "/v1/traces"
+	}
+	if name := os.Getenv("OTEL_SERVICE_NAME"); name != "" {
+		config.ServiceName = name
+	}
+	for _, header := range []string{os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"), os.Getenv("OTEL_EXPORTER_OTLP_TRACES_HEADERS")} {
+		for _, pair := range strings.Split(header, ",") {
+			if key, value, ok := strings.Cut(pair, "="); ok && strings.TrimSpace(key) != "" {
+				config.Headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+			}
+		}
+	}
+	return config
+}
+
+// EnabledFromEnv reports whether OTEL_TRACES_EXPORTER selects the OTLP exporter.
+func EnabledFromEnv() bool {
+	return os.Getenv("OTEL_TRACES_EXPORTER") == "otlp"
+}
+
+// OTLPTracer records spans in memory and sends them to an OpenTelemetry
+// collector as OTLP/HTTP JSON when Shutdown is called. It is safe for
+// concurrent use.
+type OTLPTracer struct {
+	config Config
+	client *http.Client
+
+	mu    sync.Mutex
+	spans []*otlpSpan
+}
+
+// NewOTLPTracer returns a tracer exporting to config.Endpoint.
+func NewOTLPTracer(config Config) *OTLPTracer {
+	if config.Endpoint == "" {
+		config.Endpoint = DefaultEndpoint
+	}
+	if config.ServiceName == "" {
+		config.ServiceName = DefaultServiceName
+	}
+	return &OTLPTracer{config: config, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+type spanContextKey struct{}
+
+// Start begins a span, a child of the span carried by ctx if any.
+func (t *OTLPTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	span := &otlpSpan{tracer: t, name: name, spanID: randomHex(8), start: time.Now(), attrs: attrs}
+	if parent, ok := ctx.Value(spanContextKey{}).(*otlpSpan); ok {
+		span.traceID, span.parentID = parent.traceID, parent.spanID
+	} else {
+		span.traceID = randomHex(16)
+	}
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// Shutdown sends the ended spans to the collector.
+func (t *OTLPTracer) Shutdown(ctx context.Context) error {
+	t.mu.Lock()
+	spans := t.spans
+	t.spans = nil
+	t.mu.Unlock()
+	if len(spans) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(t.payload(spans))
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create export request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range t.config.Headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export spans to %s: %w", t.config.Endpoint, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("failed to export spans to %s: %s", t.config.Endpoint, resp.Status)
+	}
+	return nil
+}
+
+func (t *OTLPTracer) payload(spans []*otlpSpan) map[string]interface{} {
+	encoded := make([]map[string]interface{}, len(spans))
+	for i, span := range spans {
+		encoded[i] = span.encode()
+	}
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{
+				"attributes": encodeAttributes([]Attribute{Attr("service.name", t.config.ServiceName)}),
+			},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]interface{}{"name": "github.com/prathyushnallamothu/aiterate"},
+				"spans": encoded,
+			}},
+		}},
+	}
+}
+
+type otlpSpan struct {
+	tracer                    *OTLPTracer
+	name                      string
+	traceID, spanID, parentID string
+	start, end                time.Time
+
+	mu    sync.Mutex
+	attrs []Attribute
+	err   error
+	ended bool
+}
+
+func (s *otlpSpan) SetAttributes(attrs ...Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs = append(s.attrs, attrs...)
+}
+
+func (s *otlpSpan) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+func (s *otlpSpan) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	s.tracer.mu.Lock()
+	s.tracer.spans = append(s.tracer.spans, s)
+	s.tracer.mu.Unlock()
+}
+
+// Span kind and status codes from the OTLP trace protocol
+const (
+	spanKindInternal = 1
+	statusError      = 2
+)
+
+func (s *otlpSpan) encode() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	span := map[string]interface{}{
+		"traceId":           s.traceID,
+		"spanId":            s.spanID,
+		"name":              s.name,
+		"kind":              spanKindInternal,
+		"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+		"endTimeUnixNano":   strconv.FormatInt(s.end.UnixNano(), 10),
+		"attributes":        encodeAttributes(s.attrs),
+	}
+	if s.err != nil {
+		span["status"] = map[string]interface{}{"code": statusError, "message": s.err.Error()}
+	}
+	if s.parentID != "" {
+		span["parentSpanId"] = s.parentID
+	}
+	return span
+}
+
+// encodeAttributes converts attributes to OTLP JSON key/value pairs.
+func encodeAttributes(attrs []Attribute) []map[string]interface{} {
+	encoded := make([]map[string]interface{}, 0, len(attrs))
+	for _, attr := range attrs {
+		var value map[string]interface{}
+		switch v := attr.Value.(type) {
+		case string:
+			value = map[string]interface{}{"stringValue": v}
+		case bool:
+			value = map[string]interface{}{"boolValue": v}
+		case int:
+			// OTLP JSON encodes 64-bit integers as strings
+			value = map[string]interface{}{"intValue": strconv.Itoa(v)}
+		case int64:
+			value = map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+		case float64:
+			value = map[string]interface{}{"doubleValue": v}
+		default:
+			value = map[string]interface{}{"stringValue": fmt.Sprint(v)}
+		}
+		encoded = append(encoded, map[string]interface{}{"key": attr.Key, "value": value})
+	}
+	return encoded
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
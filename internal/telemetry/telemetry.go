@@ -0,0 +1,44 @@
+// Package telemetry defines the tracing hooks of the pipeline and an
+// exporter that sends spans to an OpenTelemetry collector.
+package telemetry
+
+import "context"
+
+// Attribute is a key/value pair attached to a span. Values are strings,
+// integers, floats or bools.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Attr builds an Attribute.
+func Attr(key string, value interface{}) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span is a timed operation started by a Tracer.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	// RecordError marks the span as failed
+	RecordError(err error)
+	End()
+}
+
+// Tracer starts spans. The returned context carries the span, so spans
+// started from it become its children.
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// NopTracer starts spans that record nothing.
+type NopTracer struct{}
+
+func (NopTracer) Start(ctx context.Context, _ string, _ ...Attribute) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttributes(...Attribute) {}
+func (nopSpan) RecordError(error)          {}
+func (nopSpan) End()                       {}
@@ -0,0 +1,1610 @@
+// Package aiterate runs AIterate's generate/iterate loop as a library: it
+// generates tests from a description, generates an implementation, and asks
+// the AI to fix both until the tests pass.
+package aiterate
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/prathyushnallamothu/aiterate/internal/ai"
+	"github.com/prathyushnallamothu/aiterate/internal/executor"
+	"github.com/prathyushnallamothu/aiterate/internal/generator"
+	"github.com/prathyushnallamothu/aiterate/internal/storage"
+	"github.com/prathyushnallamothu/aiterate/internal/telemetry"
+)
+
+const (
+	// DefaultMaxIterations is the number of test runs attempted before giving
+	// up in languages without a default of their own; see DefaultIterations
+	DefaultMaxIterations = 5
+	// DefaultMaxDescriptionLength is the default cap on description length
+	DefaultMaxDescriptionLength = generator.DefaultMaxDescriptionLength
+	// DefaultModel is the model used when Options.Model is empty
+	DefaultModel = ai.DefaultModel
+	// DefaultFuzzTime is how long each fuzz target runs when Options.FuzzTime is zero
+	DefaultFuzzTime = executor.DefaultFuzzTime
+	// DefaultMaxFileSize caps generated implementations in bytes when Options.MaxFileSize is zero
+	DefaultMaxFileSize = generator.DefaultMaxFileSize
+	// DefaultMaxFiles caps the test files of Options.MultiFileTests when Options.MaxFiles is zero
+	DefaultMaxFiles = generator.DefaultMaxFiles
+)
+
+// httpTestTimeout bounds each test run in HTTP and concurrent modes, where
+// a handler that never responds or a deadlock would otherwise hang the run
+const httpTestTimeout = 2 * time.Minute
+
+// goVersionPattern matches Go versions accepted in Options.GoVersion
+var goVersionPattern = regexp.MustCompile(`^1\.\d+(\.\d+)?$`)
+
+// minGoVersion is the oldest Go minor version Options.GoVersion accepts;
+// the prompts know the features added since
+const minGoVersion = 17
+
+// Test styles for Options.TestStyle
+const (
+	StyleDefault = generator.StyleDefault
+	StyleTable   = generator.StyleTable
+)
+
+// Python test frameworks for Options.TestFramework
+const (
+	FrameworkPytest   = generator.FrameworkPytest
+	FrameworkUnittest = generator.FrameworkUnittest
+)
+
+// Fix strategies for Options.FixStrategy
+const (
+	// FixStrategyBoth lets fixes change the implementation and the tests
+	FixStrategyBoth = "both"
+	// FixStrategyImplOnly keeps the tests unchanged and only fixes the implementation
+	FixStrategyImplOnly = "impl-only"
+)
+
+// patchFile is the diff of the fixed implementation saved with Options.Tests
+const patchFile = "FIX.diff"
+
+// transcriptFile, in the session directory, holds the AI requests and raw
+// responses recorded with Options.AILog
+const transcriptFile = "transcript.jsonl"
+
+// RetentionPolicy bounds the sessions kept in the store.
+type RetentionPolicy = storage.RetentionPolicy
+
+// ResumePoint is a stored session's iteration a run continues from.
+type ResumePoint = storage.ResumePoint
+
+// AzureConfig selects Azure OpenAI as the provider.
+type AzureConfig = ai.AzureConfig
+
+// Usage is the number of tokens consumed by a run's completion requests.
+type Usage = ai.Usage
+
+// TestResult is the outcome of a single test run.
+type TestResult = executor.TestResult
+
+// MainResult is the outcome of running the generated program with Options.RunMain.
+type MainResult = executor.MainResult
+
+// Options configures a Generate run.
+type Options struct {
+	// Description is what the generated function should do
+	Description string
+	// Language is one of SupportedLanguages
+	Language string
+	// Model is the AI model; DefaultModel when empty
+	Model string
+	// MaxIterations caps the test runs; DefaultIterations(Language) when zero
+	MaxIterations int
+	// Plateau stops the run early when the number of passing tests hasn't
+	// improved for this many iterations with counted test results, keeping
+	// the best iteration's files; zero never stops early
+	Plateau int
+	// TestStyle is StyleDefault or StyleTable (Go only)
+	TestStyle string
+	// TestFramework is FrameworkPytest or FrameworkUnittest (Python only);
+	// FrameworkPytest when empty
+	TestFramework string
+	// MaxDescriptionLength caps the description in characters;
+	// DefaultMaxDescriptionLength when zero, unlimited when negative
+	MaxDescriptionLength int
+	// TruncateDescription cuts long descriptions instead of rejecting them
+	TruncateDescription bool
+	// MaxFileSize caps generated implementations in bytes, retrying
+	// oversized ones; DefaultMaxFileSize when zero, unlimited when negative
+	MaxFileSize int
+
+	// APIKeyFile is a file containing the API key, checked before the keyring and environment
+	APIKeyFile string
+	// Headers are extra HTTP headers attached to every provider request
+	Headers http.Header
+	// Azure, when set, uses Azure OpenAI instead of the OpenAI API; models
+	// are mapped to deployment names
+	Azure *AzureConfig
+	// RequestsPerMinute caps the request rate; zero means unlimited
+	RequestsPerMinute int
+	// MaxConcurrent caps in-flight requests; zero means unlimited
+	MaxConcurrent int
+	// MaxRetries is how often each AI request is retried after a transient
+	// error; a default when zero, none when negative
+	MaxRetries int
+	// TotalRetries caps the retries spent across the whole run; zero means unlimited
+	TotalRetries int
+	// FallbackModels are tried in order when a request to the previous
+	// model still fails with a transient error after its retries
+	FallbackModels []string
+	// PromptPrefix and PromptSuffix are added before and after every
+	// generation prompt, e.g. "do not use third-party libraries"
+	PromptPrefix string
+	PromptSuffix string
+	// ReasoningEffort, "low", "medium" or "high", trades latency and cost
+	// for quality on models that support it; other models ignore it with
+	// a warning
+	ReasoningEffort string
+	// AILog records every AI request with its phase, prompt, raw response,
+	// model and token usage in transcript.jsonl in the session directory
+	AILog bool
+	// VerboseAI reports the phase, model and token usage of every AI
+	// request, with the estimated cost of the run so far, as it's made
+	VerboseAI bool
+	// MockAI answers every AI request offline instead of calling the
+	// provider, for testing the pipeline without API calls or a key
+	MockAI bool
+	// MockResponses is a directory of canned responses for MockAI, one per
+	// prompt in <hash>.txt with default.txt as the fallback; prompts
+	// without one are saved as <hash>.prompt. Without it every request is
+	// answered with a fixed template
+	MockResponses string
+
+	// Env holds extra KEY=VALUE environment variables for the test process
+	Env []string
+	// Deps pins dependencies as module@version: Go modules, or Python
+	// packages added to requirements.txt (Go and Python only)
+	Deps []string
+	// MaxNewDeps caps how many modules beyond testify and Deps the Go code
+	// may import in a run; zero means unlimited
+	MaxNewDeps int
+	// ConfirmDeps, when set, is called with Go modules before they're first
+	// fetched and returns whether to fetch them
+	ConfirmDeps func(modules []string) (bool, error)
+	// GoModCache is the Go module cache shared by every run's workspace;
+	// DefaultGoModCache when empty, unless GOMODCACHE is set in the environment
+	GoModCache string
+	// GradleDaemon reuses a Gradle daemon across Kotlin test runs
+	GradleDaemon bool
+	// GoTestJSON parses `go test -json` output for exact per-test results
+	GoTestJSON bool
+	// TestCommand, when set, replaces the language's test command, e.g.
+	// "make test"; it is run by sh in the workspace and passes when it
+	// exits with status 0
+	TestCommand string
+	// StrictTests rejects tests that assert too little
+	StrictTests bool
+	// TestsFirst runs the generated tests against a stub implementation
+	// that does nothing before the main loop; tests that all pass against
+	// it are sent back to the AI to be strengthened
+	TestsFirst bool
+	// MaxTestLines caps the generated tests in lines; longer tests are sent
+	// back to the AI to be consolidated into representative cases. Zero
+	// means unlimited
+	MaxTestLines int
+	// MultiFileTests asks for tests split into several named files, such as
+	// happy_path_test.go and edge_cases_test.go, instead of a single test
+	// file; Result.TestCode holds them in the ---FILE: name--- format (Go and Python)
+	MultiFileTests bool
+	// MaxFiles caps the files the tests may be split into with
+	// MultiFileTests, rejecting responses with more; DefaultMaxFiles when zero
+	MaxFiles int
+	// StripMarkers removes stray section markers, code fences and trailing
+	// prose left by the AI from the code and tests as soon as it returns
+	// them, so the session, later prompts, checks and Result see them cleaned
+	StripMarkers bool
+	// MutationTest checks that passing tests catch mutated implementations (Go only)
+	MutationTest bool
+	// AllowedImports restricts the packages the implementation may import,
+	// e.g. []string{StdImports, "github.com/google/uuid"}; each also allows
+	// the packages below it. Passing code importing anything else is sent
+	// back to the AI to be rewritten (Go only)
+	AllowedImports []string
+	// Vet runs go vet once the tests pass and asks the AI to fix what it reports (Go only)
+	Vet bool
+	// Fuzz generates Go fuzz targets and, once the tests pass, fuzzes them
+	// and asks the AI to fix any crash found (Go only)
+	Fuzz bool
+	// FuzzTime is how long each fuzz target runs; DefaultFuzzTime when zero
+	FuzzTime time.Duration
+	// Examples generates Go Example functions with // Output: comments
+	// alongside the tests, which go test runs as tests. Once the tests
+	// pass, tests without verified examples are sent back to the AI (Go only)
+	Examples bool
+	// Generics asks for type parameters where a function works over
+	// several types, tested with several instantiations (Go only)
+	Generics bool
+	// GoVersion is the Go version, e.g. "1.20", the code targets: prompts
+	// ask to avoid features of later versions and the workspace's go.mod
+	// declares it, so tests run with its semantics (Go only)
+	GoVersion string
+	// Concurrent generates code safe for concurrent use, with tests that
+	// access it from many goroutines, and runs them with -race (Go only)
+	Concurrent bool
+	// HTTP generates HTTP handlers with tests that serve them from an
+	// httptest.Server and make real requests (Go only)
+	HTTP bool
+	// RunMain runs the implementation once as a program after the tests
+	// pass, and asks the AI to fix it when it fails (Go, Python, PHP and Bash)
+	RunMain bool
+	// MainArgs are the command-line arguments for RunMain
+	MainArgs []string
+	// Review asks the AI to review the code once the tests pass, with one
+	// more fix iteration when it finds critical or major issues
+	Review bool
+	// ReviewModel is the model used for Review; Model when empty
+	ReviewModel string
+	// Explain asks the AI to explain the final code after the tests pass
+	Explain bool
+	// CommitMessage asks the AI for a commit message for the final code after the tests pass
+	CommitMessage bool
+	// DescribeOutput asks the AI for clearer function, type and parameter
+	// names after the tests pass and applies them when the renamed code
+	// still passes
+	DescribeOutput bool
+	// ConfirmRenames, when set, is called with the renames proposed by
+	// DescribeOutput and returns whether to apply them
+	ConfirmRenames func(renames []Rename) (bool, error)
+
+	// RegenerateTests generates new tests for Implementation and keeps the
+	// implementation as it is: failing tests are only fixed when they're at
+	// fault, and the run stops with ErrImplementationBug otherwise
+	RegenerateTests bool
+	// Implementation is the existing code for RegenerateTests or Tests
+	Implementation string
+	// Tests, when set with Implementation, are used instead of generating
+	// tests, e.g. a failing test reproducing a bug in the implementation
+	Tests string
+	// ResumedFrom, when set, is recorded in the new session as where Tests
+	// and Implementation were taken from. Resumed tests may keep the
+	// MultiFileTests and Examples of their run, unlike other provided tests
+	ResumedFrom *ResumePoint
+	// FixStrategy is what the fix loop may change; FixStrategyBoth when empty
+	FixStrategy string
+	// GuardTests reports fixes that remove more than a quarter of the
+	// tests' assertions, showing the change to the tests
+	GuardTests bool
+	// ConfirmTestChanges, when set with GuardTests, is called with such a
+	// change and returns whether to accept it; rejected changes keep the
+	// previous tests with the fixed implementation
+	ConfirmTestChanges func(change TestChange) (bool, error)
+	// Conversational sends each fix as a follow-up in a chat history of
+	// the earlier fix requests and responses, rather than as a fresh prompt
+	Conversational bool
+
+	// Candidates is the number of initial implementations generated in
+	// parallel for the tests; the best one, ranked by test results and
+	// then Tiebreak, goes into the fix loop. One when zero
+	Candidates int
+	// Tiebreak chooses among candidates that all pass: TiebreakSize (the
+	// default) or TiebreakCoverage
+	Tiebreak string
+
+	// Signature is the function declaration the code must match, e.g.
+	// "func Divide(a, b float64) (float64, error)"; it is given to the AI
+	// and the final code is checked for it (parsed for Go)
+	Signature string
+
+	// Implements is a Go file declaring interfaces the generated code must
+	// implement; the interfaces may only refer to imported or built-in types (Go only)
+	Implements string
+
+	// Fixtures are test data files copied into a testdata directory of
+	// the workspace, where the tests can load them, and of the output
+	// directory; the prompts tell the AI their paths
+	Fixtures []string
+
+	// FileMode is the permission of output files, with directories getting
+	// matching search permission; DefaultFileMode when zero
+	FileMode os.FileMode
+	// Gitignore writes a .gitignore for build artifacts and coverage files
+	// to the output directory (not with PackageDir)
+	Gitignore bool
+	// SessionWorkspace creates the workspace at WorkspaceDir(session ID)
+	// instead of a randomly named temp directory
+	SessionWorkspace bool
+	// KeepWorkspace leaves the workspace in place after the run for debugging
+	KeepWorkspace bool
+	// OutputDirSuffix is appended to the generated output directory name
+	OutputDirSuffix string
+	// DirNameRetries is how often naming the output directory is retried
+	// when the AI fails or returns an unusable name, before falling back to
+	// a timestamped generated-function directory
+	DirNameRetries int
+	// OutputDir is the output directory, replacing the AI-generated name;
+	// files from earlier runs in it are overwritten
+	OutputDir string
+	// NoWrite keeps the final files in the session and the workspace only,
+	// without creating an output directory; Result.OutputDir is empty
+	NoWrite bool
+	// PackageDir is an existing Go package directory to add the generated
+	// files to, in that package and under names that don't clash (Go only)
+	PackageDir string
+	// StorageDir is where sessions are recorded; DefaultStorageDir when empty
+	StorageDir string
+	// Retention limits the stored sessions, pruning the oldest when the
+	// run's session is created; the zero value keeps them all
+	Retention RetentionPolicy
+
+	// ReviewTests, when set, is called with the generated tests before the
+	// implementation is generated and returns the tests to use
+	ReviewTests func(testCode, language string) (string, error)
+	// FixHint, when set, is called after each failing test run and returns
+	// optional guidance for the next fix; empty means no guidance
+	FixHint func(iteration int, result *TestResult) (string, error)
+	// Observer, when set, is notified of each step of the run
+	Observer Observer
+	// Tracer, when set, receives a span for the run and for each phase in
+	// it: generating tests and the implementation, test runs and fixes
+	Tracer Tracer
+}
+
+// Result summarizes a completed run.
+type Result struct {
+	SessionID  string
+	Success    bool
+	Iterations int
+	OutputDir  string
+	Duration   time.Duration
+	Usage      Usage
+	TestCode   string
+	Code       string
+	// Explanation describes the final code when Options.Explain is set
+	Explanation string
+	// CommitMessage is set when Options.CommitMessage is set
+	CommitMessage string
+	// Main is the program run when Options.RunMain is set
+	Main *MainResult
+	// ReviewFindings are the issues raised by the review with Options.Review
+	ReviewFindings []string
+	// Diagnosis explains why the implementation is at fault when
+	// Options.RegenerateTests stops with ErrImplementationBug
+	Diagnosis string
+	// Renames are the renames applied with Options.DescribeOutput
+	Renames []Rename
+	// Patch is the unified diff from Options.Implementation to the final
+	// code when Options.Tests is set; empty when it wasn't changed
+	Patch string
+	// API lists the public types and functions of the final code of a
+	// successful run, see PublicAPI
+	API []string
+	// Plateaued is set when the run stopped early under Options.Plateau;
+	// TestCode and Code are then the best iteration's
+	Plateaued bool
+}
+
+// StorageDirEnv overrides the session store returned by DefaultStorageDir
+const StorageDirEnv = "AITERATE_HOME"
+
+// DefaultStorageDir returns the session store: AITERATE_HOME when set, or
+// else .aiterate in the user's home directory.
+func DefaultStorageDir() (string, error) {
+	if dir := os.Getenv(StorageDirEnv); dir != "" {
+		return dir, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".aiterate"), nil
+}
+
+// DefaultGoModCache returns the AIterate-managed Go module cache in the
+// user's cache directory.
+func DefaultGoModCache() (string, error) {
+	return executor.DefaultGoModCache()
+}
+
+// WorkspaceDir returns where a session's workspace is created with
+// Options.SessionWorkspace.
+func WorkspaceDir(sessionID string) string {
+	return executor.SessionWorkspaceDir(sessionID)
+}
+
+// CheckCredentials reports whether an API key can be found for the options,
+// so callers can fail before collecting the rest of their input.
+func CheckCredentials(opts Options) error {
+	if opts.MockAI {
+		return nil
+	}
+	if opts.Azure != nil {
+		return ai.CheckAzureAPIKey(opts.APIKeyFile)
+	}
+	return ai.CheckAPIKey(opts.APIKeyFile)
+}
+
+// validate checks the options and fills in defaults. It returns warnings
+// about changes made to the description.
+func (o *Options) validate() ([]string, error) {
+	if o.MaxDescriptionLength == 0 {
+		o.MaxDescriptionLength = DefaultMaxDescriptionLength
+	}
+	description, warnings, err := generator.PrepareDescription(o.Description, o.MaxDescriptionLength, o.TruncateDescription)
+	if err != nil {
+		return nil, err
+	}
+	o.Description = description
+	if o.Language == "" {
+		return nil, fmt.Errorf("language is required")
+	}
+	if !IsSupported(o.Language) {
+		return nil, fmt.Errorf("unsupported language: %s. Supported languages: %s", o.Language, SupportedLanguageNames())
+	}
+	if o.TestStyle == "" {
+		o.TestStyle = StyleDefault
+	}
+	if o.TestStyle != StyleDefault && o.TestStyle != StyleTable {
+		return nil, fmt.Errorf("unsupported test style: %s. Supported styles: default, table", o.TestStyle)
+	}
+	if o.TestFramework != "" && o.Language != "python" {
+		return nil, fmt.Errorf("choosing a test framework is only supported for Python")
+	}
+	if o.TestFramework == "" && o.Language == "python" {
+		o.TestFramework = FrameworkPytest
+	}
+	if o.Language == "python" && o.TestFramework != FrameworkPytest && o.TestFramework != FrameworkUnittest {
+		return nil, fmt.Errorf("unsupported test framework: %s. Supported frameworks: pytest, unittest", o.TestFramework)
+	}
+	if o.MutationTest && o.Language != "go" {
+		return nil, fmt.Errorf("mutation testing is only supported for Go")
+	}
+	if len(o.AllowedImports) > 0 {
+		if o.Language != "go" {
+			return nil, fmt.Errorf("allowed imports are only supported for Go")
+		}
+		for _, entry := range o.AllowedImports {
+			if entry == "" || strings.ContainsAny(entry, " \t\"") || strings.HasSuffix(entry, "/") {
+				return nil, fmt.Errorf("invalid allowed import %q", entry)
+			}
+		}
+	}
+	if err := validateFixtures(o.Fixtures); err != nil {
+		return nil, err
+	}
+	if o.Vet && o.Language != "go" {
+		return nil, fmt.Errorf("go vet checks are only supported for Go")
+	}
+	if o.Examples && (o.Language != "go" || (o.Tests != "" && o.ResumedFrom == nil)) {
+		return nil, fmt.Errorf("examples are only supported for Go and can't be combined with provided tests")
+	}
+	if o.Fuzz && o.Language != "go" {
+		return nil, fmt.Errorf("fuzzing is only supported for Go")
+	}
+	if o.Generics && o.Language != "go" {
+		return nil, fmt.Errorf("generics are only supported for Go")
+	}
+	if o.GoVersion != "" {
+		if o.Language != "go" {
+			return nil, fmt.Errorf("a Go version is only supported for Go")
+		}
+		if !goVersionPattern.MatchString(o.GoVersion) || generator.GoMinorVersion(o.GoVersion) < minGoVersion {
+			return nil, fmt.Errorf("invalid Go version %q: must be 1.%d or later, e.g. 1.20", o.GoVersion, minGoVersion)
+		}
+		if o.Generics && generator.GoMinorVersion(o.GoVersion) < 18 {
+			return nil, fmt.Errorf("generics need Go 1.18 or later, not %s", o.GoVersion)
+		}
+	}
+	if o.Concurrent && o.Language != "go" {
+		return nil, fmt.Errorf("concurrent mode is only supported for Go")
+	}
+	if o.HTTP && o.Language != "go" {
+		return nil, fmt.Errorf("HTTP mode is only supported for Go")
+	}
+	if o.FuzzTime < 0 {
+		return nil, fmt.Errorf("fuzz time must not be negative")
+	}
+	if o.FuzzTime == 0 {
+		o.FuzzTime = DefaultFuzzTime
+	}
+	if o.RunMain && !executor.CanRunMain(o.Language) {
+		return nil, fmt.Errorf("running the program is only supported for Go, Python, PHP and Bash")
+	}
+	if len(o.Deps) > 0 && o.Language != "go" && o.Language != "python" {
+		return nil, fmt.Errorf("pinning dependencies is only supported for Go and Python")
+	}
+	o.Signature = strings.TrimSpace(o.Signature)
+	if o.Signature != "" && o.Language == "go" {
+		if _, err := parseGoSignature(o.Signature); err != nil {
+			return nil, err
+		}
+	}
+	if o.Implements != "" && o.Language != "go" {
+		return nil, fmt.Errorf("implementing an interface is only supported for Go")
+	}
+	if o.PackageDir != "" && o.Language != "go" {
+		return nil, fmt.Errorf("adding to an existing package is only supported for Go")
+	}
+	if o.OutputDir != "" && o.PackageDir != "" {
+		return nil, fmt.Errorf("an output directory can't be combined with adding to an existing package")
+	}
+	if o.NoWrite && (o.OutputDir != "" || o.PackageDir != "" || o.Gitignore) {
+		return nil, fmt.Errorf("not writing files can't be combined with an output directory, adding to an existing package or a .gitignore")
+	}
+	if o.FileMode&^os.ModePerm != 0 {
+		return nil, fmt.Errorf("file mode %v must only contain permission bits", o.FileMode)
+	}
+	if o.MaxTestLines < 0 {
+		return nil, fmt.Errorf("max test lines must not be negative")
+	}
+	if o.DirNameRetries < 0 {
+		return nil, fmt.Errorf("directory name retries must not be negative")
+	}
+	if o.MaxNewDeps < 0 {
+		return nil, fmt.Errorf("max new dependencies must not be negative")
+	}
+	if o.MultiFileTests {
+		if o.Language != "go" && o.Language != "python" {
+			return nil, fmt.Errorf("tests split into files are only supported for Go and Python")
+		}
+		if o.PackageDir != "" || o.RegenerateTests || (o.Tests != "" && o.ResumedFrom == nil) || o.MutationTest || o.StrictTests || o.TestsFirst {
+			return nil, fmt.Errorf("tests split into files can't be combined with package, regenerating or providing tests, mutation testing, strict tests or checking the tests against a stub")
+		}
+	}
+	if o.MaxFiles < 0 {
+		return nil, fmt.Errorf("max files must be positive")
+	}
+	if o.MaxFiles > 0 && !o.MultiFileTests {
+		return nil, fmt.Errorf("a file limit requires tests split into files")
+	}
+	if o.Candidates < 0 {
+		return nil, fmt.Errorf("candidates must not be negative")
+	}
+	if o.DescribeOutput && (o.Tests != "" || o.RegenerateTests) {
+		return nil, fmt.Errorf("renaming the output can't be combined with an existing implementation")
+	}
+	if o.Candidates > 1 && (o.Tests != "" || o.RegenerateTests) {
+		return nil, fmt.Errorf("candidates can't be combined with an existing implementation")
+	}
+	switch o.Tiebreak {
+	case "":
+		o.Tiebreak = TiebreakSize
+	case TiebreakSize, TiebreakCoverage:
+	default:
+		return nil, fmt.Errorf("unknown tie-break %q: must be %s or %s", o.Tiebreak, TiebreakSize, TiebreakCoverage)
+	}
+	if o.Tiebreak == TiebreakCoverage && o.Language != "go" {
+		return nil, fmt.Errorf("the coverage tie-break is only supported for Go")
+	}
+	if o.Retention.Keep < 0 || o.Retention.MaxAge < 0 {
+		return nil, fmt.Errorf("session retention limits must not be negative")
+	}
+	if o.ReasoningEffort != "" && !ai.ValidReasoningEffort(o.ReasoningEffort) {
+		return nil, fmt.Errorf("invalid reasoning effort %q: must be one of %s", o.ReasoningEffort, strings.Join(ai.ReasoningEfforts, ", "))
+	}
+	if o.MockResponses != "" {
+		if !o.MockAI {
+			return nil, fmt.Errorf("mock responses require MockAI")
+		}
+		if info, err := os.Stat(o.MockResponses); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("mock responses directory %s not found", o.MockResponses)
+		}
+	}
+	if o.RegenerateTests {
+		if strings.TrimSpace(o.Implementation) == "" {
+			return nil, fmt.Errorf("regenerating tests requires an implementation")
+		}
+		if o.PackageDir != "" || o.Implements != "" || o.Vet || o.Fuzz || o.RunMain || o.Review || len(o.AllowedImports) > 0 {
+			return nil, fmt.Errorf("regenerating tests can't be combined with package, implements, vet, fuzz, run-main, review or allowed imports")
+		}
+	}
+	if o.Tests != "" {
+		if strings.TrimSpace(o.Implementation) == "" {
+			return nil, fmt.Errorf("providing tests requires an implementation")
+		}
+		if o.RegenerateTests || o.StrictTests || o.TestsFirst || o.PackageDir != "" {
+			return nil, fmt.Errorf("providing tests can't be combined with regenerating tests, strict tests, checking the tests against a stub or package")
+		}
+	} else if o.Implementation != "" && !o.RegenerateTests {
+		return nil, fmt.Errorf("an implementation requires regenerating tests or providing tests")
+	}
+	if o.ResumedFrom != nil && o.Tests == "" {
+		return nil, fmt.Errorf("resuming requires the tests and implementation of the iteration resumed from")
+	}
+	switch o.FixStrategy {
+	case "":
+		o.FixStrategy = FixStrategyBoth
+	case FixStrategyBoth, FixStrategyImplOnly:
+	default:
+		return nil, fmt.Errorf("unknown fix strategy %q: must be %s or %s", o.FixStrategy, FixStrategyBoth, FixStrategyImplOnly)
+	}
+	if o.FixStrategy == FixStrategyImplOnly && o.GuardTests {
+		return nil, fmt.Errorf("guarding the tests can't be combined with the %s fix strategy, which keeps the tests unchanged", FixStrategyImplOnly)
+	}
+	if o.FixStrategy == FixStrategyImplOnly && o.MutationTest {
+		return nil, fmt.Errorf("mutation testing can't be combined with the %s fix strategy, which keeps the tests unchanged", FixStrategyImplOnly)
+	}
+	if o.MaxIterations < 0 {
+		return nil, fmt.Errorf("max iterations must not be negative")
+	}
+	if o.TestCommand != "" && o.GoTestJSON {
+		return nil, fmt.Errorf("a test command can't be combined with go test -json")
+	}
+	if o.Plateau < 0 {
+		return nil, fmt.Errorf("plateau must not be negative")
+	}
+	if o.MaxIterations == 0 {
+		o.MaxIterations = DefaultIterations(o.Language)
+	}
+	if o.Model == "" {
+		o.Model = DefaultModel
+	}
+	return warnings, nil
+}
+
+// generatorOptions returns the prompt options and the interface to
+// implement, if any, for opts.
+func generatorOptions(opts Options) (generator.Options, *goInterface, error) {
+	var err error
+	genOpts := generator.Options{TestStyle: opts.TestStyle, PythonFramework: opts.TestFramework, Fuzz: opts.Fuzz, Examples: opts.Examples, Generics: opts.Generics, HTTP: opts.HTTP, Concurrent: opts.Concurrent, Signature: opts.Signature, AllowedImports: opts.AllowedImports, MultiFileTests: opts.MultiFileTests, MaxFiles: opts.MaxFiles, MaxFileSize: opts.MaxFileSize, Conversational: opts.Conversational, GoVersion: opts.GoVersion}
+	if opts.PackageDir != "" {
+		genOpts.GoPackage, err = DetectGoPackage(opts.PackageDir)
+		if err != nil {
+			return generator.Options{}, nil, err
+		}
+	}
+
+	if genOpts.Fixtures, err = fixturePrompts(opts.Fixtures); err != nil {
+		return generator.Options{}, nil, err
+	}
+
+	var iface *goInterface
+	if opts.Implements != "" {
+		pkg := genOpts.GoPackage
+		if pkg == "" {
+			pkg = "main"
+		}
+		iface, err = loadGoInterface(opts.Implements, pkg)
+		if err != nil {
+			return generator.Options{}, nil, err
+		}
+		genOpts.GoInterface = iface.source
+	}
+	return genOpts, iface, nil
+}
+
+// pipeline holds the state of a single Generate run.
+type pipeline struct {
+	opts     Options
+	aiClient *ai.AIClient
+	testGen  *generator.TestGenerator
+	codeGen  *generator.CodeGenerator
+	store    *storage.Storage
+	observer Observer
+	// runnerOpts configures how tests are run in the workspace
+	runnerOpts executor.Options
+	// iface is the interface the code must implement, if any
+	iface *goInterface
+	// reviewer reviews passing code with Options.Review
+	reviewer *generator.CodeGenerator
+	// approvedModules are the Go modules allowed to be fetched so far
+	approvedModules map[string]bool
+	tracer          Tracer
+	// transcript records the AI requests of the current session with Options.AILog
+	transcript *ai.Transcript
+	// spend is the running cost reported with Options.VerboseAI
+	spend spend
+	// held buffers messages sent while AI requests run concurrently
+	held heldMessages
+}
+
+// Generate runs the generate/iterate loop described by opts. The final
+// files are always copied to the output directory. When the tests never
+// pass, the returned Result is still populated and the error wraps
+// ErrNotConverged. Cancelling ctx stops the run between steps.
+func Generate(ctx context.Context, opts Options) (*Result, error) {
+	warnings, err := opts.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	storageDir := opts.StorageDir
+	if storageDir == "" {
+		dir, err := DefaultStorageDir()
+		if err != nil {
+			return nil, err
+		}
+		storageDir = dir
+	}
+	store, err := storage.NewStorage(storageDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	store.SetRetention(opts.Retention)
+
+	runnerOpts := executor.Options{
+		GradleDaemon:   opts.GradleDaemon,
+		Env:            opts.Env,
+		GoJSON:         opts.GoTestJSON,
+		PythonUnittest: opts.TestFramework == FrameworkUnittest,
+		MultiFileTests: opts.MultiFileTests,
+		TestCommand:    opts.TestCommand,
+		GoVersion:      opts.GoVersion,
+		GoRace:         opts.Concurrent,
+	}
+	if opts.HTTP || opts.Concurrent {
+		// A handler that never responds or a deadlock would otherwise hang the run for go test's default 10m
+		runnerOpts.GoTestTimeout = httpTestTimeout
+	}
+	if opts.Language == "go" && os.Getenv("GOMODCACHE") == "" {
+		runnerOpts.GoModCache = opts.GoModCache
+		if runnerOpts.GoModCache == "" {
+			runnerOpts.GoModCache, err = DefaultGoModCache()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, value := range opts.Deps {
+		dep, err := executor.ParseDependency(value)
+		if err != nil {
+			return nil, err
+		}
+		runnerOpts.Deps = append(runnerOpts.Deps, dep)
+	}
+
+	genOpts, iface, err := generatorOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &pipeline{
+		opts:       opts,
+		store:      store,
+		observer:   opts.Observer,
+		runnerOpts: runnerOpts,
+		iface:      iface,
+		tracer:     opts.Tracer,
+	}
+	if p.observer == nil {
+		p.observer = NopObserver{}
+	}
+	if p.tracer == nil {
+		p.tracer = telemetry.NopTracer{}
+	}
+	p.runnerOpts.Logger = p.runnerMessage
+
+	aiConfig := ai.Config{
+		Model:             opts.Model,
+		APIKeyFile:        opts.APIKeyFile,
+		Headers:           opts.Headers,
+		RequestsPerMinute: opts.RequestsPerMinute,
+		MaxConcurrent:     opts.MaxConcurrent,
+		MaxRetries:        opts.MaxRetries,
+		TotalRetries:      opts.TotalRetries,
+		FallbackModels:    opts.FallbackModels,
+		PromptPrefix:      opts.PromptPrefix,
+		PromptSuffix:      opts.PromptSuffix,
+		ReasoningEffort:   opts.ReasoningEffort,
+		Mock:              opts.MockAI,
+		MockDir:           opts.MockResponses,
+		MockTemplates:     generator.MockTemplates(),
+		Azure:             opts.Azure,
+		OnFallback: func(from, to string, err error) {
+			p.warn("Model %s is unavailable (%v); falling back to %s", from, err, to)
+		},
+	}
+	if opts.AILog || opts.VerboseAI {
+		aiConfig.OnExchange = p.recordExchange
+	}
+	aiClient, err := ai.NewAIClient(aiConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize AI client: %w", err)
+	}
+	p.aiClient = aiClient
+	p.testGen = generator.NewTestGenerator(aiClient, genOpts)
+	p.codeGen = generator.NewCodeGenerator(aiClient, genOpts)
+	p.reviewer = p.codeGen
+
+	// A separate review model gets a client sharing the run's rate limits
+	// and retry budget
+	var reviewClient *ai.AIClient
+	if opts.Review && opts.ReviewModel != "" && opts.ReviewModel != opts.Model {
+		reviewClient = aiClient.WithModel(opts.ReviewModel)
+		p.reviewer = generator.NewCodeGenerator(reviewClient, genOpts)
+	}
+	for _, warning := range warnings {
+		p.warn("%s", warning)
+	}
+	if opts.ReasoningEffort != "" {
+		models := append([]string{aiClient.Model()}, opts.FallbackModels...)
+		if reviewClient != nil {
+			models = append(models, reviewClient.Model())
+		}
+		for _, model := range models {
+			if !ai.SupportsReasoningEffort(model) {
+				p.warn("Model %s doesn't support a reasoning effort; ignoring it for this model", model)
+			}
+		}
+	}
+	run := p.run
+	if opts.RegenerateTests {
+		run = p.regenerateTests
+	}
+	ctx, ph := p.startPhase(ctx, SpanGenerate)
+	result, err := run(ctx)
+	if result != nil {
+		result.Usage = aiClient.Usage()
+		if reviewClient != nil {
+			reviewUsage := reviewClient.Usage()
+			result.Usage.PromptTokens += reviewUsage.PromptTokens
+			result.Usage.CompletionTokens += reviewUsage.CompletionTokens
+		}
+		ph.span.SetAttributes(
+			telemetry.Attr("aiterate.session_id", result.SessionID),
+			telemetry.Attr("aiterate.success", result.Success),
+			telemetry.Attr("aiterate.iterations", result.Iterations),
+		)
+	}
+	ph.end(err)
+	return result, err
+}
+
+// workspace is the environment a run generates and tests code in.
+type workspace struct {
+	session *storage.Session
+	runner  *executor.TestRunner
+	// dir is the temporary directory the tests run in
+	dir string
+	// outputDir receives finalFiles from dir when the run ends
+	outputDir  string
+	finalFiles []fileCopy
+}
+
+// prepare creates the session, the test workspace and the output directory
+// for a run. Callers must release the workspace.
+func (p *pipeline) prepare(ctx context.Context) (*workspace, error) {
+	description, language := p.opts.Description, p.opts.Language
+
+	// Create new session
+	session, err := p.store.CreateSession(description, language, p.aiClient.Model())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	// Create test runner with temporary workspace
+	runnerOpts := p.runnerOpts
+	if p.opts.SessionWorkspace {
+		runnerOpts.WorkspaceDir = WorkspaceDir(session.ID)
+	}
+	runner := executor.NewTestRunner("", runnerOpts)
+	workDir, err := runner.PrepareWorkspace(language)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare workspace: %w", err)
+	}
+	ws := &workspace{session: session, runner: runner, dir: workDir}
+
+	if from := p.opts.ResumedFrom; from != nil {
+		if err := p.store.SetResumedFrom(session.ID, *from); err != nil {
+			p.release(ws)
+			return nil, fmt.Errorf("failed to record the resumed session: %w", err)
+		}
+		p.info("Resuming session %s from iteration %d as session %s", from.Session, from.Iteration, session.ID)
+	}
+	if err := p.store.SetRunOptions(session.ID, runOptions(p.opts)); err != nil {
+		p.release(ws)
+		return nil, fmt.Errorf("failed to record the run options: %w", err)
+	}
+
+	if p.opts.AILog {
+		path := filepath.Join(p.store.SessionDir(session.ID), transcriptFile)
+		if p.transcript, err = ai.OpenTranscript(path); err != nil {
+			p.release(ws)
+			return nil, err
+		}
+		p.info("Recording AI requests to %s", path)
+	}
+
+	if p.iface != nil {
+		if err := os.WriteFile(filepath.Join(workDir, interfaceFile), []byte(p.iface.source), 0644); err != nil {
+			p.release(ws)
+			return nil, fmt.Errorf("failed to write interface file: %w", err)
+		}
+	}
+	if err := copyFixtures(workDir, p.opts.Fixtures); err != nil {
+		p.release(ws)
+		return nil, err
+	}
+
+	if p.opts.NoWrite {
+		p.info("Not writing files; they are kept in session %s", session.ID)
+		return ws, nil
+	}
+
+	// Create output directory with AI-generated name, unless one is given
+	outputDir := p.opts.OutputDir
+	var outputDirName string
+	if outputDir == "" {
+		p.observer.OnGenerate(StepDirectoryName)
+		for attempt := 0; attempt <= p.opts.DirNameRetries; attempt++ {
+			if outputDirName, err = p.codeGen.GenerateDirectoryName(ctx, description); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			outputDirName = fallbackDirName(p.opts.OutputDirSuffix)
+			p.warn("Failed to name the output directory (%v); using %s", err, outputDirName)
+		}
+		outputDir = filepath.Join(".", outputDirName+p.opts.OutputDirSuffix)
+	}
+
+	// Create the output directory
+	finalFiles := outputFiles(language)
+	if p.opts.PackageDir != "" {
+		outputDir = p.opts.PackageDir
+		testName, implName := FileNames(language)
+		pkgTest, pkgImpl := uniqueGoFileNames(outputDir, outputDirName)
+		finalFiles = []fileCopy{{src: testName, dst: pkgTest}, {src: implName, dst: pkgImpl}}
+		p.info("Adding %s and %s to package directory: %s", pkgImpl, pkgTest, outputDir)
+		for _, src := range p.opts.Fixtures {
+			fixture := fixturePath(src)
+			if fileExists(filepath.Join(outputDir, fixture)) {
+				p.warn("%s already exists in the package directory; not overwriting it", fixture)
+				continue
+			}
+			finalFiles = append(finalFiles, fileCopy{src: fixture, dst: fixture})
+		}
+	} else {
+		for _, src := range p.opts.Fixtures {
+			finalFiles = append(finalFiles, fileCopy{src: fixturePath(src), dst: fixturePath(src)})
+		}
+		if p.iface != nil {
+			finalFiles = append(finalFiles, fileCopy{src: interfaceFile, dst: interfaceFile})
+		}
+		if err := p.makeOutputDir(outputDir); err != nil {
+			p.release(ws)
+			return nil, err
+		}
+		p.info("Created output directory: %s", outputDir)
+	}
+
+	ws.outputDir, ws.finalFiles = outputDir, finalFiles
+	return ws, nil
+}
+
+// release removes the workspace, unless Options.KeepWorkspace is set.
+func (p *pipeline) release(ws *workspace) {
+	if p.transcript != nil {
+		if err := p.transcript.Close(); err != nil {
+			p.warn("Failed to close the AI transcript: %v", err)
+		}
+		p.transcript = nil
+	}
+	if p.opts.KeepWorkspace {
+		p.info("Workspace kept at %s", ws.dir)
+		return
+	}
+	os.RemoveAll(ws.dir)
+}
+
+// recordExchange reports an AI request with Options.VerboseAI and adds it
+// to the session transcript. Requests made before the session exists
+// aren't recorded.
+func (p *pipeline) recordExchange(exchange ai.Exchange) {
+	if p.opts.VerboseAI {
+		p.reportExchange(exchange)
+	}
+	if p.transcript == nil {
+		return
+	}
+	if err := p.transcript.Record(exchange); err != nil {
+		p.warn("%v", err)
+	}
+}
+
+// iteration describes a test run for the session store.
+func (p *pipeline) iteration(testCode, code string, result *TestResult) storage.Iteration {
+	return storage.Iteration{
+		TestCode: testCode,
+		Code:     code,
+		TestLogs: result.Output,
+		Success:  result.Success,
+		Model:    p.aiClient.LastModel(),
+		Passed:   result.Passed,
+		Failed:   result.Failed,
+	}
+}
+
+// generate generates the tests and then the initial implementation.
+func (p *pipeline) generate(ctx context.Context, ws *workspace) (testCode, code string, err error) {
+	description, language := p.opts.Description, p.opts.Language
+
+	if err := ctx.Err(); err != nil {
+		return "", "", err
+	}
+
+	// Generate tests
+	p.info("Generating tests...")
+	p.observer.OnGenerate(StepTests)
+	_, ph := p.startPhase(ctx, SpanGenerateTests)
+	testCode, err = p.testGen.GenerateTests(ctx, description, language)
+	ph.end(err)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate tests: %w", err)
+	}
+	testCode = p.cleanTests(testCode)
+
+	if p.opts.MaxTestLines > 0 {
+		testCode, err = p.consolidateTests(ctx, description, testCode, language)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to consolidate tests: %w", err)
+		}
+	}
+
+	if p.opts.StrictTests {
+		testCode, err = p.strengthenTests(ctx, description, testCode, language)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to strengthen tests: %w", err)
+		}
+	}
+
+	if p.opts.TestsFirst {
+		testCode, err = p.checkTestsFail(ctx, ws, description, testCode, language)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	if p.opts.ReviewTests != nil {
+		testCode, err = p.opts.ReviewTests(testCode, language)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return "", "", err
+	}
+
+	// Generate initial implementation
+	p.info("Generating initial implementation...")
+	p.observer.OnGenerate(StepImplementation)
+	_, ph = p.startPhase(ctx, SpanGenerateImplementation)
+	code, err = p.codeGen.GenerateImplementation(ctx, description, testCode, language)
+	ph.end(err)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate implementation: %w", err)
+	}
+	code = p.cleanCode(code)
+	return testCode, code, nil
+}
+
+func (p *pipeline) run(ctx context.Context) (*Result, error) {
+	started := time.Now()
+	description, language := p.opts.Description, p.opts.Language
+
+	ws, err := p.prepare(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer p.release(ws)
+	session, runner, workDir, outputDir, finalFiles := ws.session, ws.runner, ws.dir, ws.outputDir, ws.finalFiles
+
+	testCode, code := p.opts.Tests, p.opts.Implementation
+	if testCode != "" {
+		p.info("Using the provided tests and implementation")
+	} else {
+		testCode, code, err = p.generate(ctx, ws)
+		if err != nil {
+			return nil, err
+		}
+		if p.opts.Candidates > 1 {
+			code, err = p.selectCandidate(ctx, ws, testCode, code)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	original := code
+
+	// Save test and implementation files
+	if err := p.writeFiles(workDir, testCode, code, language); err != nil {
+		return nil, fmt.Errorf("failed to write files: %w", err)
+	}
+
+	// Iteration loop
+	var success bool
+	var lastTestOutput string
+	var iterations int
+	var mutantsChecked bool
+	var vetChecked bool
+	var examplesChecked bool
+	var importRewrites int
+	var fuzzChecked bool
+	var assertionsChecked bool
+	var mainResult *MainResult
+	var review *generator.Review
+	var best *bestIteration
+	var staleRuns int
+	var plateaued bool
+	iterationLimit := p.opts.MaxIterations
+	for i := 0; i < iterationLimit; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		iterations = i + 1
+		p.observer.OnIterationStart(i+1, iterationLimit)
+
+		result, err := p.runTests(ctx, ws, i+1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to run tests: %w", err)
+		}
+		p.observer.OnTestResult(i+1, result)
+
+		lastTestOutput = result.Output
+		// Store iteration
+		if err := p.store.AddIteration(session.ID, p.iteration(testCode, code, result)); err != nil {
+			return nil, fmt.Errorf("failed to store iteration: %w", err)
+		}
+
+		if result.Success && p.opts.Vet && !vetChecked {
+			vetChecked = true
+			vet, err := runner.RunVet(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to run go vet: %w", err)
+			}
+			if !vet.Success {
+				p.warn("go vet reported issues:")
+				p.observer.OnMessage(EventOutput, vet.Output)
+				p.warn("Asking the AI to fix the go vet issues...")
+				p.observer.OnGenerate(StepFix)
+				fixResult, err := p.fix(ctx, i+1, "vet", code, testCode, "The tests pass, but go vet reported:\n"+vet.Output, "")
+				if err != nil {
+					return nil, fmt.Errorf("failed to fix code: %w", err)
+				}
+				code = fixResult.Code
+				testCode = fixResult.TestCode
+				if err := p.writeFiles(workDir, testCode, code, language); err != nil {
+					return nil, fmt.Errorf("failed to write files: %w", err)
+				}
+				// The fixed code always gets at least one run
+				if i == iterationLimit-1 {
+					iterationLimit++
+				}
+				continue
+			}
+			p.success("go vet found no issues")
+		}
+
+		if result.Success && len(p.opts.AllowedImports) > 0 {
+			disallowed, err := disallowedImports(code, p.opts.AllowedImports)
+			if err != nil {
+				return nil, err
+			}
+			if len(disallowed) > 0 {
+				importRewrites++
+				p.warn("The implementation imports packages that aren't allowed: %s", strings.Join(disallowed, ", "))
+				p.warn("Asking the AI to rewrite it with the allowed imports...")
+				p.observer.OnGenerate(StepFix)
+				fixResult, err := p.fix(ctx, i+1, "imports", code, testCode, importsFailure(disallowed, p.opts.AllowedImports), "")
+				if err != nil {
+					return nil, fmt.Errorf("failed to fix code: %w", err)
+				}
+				code = fixResult.Code
+				testCode = fixResult.TestCode
+				if err := p.writeFiles(workDir, testCode, code, language); err != nil {
+					return nil, fmt.Errorf("failed to write files: %w", err)
+				}
+				// The first rewrite always gets a run; later ones count
+				// against the iterations, so the run can't loop forever
+				if i == iterationLimit-1 && importRewrites == 1 {
+					iterationLimit++
+				}
+				continue
+			}
+		}
+
+		if result.Success && p.iface != nil && !assertionsChecked {
+			assertionsChecked = true
+			if missing := p.iface.missingAssertions(code); len(missing) > 0 {
+				p.warn("The implementation doesn't assert that it implements %s; asking the AI to add it...", strings.Join(missing, ", "))
+				p.observer.OnGenerate(StepFix)
+				fixResult, err := p.fix(ctx, i+1, "implements", code, testCode, assertionFailure(missing), "")
+				if err != nil {
+					return nil, fmt.Errorf("failed to fix code: %w", err)
+				}
+				code = fixResult.Code
+				testCode = fixResult.TestCode
+				if err := p.writeFiles(workDir, testCode, code, language); err != nil {
+					return nil, fmt.Errorf("failed to write files: %w", err)
+				}
+				// The fixed code always gets at least one run, which compiles the assertion
+				if i == iterationLimit-1 {
+					iterationLimit++
+				}
+				continue
+			}
+			p.success("The implementation satisfies %s", strings.Join(p.iface.names, ", "))
+		}
+
+		if result.Success && p.opts.Fuzz && !fuzzChecked {
+			fuzzChecked = true
+			crash, err := p.fuzz(ctx, runner, session.ID, testCode)
+			if err != nil {
+				return nil, fmt.Errorf("failed to run fuzz tests: %w", err)
+			}
+			if crash != nil {
+				p.warn("Asking the AI to fix the crash found by %s...", crash.Target)
+				p.observer.OnGenerate(StepFix)
+				fixResult, err := p.fix(ctx, i+1, "fuzz", code, testCode, fuzzFailure(crash), "")
+				if err != nil {
+					return nil, fmt.Errorf("failed to fix code: %w", err)
+				}
+				code = fixResult.Code
+				testCode = fixResult.TestCode
+				if err := p.writeFiles(workDir, testCode, code, language); err != nil {
+					return nil, fmt.Errorf("failed to write files: %w", err)
+				}
+				// The fixed code always gets at least one run, which replays the crash
+				if i == iterationLimit-1 {
+					iterationLimit++
+				}
+				continue
+			}
+		}
+
+		if result.Success && p.opts.Examples && !examplesChecked {
+			examplesChecked = true
+			files, err := p.testFiles(testCode)
+			if err != nil {
+				return nil, err
+			}
+			var sources []string
+			for _, file := range files {
+				sources = append(sources, file.Content)
+			}
+			verified, unverified := goExamples(sources)
+			if len(verified) == 0 || len(unverified) > 0 {
+				p.warn("The tests don't have verified examples for every function; asking the AI to add them...")
+				p.observer.OnGenerate(StepStrengthen)
+				testCode, err = p.testGen.StrengthenTests(ctx, description, testCode, language, examplesWeakness(unverified))
+				if err != nil {
+					return nil, fmt.Errorf("failed to add examples: %w", err)
+				}
+				testCode = p.cleanTests(testCode)
+				if err := p.writeFiles(workDir, testCode, code, language); err != nil {
+					return nil, fmt.Errorf("failed to write files: %w", err)
+				}
+				// The new examples always get at least one run
+				if i == iterationLimit-1 {
+					iterationLimit++
+				}
+				continue
+			}
+			if missing := examplesNotRun(result.Output, verified); len(missing) > 0 {
+				p.warn("go test didn't report running these examples: %s", strings.Join(missing, ", "))
+			} else {
+				p.success("go test verified the output of %d examples: %s", len(verified), strings.Join(verified, ", "))
+			}
+		}
+
+		if result.Success && p.opts.MutationTest && !mutantsChecked {
+			mutantsChecked = true
+			survivors, err := p.findSurvivingMutants(ctx, runner, code, language)
+			if err != nil {
+				return nil, fmt.Errorf("failed to run mutation tests: %w", err)
+			}
+			if len(survivors) > 0 {
+				p.warn("Asking the AI to add test cases that catch the surviving mutants...")
+				p.observer.OnGenerate(StepStrengthen)
+				testCode, err = p.testGen.StrengthenTests(ctx, description, testCode, language, mutantWeakness(survivors))
+				if err != nil {
+					return nil, fmt.Errorf("failed to strengthen tests: %w", err)
+				}
+				testCode = p.cleanTests(testCode)
+				if err := p.writeFiles(workDir, testCode, code, language); err != nil {
+					return nil, fmt.Errorf("failed to write files: %w", err)
+				}
+				// The strengthened tests always get at least one run
+				if i == iterationLimit-1 {
+					iterationLimit++
+				}
+				continue
+			}
+		}
+
+		if result.Success && p.opts.RunMain && mainResult == nil {
+			mainResult, err = p.runMain(ctx, runner, language)
+			if err != nil {
+				return nil, fmt.Errorf("failed to run the program: %w", err)
+			}
+			if !mainResult.Success() {
+				p.warn("Asking the AI to fix the program...")
+				p.observer.OnGenerate(StepFix)
+				fixResult, err := p.fix(ctx, i+1, "run-main", code, testCode, mainFailure(mainResult), "")
+				if err != nil {
+					return nil, fmt.Errorf("failed to fix code: %w", err)
+				}
+				code = fixResult.Code
+				testCode = fixResult.TestCode
+				if err := p.writeFiles(workDir, testCode, code, language); err != nil {
+					return nil, fmt.Errorf("failed to write files: %w", err)
+				}
+				// The fixed code always gets at least one run
+				if i == iterationLimit-1 {
+					iterationLimit++
+				}
+				continue
+			}
+		}
+
+		if result.Success && p.opts.Review && review == nil {
+			review, err = p.review(ctx, description, code, testCode, language)
+			if err != nil {
+				return nil, fmt.Errorf("failed to review code: %w", err)
+			}
+			if review.Serious() {
+				p.warn("Asking the AI to fix the issues found in review...")
+				p.observer.OnGenerate(StepFix)
+				fixResult, err := p.fix(ctx, i+1, "review", code, testCode, review.FixRequest(), "")
+				if err != nil {
+					return nil, fmt.Errorf("failed to fix code: %w", err)
+				}
+				code = fixResult.Code
+				testCode = fixResult.TestCode
+				if err := p.writeFiles(workDir, testCode, code, language); err != nil {
+					return nil, fmt.Errorf("failed to write files: %w", err)
+				}
+				// The fixed code always gets at least one run
+				if i == iterationLimit-1 {
+					iterationLimit++
+				}
+				continue
+			}
+		}
+
+		if result.Success {
+			success = true
+			p.success("All tests passed!")
+			break
+		}
+
+		// Runs without test counts, e.g. build failures or output the
+		// runner can't parse, tell nothing about progress and don't count
+		if p.opts.Plateau > 0 && result.Passed+result.Failed > 0 {
+			if best == nil || result.Passed > best.passed {
+				best = &bestIteration{number: i + 1, passed: result.Passed, testCode: testCode, code: code, output: result.Output}
+				staleRuns = 0
+			} else if staleRuns++; staleRuns >= p.opts.Plateau {
+				plateaued = true
+				break
+			}
+		}
+
+		var hint string
+		if p.opts.FixHint != nil {
+			hint, err = p.opts.FixHint(i+1, result)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if p.opts.FixStrategy == FixStrategyImplOnly {
+			p.warn("Attempting to fix the implementation...")
+		} else {
+			p.warn("Attempting to fix implementation and tests...")
+		}
+		p.observer.OnGenerate(StepFix)
+
+		// Fix the implementation, and the tests unless they're frozen
+		fixResult, err := p.fix(ctx, i+1, "tests", code, testCode, result.Output, hint)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fix code: %w", err)
+		}
+
+		// Update both files
+		code = fixResult.Code
+		testCode = fixResult.TestCode
+
+		if err := p.writeFiles(workDir, testCode, code, language); err != nil {
+			return nil, fmt.Errorf("failed to write files: %w", err)
+		}
+	}
+
+	if plateaued {
+		p.warn("Passing tests haven't improved for %d iterations; stopping early with iteration %d (%d passing)",
+			p.opts.Plateau, best.number, best.passed)
+		testCode, code, lastTestOutput = best.testCode, best.code, best.output
+		if err := p.writeFiles(workDir, testCode, code, language); err != nil {
+			return nil, fmt.Errorf("failed to write files: %w", err)
+		}
+	}
+
+	// Always copy files, even if tests didn't pass
+	var renames []Rename
+	if success && p.opts.DescribeOutput {
+		code, testCode, renames, err = p.describeOutput(ctx, ws, code, testCode)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if p.opts.MultiFileTests {
+		tests, err := p.testFiles(testCode)
+		if err != nil {
+			return nil, err
+		}
+		finalFiles = withTestFiles(finalFiles, language, tests)
+	}
+	if !p.opts.NoWrite {
+		if err := p.copyFinalFiles(workDir, outputDir, finalFiles); err != nil {
+			return nil, fmt.Errorf("failed to copy final files: %w", err)
+		}
+		if p.opts.Gitignore && p.opts.PackageDir == "" {
+			if err := p.writeGitignore(outputDir, language); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	summary := &Result{
+		SessionID:  session.ID,
+		Success:    success,
+		Iterations: iterations,
+		OutputDir:  outputDir,
+		Duration:   time.Since(started),
+		TestCode:   testCode,
+		Code:       code,
+		Main:       mainResult,
+		Renames:    renames,
+		Plateaued:  plateaued,
+	}
+	if review != nil {
+		for _, finding := range review.Findings {
+			summary.ReviewFindings = append(summary.ReviewFindings, finding.String())
+		}
+	}
+	if p.opts.Tests != "" {
+		summary.Patch = p.reportPatch(original, code, outputDir)
+	}
+
+	if plateaued {
+		p.failure("Stopped after %d iterations without improvement in passing tests", iterations)
+		p.warn("Test output of iteration %d:", best.number)
+		p.observer.OnMessage(EventOutput, lastTestOutput)
+		p.warn("Files of iteration %d have been saved to: %s", best.number, p.filesLocation(ws))
+		return summary, fmt.Errorf("%w: plateaued after %d iterations", ErrNotConverged, iterations)
+	}
+	if !success {
+		p.failure("Failed to generate passing implementation after %d iterations", iterations)
+		p.warn("Last test output:")
+		p.observer.OnMessage(EventOutput, lastTestOutput)
+		p.warn("Files have been saved to: %s", p.filesLocation(ws))
+		return summary, fmt.Errorf("%w after %d iterations", ErrNotConverged, iterations)
+	}
+
+	summary.API = p.recordAPI(session.ID, code)
+	if p.opts.Signature != "" {
+		p.checkSignature(code)
+	}
+	if p.opts.Explain {
+		summary.Explanation = p.explain(ctx, code, testCode, language, outputDir)
+	}
+	if p.opts.CommitMessage {
+		summary.CommitMessage = p.commitMessage(ctx, description, code, testCode, language, outputDir)
+	}
+
+	p.success("Successfully generated code! Check %s for the files.", p.filesLocation(ws))
+	return summary, nil
+}
+
+// Files saved next to the generated code
+const (
+	explanationFile   = "EXPLANATION.md"
+	commitMessageFile = "COMMIT_MSG"
+)
+
+// recordAPI shows the public API of the final code and records it in the
+// session. Failures to record it are reported but don't fail the run.
+func (p *pipeline) recordAPI(sessionID, code string) []string {
+	api := PublicAPI(code, p.opts.Language)
+	if len(api) == 0 {
+		return nil
+	}
+	p.info("Public API:")
+	p.observer.OnMessage(EventOutput, strings.Join(api, "\n"))
+	if err := p.store.SetAPI(sessionID, api); err != nil {
+		p.warn("Failed to record the public API: %v", err)
+	}
+	return api
+}
+
+// checkSignature warns when the final code doesn't declare Options.Signature.
+func (p *pipeline) checkSignature(code string) {
+	if declaresSignature(code, p.opts.Language, p.opts.Signature) {
+		p.success("The implementation declares %s", p.opts.Signature)
+		return
+	}
+	p.warn("The implementation doesn't declare the expected signature %s", p.opts.Signature)
+	if found := PublicSignatures(code, p.opts.Language); len(found) > 0 {
+		p.warn("It declares:")
+		p.observer.OnMessage(EventOutput, strings.Join(found, "\n"))
+	}
+}
+
+// reportPatch shows the diff from the provided implementation to the final
+// code and saves it next to the code. Failures to save are reported but
+// don't fail the run.
+func (p *pipeline) reportPatch(original, code, outputDir string) string {
+	_, implName := FileNames(p.opts.Language)
+	patch := unifiedDiff(implName, original, code)
+	if patch == "" {
+		p.info("The implementation was not changed")
+		return ""
+	}
+	p.info("Changes to the implementation:")
+	p.observer.OnMessage(EventOutput, patch)
+	if p.opts.NoWrite {
+		return patch
+	}
+	path := filepath.Join(outputDir, patchFile)
+	if err := p.writeOutputFile(path, []byte(patch)); err != nil {
+		p.warn("Failed to save the patch: %v", err)
+	} else {
+		p.info("Saved the patch to %s", path)
+	}
+	return patch
+}
+
+// explain asks the AI to explain the final code and saves the explanation
+// next to it. Failures are reported but don't fail the run.
+func (p *pipeline) explain(ctx context.Context, code, testCode, language, outputDir string) string {
+	p.info("Generating explanation...")
+	p.observer.OnGenerate(StepExplanation)
+	explanation, err := p.codeGen.ExplainCode(ctx, code, testCode, language)
+	if err != nil {
+		p.warn("Failed to generate explanation: %v", err)
+		return ""
+	}
+
+	p.info("Explanation:")
+	p.observer.OnMessage(EventOutput, explanation)
+
+	// Don't add stray files to an existing package, nor write any with NoWrite
+	if p.opts.PackageDir == "" && !p.opts.NoWrite {
+		path := filepath.Join(outputDir, explanationFile)
+		if err := p.writeOutputFile(path, []byte(explanation+"\n")); err != nil {
+			p.warn("Failed to save explanation: %v", err)
+		} else {
+			p.info("Saved explanation to %s", path)
+		}
+	}
+	return explanation
+}
+
+// commitMessage asks the AI for a commit message for the final code and
+// saves it next to it. Failures are reported but don't fail the run.
+func (p *pipeline) commitMessage(ctx context.Context, description, code, testCode, language, outputDir string) string {
+	p.info("Generating commit message...")
+	p.observer.OnGenerate(StepCommitMessage)
+	message, err := p.codeGen.GenerateCommitMessage(ctx, description, code, testCode, language)
+	if err != nil {
+		p.warn("Failed to generate commit message: %v", err)
+		return ""
+	}
+
+	p.info("Commit message:")
+	p.observer.OnMessage(EventOutput, message)
+
+	// Don't add stray files to an existing package, nor write any with NoWrite
+	if p.opts.PackageDir == "" && !p.opts.NoWrite {
+		path := filepath.Join(outputDir, commitMessageFile)
+		if err := p.writeOutputFile(path, []byte(message+"\n")); err != nil {
+			p.warn("Failed to save commit message: %v", err)
+		} else {
+			p.info("Saved commit message to %s (use git commit -F %s)", path, path)
+		}
+	}
+	return message
+}
@@ -0,0 +1,143 @@
+package aiterate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/prathyushnallamothu/aiterate/internal/storage"
+	"github.com/prathyushnallamothu/aiterate/internal/telemetry"
+)
+
+// Tie-breaks for Options.Tiebreak, choosing among candidates that all pass
+const (
+	// TiebreakSize prefers the smallest implementation
+	TiebreakSize = "size"
+	// TiebreakCoverage prefers the highest statement coverage, then the
+	// smallest implementation (Go only)
+	TiebreakCoverage = "coverage"
+)
+
+// candidate is one of the implementations generated with Options.Candidates.
+type candidate struct {
+	number   int
+	code     string
+	result   *TestResult
+	coverage float64
+}
+
+func (c *candidate) record() storage.Candidate {
+	return storage.Candidate{
+		Number:   c.number,
+		Success:  c.result.Success,
+		Passed:   c.result.Passed,
+		Failed:   c.result.Failed,
+		Size:     len(c.code),
+		Coverage: c.coverage,
+	}
+}
+
+// selectCandidate generates Options.Candidates-1 more implementations for
+// testCode alongside first, runs the tests against each and returns the
+// best one, recording the choice in the session.
+func (p *pipeline) selectCandidate(ctx context.Context, ws *workspace, testCode, first string) (string, error) {
+	description, language, count := p.opts.Description, p.opts.Language, p.opts.Candidates
+
+	p.info("Generating %d more candidate implementations...", count-1)
+	p.observer.OnGenerate(StepCandidates)
+	codes := make([]string, count)
+	errs := make([]error, count)
+	codes[0] = first
+	_, ph := p.startPhase(ctx, SpanGenerateCandidates, telemetry.Attr("aiterate.candidates", count))
+	var wg sync.WaitGroup
+	p.holdMessages()
+	for i := 1; i < count; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i], errs[i] = p.codeGen.GenerateImplementation(ctx, description, testCode, language)
+		}(i)
+	}
+	wg.Wait()
+	p.releaseMessages()
+	ph.end(nil)
+
+	var candidates []*candidate
+	for i, code := range codes {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		if errs[i] != nil {
+			p.warn("Failed to generate candidate %d: %v", i+1, errs[i])
+			continue
+		}
+		if i > 0 {
+			code = p.cleanCode(code)
+		}
+
+		p.info("Testing candidate %d of %d...", i+1, count)
+		if err := p.writeFiles(ws.dir, testCode, code, language); err != nil {
+			return "", fmt.Errorf("failed to write files: %w", err)
+		}
+		result, err := ws.runner.RunTests(ctx, language)
+		if err != nil {
+			return "", fmt.Errorf("failed to run tests: %w", err)
+		}
+		c := &candidate{number: i + 1, code: code, result: result}
+		if result.Success && p.opts.Tiebreak == TiebreakCoverage {
+			if c.coverage, err = ws.runner.RunCoverage(ctx); err != nil {
+				p.warn("Failed to measure the coverage of candidate %d: %v", i+1, err)
+			}
+		}
+		candidates = append(candidates, c)
+	}
+
+	best, reason := bestCandidate(candidates, p.opts.Tiebreak)
+	p.success("Using candidate %d: %s", best.number, reason)
+
+	selection := storage.CandidateSelection{Chosen: best.number, Reason: reason}
+	for _, c := range candidates {
+		selection.Candidates = append(selection.Candidates, c.record())
+	}
+	if err := p.store.SetCandidateSelection(ws.session.ID, selection); err != nil {
+		return "", fmt.Errorf("failed to store candidate selection: %w", err)
+	}
+	return best.code, nil
+}
+
+// bestCandidate ranks candidates that pass first, then by tiebreak, and
+// failing ones by the number of passing tests. It returns the first in the
+// ranking and why it was chosen. candidates must not be empty.
+func bestCandidate(candidates []*candidate, tiebreak string) (*candidate, string) {
+	ranked := append([]*candidate(nil), candidates...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		switch {
+		case a.result.Success != b.result.Success:
+			return a.result.Success
+		case !a.result.Success:
+			return a.result.Passed > b.result.Passed
+		case tiebreak == TiebreakCoverage && a.coverage != b.coverage:
+			return a.coverage > b.coverage
+		}
+		return len(a.code) < len(b.code)
+	})
+
+	best := ranked[0]
+	passing := 0
+	for _, c := range candidates {
+		if c.result.Success {
+			passing++
+		}
+	}
+	switch {
+	case passing == 0:
+		return best, fmt.Sprintf("no candidate passed all tests; it passed the most (%d)", best.result.Passed)
+	case passing == 1:
+		return best, "the only candidate passing all tests"
+	case tiebreak == TiebreakCoverage:
+		return best, fmt.Sprintf("highest coverage (%.1f%%) of %d passing candidates", best.coverage, passing)
+	}
+	return best, fmt.Sprintf("smallest implementation (%d bytes) of %d passing candidates", len(best.code), passing)
+}
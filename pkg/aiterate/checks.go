@@ -0,0 +1,313 @@
+package aiterate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/prathyushnallamothu/aiterate/internal/executor"
+	"github.com/prathyushnallamothu/aiterate/internal/generator"
+)
+
+// maxStrengthenAttempts bounds how often weak tests are sent back to the AI
+const maxStrengthenAttempts = 2
+
+// strengthenTests checks the tests' assertions and asks the AI to improve
+// them while they're too weak.
+func (p *pipeline) strengthenTests(ctx context.Context, description, testCode, language string) (string, error) {
+	for attempt := 0; ; attempt++ {
+		quality, ok := generator.AnalyzeTests(testCode, language)
+		if !ok {
+			p.warn("Strict test checks are not available for %s", language)
+			return testCode, nil
+		}
+		weakness := quality.Weakness()
+		if weakness == "" {
+			p.info("Tests passed strict checks (%d tests, %d assertions)", quality.Tests, quality.Assertions)
+			return testCode, nil
+		}
+		if attempt == maxStrengthenAttempts {
+			p.warn("Tests are still weak after %d attempts (%s); continuing anyway", attempt, weakness)
+			return testCode, nil
+		}
+
+		p.warn("Generated tests are too weak: %s. Asking the AI to strengthen them...", weakness)
+		p.observer.OnGenerate(StepStrengthen)
+		var err error
+		testCode, err = p.testGen.StrengthenTests(ctx, description, testCode, language, weakness)
+		if err != nil {
+			return "", err
+		}
+		testCode = p.cleanTests(testCode)
+	}
+}
+
+// stubWeakness describes tests passing against a stub for the
+// strengthening prompt
+const stubWeakness = "every test passes against a stub implementation whose functions do nothing and return zero or empty values, so the tests don't check the described behavior"
+
+// checkTestsFail runs the tests against a stub implementation that does
+// nothing and asks the AI to strengthen them while they all pass, since
+// tests that pass before the code exists verify nothing.
+func (p *pipeline) checkTestsFail(ctx context.Context, ws *workspace, description, testCode, language string) (string, error) {
+	for attempt := 0; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		p.info("Running the tests against an empty stub...")
+		p.observer.OnGenerate(StepStub)
+		stub, err := p.codeGen.GenerateStub(ctx, testCode, language)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate stub: %w", err)
+		}
+		stub = p.cleanCode(stub)
+		if err := p.writeFiles(ws.dir, testCode, stub, language); err != nil {
+			return "", fmt.Errorf("failed to write files: %w", err)
+		}
+		result, err := ws.runner.RunTests(ctx, language)
+		if err != nil {
+			return "", fmt.Errorf("failed to run tests: %w", err)
+		}
+		if !result.Success {
+			if result.Passed+result.Failed == 0 {
+				p.warn("Tests fail against the stub, but no test results were counted; the stub may not compile")
+			} else {
+				p.success("Tests fail against the stub as expected (%d passed, %d failed)", result.Passed, result.Failed)
+			}
+			return testCode, nil
+		}
+		if attempt == maxStrengthenAttempts {
+			p.warn("Tests still pass against the stub after %d attempts; continuing anyway", attempt)
+			return testCode, nil
+		}
+
+		p.warn("Tests pass against an empty stub, so they don't verify anything. Asking the AI to strengthen them...")
+		p.observer.OnGenerate(StepStrengthen)
+		testCode, err = p.testGen.StrengthenTests(ctx, description, testCode, language, stubWeakness)
+		if err != nil {
+			return "", fmt.Errorf("failed to strengthen tests: %w", err)
+		}
+		testCode = p.cleanTests(testCode)
+	}
+}
+
+// maxConsolidateAttempts bounds how often oversized tests are sent back to the AI
+const maxConsolidateAttempts = 2
+
+// consolidateTests asks the AI to shrink tests over Options.MaxTestLines
+// lines while they're too long.
+func (p *pipeline) consolidateTests(ctx context.Context, description, testCode, language string) (string, error) {
+	max := p.opts.MaxTestLines
+	for attempt := 0; ; attempt++ {
+		lines := strings.Count(strings.TrimRight(testCode, "\n"), "\n") + 1
+		if lines <= max {
+			if attempt > 0 {
+				p.success("Consolidated the tests to %d lines", lines)
+			}
+			return testCode, nil
+		}
+		if attempt == maxConsolidateAttempts {
+			p.warn("Tests are still %d lines after %d attempts, over the limit of %d; continuing anyway", lines, attempt, max)
+			return testCode, nil
+		}
+
+		p.warn("Generated tests are %d lines, over the limit of %d. Asking the AI to consolidate them...", lines, max)
+		p.observer.OnGenerate(StepConsolidate)
+		var err error
+		testCode, err = p.testGen.ConsolidateTests(ctx, description, testCode, language, lines, max)
+		if err != nil {
+			return "", err
+		}
+		testCode = p.cleanTests(testCode)
+	}
+}
+
+// weakenedTestsShare is the share of the tests' assertions a fix may remove
+// before Options.GuardTests reports it
+const weakenedTestsShare = 0.25
+
+// TestChange is a fix's rewrite of the tests that removes many of their
+// assertions, reported with Options.GuardTests.
+type TestChange struct {
+	Iteration int
+	// Before and After count the assertions in the tests before and after the fix
+	Before int
+	After  int
+	// Diff is the unified diff of the tests
+	Diff string
+}
+
+// guardTests compares the assertions in the tests before and after a fix
+// and, when the fix removes more than weakenedTestsShare of them, shows
+// the change and asks Options.ConfirmTestChanges whether to accept it. It
+// returns the tests to continue with.
+func (p *pipeline) guardTests(iteration int, before, after string) (string, error) {
+	language := p.opts.Language
+	old, ok := generator.AnalyzeTests(before, language)
+	if !ok || old.Assertions == 0 {
+		return after, nil
+	}
+	updated, _ := generator.AnalyzeTests(after, language)
+	removed := old.Assertions - updated.Assertions
+	if float64(removed) <= weakenedTestsShare*float64(old.Assertions) {
+		return after, nil
+	}
+
+	testName, _ := FileNames(language)
+	change := TestChange{Iteration: iteration, Before: old.Assertions, After: updated.Assertions, Diff: unifiedDiff(testName, before, after)}
+	p.warn("The fix removes %d of the tests' %d assertions, which may weaken them to pass:", removed, old.Assertions)
+	p.observer.OnMessage(EventOutput, change.Diff)
+	if p.opts.ConfirmTestChanges == nil {
+		return after, nil
+	}
+	accept, err := p.opts.ConfirmTestChanges(change)
+	if err != nil {
+		return "", err
+	}
+	if !accept {
+		p.info("Keeping the previous tests")
+		return before, nil
+	}
+	return after, nil
+}
+
+// findSurvivingMutants mutates the passing implementation and reports the
+// mutants the tests don't catch.
+func (p *pipeline) findSurvivingMutants(ctx context.Context, runner *executor.TestRunner, code, language string) ([]executor.Mutant, error) {
+	mutants, err := executor.GenerateGoMutants(code)
+	if err != nil {
+		return nil, err
+	}
+	if len(mutants) == 0 {
+		p.info("No mutation candidates found in the implementation")
+		return nil, nil
+	}
+
+	p.info("Running tests against %d mutants...", len(mutants))
+	_, implFile := FileNames(language)
+	survivors, err := runner.FindSurvivingMutants(ctx, language, implFile, mutants)
+	if err != nil {
+		return nil, err
+	}
+	if len(survivors) == 0 {
+		p.success("Tests caught all %d mutants", len(mutants))
+		return nil, nil
+	}
+	p.warn("Tests missed %d of %d mutants:", len(survivors), len(mutants))
+	for _, mutant := range survivors {
+		p.warn("  %s", mutant.Description)
+	}
+	return survivors, nil
+}
+
+// mutantWeakness describes surviving mutants for the strengthening prompt.
+func mutantWeakness(survivors []executor.Mutant) string {
+	descriptions := make([]string, len(survivors))
+	for i, mutant := range survivors {
+		descriptions[i] = mutant.Description
+	}
+	return "they still pass when the implementation is deliberately broken (" + strings.Join(descriptions, "; ") +
+		"). Add test cases that would fail for these changes"
+}
+
+// fuzz runs the fuzz targets in the tests and records any crash found in
+// the session.
+func (p *pipeline) fuzz(ctx context.Context, runner *executor.TestRunner, sessionID, testCode string) (*executor.FuzzCrash, error) {
+	targets := executor.FuzzTargets(testCode)
+	if len(targets) == 0 {
+		p.warn("The tests contain no fuzz targets; skipping fuzzing")
+		return nil, nil
+	}
+
+	p.info("Fuzzing %d target(s) for %s each...", len(targets), p.opts.FuzzTime)
+	crash, err := runner.RunFuzz(ctx, targets, p.opts.FuzzTime)
+	if err != nil {
+		return nil, err
+	}
+	if crash == nil {
+		p.success("Fuzzing found no crashes")
+		return nil, nil
+	}
+
+	p.warn("%s found a failing input:", crash.Target)
+	p.observer.OnMessage(EventOutput, crash.Output)
+	if err := p.store.AddFuzzCrash(sessionID, crash.Target, crash.Input, crash.Output); err != nil {
+		return nil, fmt.Errorf("failed to store fuzz crash: %w", err)
+	}
+	return crash, nil
+}
+
+// fuzzFailure describes a fuzz crash for the fix prompt.
+func fuzzFailure(crash *executor.FuzzCrash) string {
+	failure := fmt.Sprintf("The tests pass, but fuzzing with %s found an input that fails.\n", crash.Target)
+	if crash.Input != "" {
+		failure += fmt.Sprintf("Failing input (go test corpus file):\n%s\n", crash.Input)
+	}
+	return failure + "Fuzz output:\n" + crash.Output
+}
+
+// runMain runs the implementation once as a program and reports its exit
+// code and output.
+func (p *pipeline) runMain(ctx context.Context, runner *executor.TestRunner, language string) (*executor.MainResult, error) {
+	p.info("Running the program once as a smoke test...")
+	result, err := runner.RunMain(ctx, language, p.opts.MainArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case result.TimedOut:
+		p.warn("%s did not exit within %s", result.Command, executor.MainTimeout)
+	case result.ExitCode != 0:
+		p.warn("%s exited with code %d", result.Command, result.ExitCode)
+	default:
+		p.success("%s exited with code 0", result.Command)
+	}
+	if output := result.Stdout + result.Stderr; output != "" {
+		p.observer.OnMessage(EventOutput, output)
+	}
+	return result, nil
+}
+
+// mainFailure describes a failed program run for the fix prompt.
+func mainFailure(result *executor.MainResult) string {
+	failure := fmt.Sprintf("The tests pass, but running the program with `%s` exited with code %d.", result.Command, result.ExitCode)
+	if result.TimedOut {
+		failure = fmt.Sprintf("The tests pass, but running the program with `%s` (with no input on stdin) did not exit within %s.", result.Command, executor.MainTimeout)
+	}
+	return failure + "\nStdout:\n" + result.Stdout + "\nStderr:\n" + result.Stderr
+}
+
+// review asks the review model to critique the passing code and reports
+// its findings.
+func (p *pipeline) review(ctx context.Context, description, code, testCode, language string) (*generator.Review, error) {
+	p.info("Reviewing the code...")
+	p.observer.OnGenerate(StepReview)
+	review, err := p.reviewer.ReviewCode(ctx, description, code, testCode, language)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(review.Findings) == 0 {
+		p.success("Review found no issues")
+		return review, nil
+	}
+	p.warn("Review found %d issue(s):", len(review.Findings))
+	for _, finding := range review.Findings {
+		p.warn("  %s", finding)
+	}
+	if !review.Serious() {
+		p.info("Only minor issues found; accepting the code")
+	}
+	return review, nil
+}
+
+// bestIteration is the failing iteration with the most passing tests, kept
+// for Options.Plateau.
+type bestIteration struct {
+	number   int
+	passed   int
+	testCode string
+	code     string
+	output   string
+}
@@ -0,0 +1,116 @@
+package aiterate
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/prathyushnallamothu/aiterate/internal/ai"
+	"github.com/prathyushnallamothu/aiterate/internal/executor"
+	"github.com/prathyushnallamothu/aiterate/internal/generator"
+)
+
+// ErrNotConverged is returned when the tests never passed within the iteration limit.
+var ErrNotConverged = errors.New("tests did not pass")
+
+// ErrDependenciesRejected is returned when the code imports modules over
+// Options.MaxNewDeps or declined by Options.ConfirmDeps.
+var ErrDependenciesRejected = errors.New("dependencies rejected")
+
+// ErrImplementationBug is returned when tests regenerated with
+// Options.RegenerateTests fail because of a bug in the implementation.
+var ErrImplementationBug = errors.New("implementation bug found")
+
+// Sentinel errors for the failure categories of a run, for use with errors.Is
+var (
+	// ErrAPIFailure reports that a request to the AI provider failed
+	ErrAPIFailure = ai.ErrAPIFailure
+	// ErrToolchainMissing reports that a language toolchain couldn't be found
+	ErrToolchainMissing = executor.ErrToolchainMissing
+	// ErrInvalidAIResponse reports that the AI returned output that couldn't be used
+	ErrInvalidAIResponse = generator.ErrInvalidAIResponse
+)
+
+// APIError describes a failed AI provider request; use errors.As to get
+// its Category and Brief message.
+type APIError = ai.APIError
+
+// Reasons the output directory couldn't be created, for OutputDirError
+const (
+	OutputDirPermission   = "permission denied"
+	OutputDirNotDirectory = "path is a file"
+	OutputDirReadOnly     = "read-only filesystem"
+	OutputDirNoSpace      = "no space left on device"
+	OutputDirOther        = "other"
+)
+
+// OutputDirError is returned when the output directory can't be created.
+// Reason is one of the OutputDir constants, so callers can suggest a
+// remedy.
+type OutputDirError struct {
+	Dir    string
+	Reason string
+	// Path is the file in the way with OutputDirNotDirectory
+	Path string
+	Err  error
+}
+
+func (e *OutputDirError) Error() string {
+	switch e.Reason {
+	case OutputDirNotDirectory:
+		return fmt.Sprintf("cannot create output directory %s: %s is a file, not a directory", e.Dir, e.Path)
+	case OutputDirOther:
+		return fmt.Sprintf("cannot create output directory %s: %v", e.Dir, e.Err)
+	default:
+		return fmt.Sprintf("cannot create output directory %s: %s", e.Dir, e.Reason)
+	}
+}
+
+func (e *OutputDirError) Unwrap() error {
+	return e.Err
+}
+
+// outputDirError classifies a failure to create dir.
+func outputDirError(dir string, err error) error {
+	dirErr := &OutputDirError{Dir: dir, Reason: OutputDirOther, Err: err}
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		dirErr.Reason = OutputDirPermission
+	case errors.Is(err, syscall.EROFS):
+		dirErr.Reason = OutputDirReadOnly
+	case errors.Is(err, syscall.ENOSPC):
+		dirErr.Reason = OutputDirNoSpace
+	case errors.Is(err, syscall.ENOTDIR), errors.Is(err, fs.ErrExist):
+		if file := fileInPath(dir); file != "" {
+			dirErr.Reason = OutputDirNotDirectory
+			dirErr.Path = file
+		}
+	}
+	return dirErr
+}
+
+// fileInPath returns the first element of dir, from the top, that exists
+// but isn't a directory, or "" when there's none.
+func fileInPath(dir string) string {
+	dir = filepath.Clean(dir)
+	var parents []string
+	for path := dir; ; path = filepath.Dir(path) {
+		parents = append(parents, path)
+		if filepath.Dir(path) == path {
+			break
+		}
+	}
+	for i := len(parents) - 1; i >= 0; i-- {
+		info, err := os.Stat(parents[i])
+		if err != nil {
+			return ""
+		}
+		if !info.IsDir() {
+			return parents[i]
+		}
+	}
+	return ""
+}
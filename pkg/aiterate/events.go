@@ -0,0 +1,172 @@
+package aiterate
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/prathyushnallamothu/aiterate/internal/executor"
+)
+
+// EventKind identifies the step an Event reports.
+type EventKind int
+
+const (
+	// EventInfo, EventWarn, EventSuccess and EventFailure carry a log message
+	EventInfo EventKind = iota
+	EventWarn
+	EventSuccess
+	EventFailure
+	// EventOutput carries raw test output in Message
+	EventOutput
+	// EventIterationStart is sent before each test run
+	EventIterationStart
+	// EventTestResult carries the outcome of a test run in Result
+	EventTestResult
+	// EventGenerate is sent before each AI generation step named in Step
+	EventGenerate
+)
+
+// Step names an AI generation step reported to Observer.OnGenerate.
+type Step string
+
+const (
+	StepDirectoryName  Step = "directory name"
+	StepTests          Step = "tests"
+	StepStrengthen     Step = "stronger tests"
+	StepConsolidate    Step = "consolidated tests"
+	StepImplementation Step = "implementation"
+	StepCandidates     Step = "candidate implementations"
+	StepFix            Step = "fix"
+	StepFixTests       Step = "test fix"
+	StepReview         Step = "review"
+	StepDiagnosis      Step = "diagnosis"
+	StepExplanation    Step = "explanation"
+	StepNames          Step = "names"
+	StepCommitMessage  Step = "commit message"
+	StepStub           Step = "stub"
+)
+
+// Observer receives progress from a Generate run. Methods are called from
+// the goroutine running Generate.
+type Observer interface {
+	// OnGenerate is called before each AI generation step
+	OnGenerate(step Step)
+	// OnIterationStart is called before each test run
+	OnIterationStart(iteration, max int)
+	// OnTestResult is called with the outcome of each test run
+	OnTestResult(iteration int, result *TestResult)
+	// OnMessage is called with log lines; kind is EventInfo, EventWarn,
+	// EventSuccess, EventFailure or EventOutput
+	OnMessage(kind EventKind, message string)
+}
+
+// NopObserver ignores all progress. Embed it to implement only some of
+// the Observer methods.
+type NopObserver struct{}
+
+func (NopObserver) OnGenerate(Step)               {}
+func (NopObserver) OnIterationStart(int, int)     {}
+func (NopObserver) OnTestResult(int, *TestResult) {}
+func (NopObserver) OnMessage(EventKind, string)   {}
+
+// Event describes a single step of the generate/iterate loop.
+type Event struct {
+	Kind      EventKind
+	Message   string
+	Iteration int
+	Max       int
+	Step      Step
+	Result    *TestResult
+}
+
+// EventFunc adapts a function receiving every step as an Event to the
+// Observer interface.
+type EventFunc func(Event)
+
+func (f EventFunc) OnGenerate(step Step) {
+	f(Event{Kind: EventGenerate, Step: step, Message: fmt.Sprintf("Generating %s...", step)})
+}
+
+func (f EventFunc) OnIterationStart(iteration, max int) {
+	f(Event{Kind: EventIterationStart, Iteration: iteration, Max: max})
+}
+
+func (f EventFunc) OnTestResult(iteration int, result *TestResult) {
+	f(Event{Kind: EventTestResult, Iteration: iteration, Result: result})
+}
+
+func (f EventFunc) OnMessage(kind EventKind, message string) {
+	f(Event{Kind: kind, Message: message})
+}
+
+// heldMessages buffers the messages sent between hold and release, e.g.
+// AI request reports and fallback warnings while candidates are generated
+// concurrently, so they reach the Observer from the goroutine running
+// Generate.
+type heldMessages struct {
+	mu       sync.Mutex
+	holding  bool
+	messages []Event
+}
+
+// holdMessages buffers messages until releaseMessages.
+func (p *pipeline) holdMessages() {
+	p.held.mu.Lock()
+	defer p.held.mu.Unlock()
+	p.held.holding = true
+}
+
+// releaseMessages delivers the buffered messages in the order they were
+// sent and stops buffering. It must be called from the goroutine running
+// Generate once the concurrent requests are done.
+func (p *pipeline) releaseMessages() {
+	p.held.mu.Lock()
+	messages := p.held.messages
+	p.held.holding, p.held.messages = false, nil
+	p.held.mu.Unlock()
+	for _, m := range messages {
+		p.observer.OnMessage(m.Kind, m.Message)
+	}
+}
+
+func (p *pipeline) message(kind EventKind, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	p.held.mu.Lock()
+	if p.held.holding {
+		p.held.messages = append(p.held.messages, Event{Kind: kind, Message: message})
+		p.held.mu.Unlock()
+		return
+	}
+	p.held.mu.Unlock()
+	p.observer.OnMessage(kind, message)
+}
+
+// runnerMessage reports a progress message of the test runner.
+func (p *pipeline) runnerMessage(level executor.Level, message string) {
+	kind := EventInfo
+	switch level {
+	case executor.LevelWarn:
+		kind = EventWarn
+	case executor.LevelSuccess:
+		kind = EventSuccess
+	case executor.LevelFailure:
+		kind = EventFailure
+	}
+	p.message(kind, "%s", message)
+}
+
+func (p *pipeline) info(format string, args ...interface{}) {
+	p.message(EventInfo, format, args...)
+}
+
+func (p *pipeline) warn(format string, args ...interface{}) {
+	p.message(EventWarn, format, args...)
+}
+
+func (p *pipeline) success(format string, args ...interface{}) {
+	p.message(EventSuccess, format, args...)
+}
+
+func (p *pipeline) failure(format string, args ...interface{}) {
+	p.message(EventFailure, format, args...)
+}
@@ -0,0 +1,52 @@
+package aiterate
+
+import (
+	"go/doc"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// goExamples returns the Example functions in Go test files: verified ones
+// have an // Output: comment that go test checks, unverified ones are only
+// compiled.
+func goExamples(files []string) (verified, unverified []string) {
+	for _, source := range files {
+		file, err := parser.ParseFile(token.NewFileSet(), "", source, parser.ParseComments)
+		if err != nil {
+			continue
+		}
+		for _, example := range doc.Examples(file) {
+			name := "Example" + example.Name
+			if example.Output != "" || example.EmptyOutput {
+				verified = append(verified, name)
+			} else {
+				unverified = append(unverified, name)
+			}
+		}
+	}
+	return verified, unverified
+}
+
+// examplesNotRun returns the examples that didn't pass in the verbose go
+// test output.
+func examplesNotRun(output string, examples []string) []string {
+	var missing []string
+	for _, name := range examples {
+		if !strings.Contains(output, "--- PASS: "+name+" ") {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// examplesWeakness describes missing or unverified examples for the
+// strengthening prompt.
+func examplesWeakness(unverified []string) string {
+	const add = "Add a func ExampleXxx() for each exported function (ExampleXxx_suffix for more than one) that prints results with fmt.Println " +
+		"and ends with an // Output: comment holding the exact expected output, keeping the existing tests"
+	if len(unverified) == 0 {
+		return "they include no example functions that go test verifies. " + add
+	}
+	return "the examples " + strings.Join(unverified, ", ") + " have no // Output: comment, so go test only compiles them. " + add
+}
@@ -0,0 +1,468 @@
+package aiterate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prathyushnallamothu/aiterate/internal/executor"
+	"github.com/prathyushnallamothu/aiterate/internal/generator"
+)
+
+// Supported languages
+var supportedLanguages = map[string]bool{
+	"go":     true,
+	"python": true,
+	"php":    true,
+	"csharp": true,
+	"kotlin": true,
+	"swift":  true,
+	"bash":   true,
+	"elixir": true,
+	"dart":   true,
+}
+
+// IsSupported reports whether language can be generated.
+func IsSupported(language string) bool {
+	return supportedLanguages[language]
+}
+
+// SupportedLanguageNames returns the supported languages as a sorted, comma-separated list.
+func SupportedLanguageNames() string {
+	names := make([]string, 0, len(supportedLanguages))
+	for name := range supportedLanguages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// FileExtension returns the source file extension for a language.
+func FileExtension(language string) string {
+	switch language {
+	case "python":
+		return "py"
+	case "go":
+		return "go"
+	case "php":
+		return "php"
+	case "csharp":
+		return "cs"
+	case "kotlin":
+		return "kt"
+	case "swift":
+		return "swift"
+	case "bash":
+		return "sh"
+	case "elixir":
+		return "ex"
+	case "dart":
+		return "dart"
+	default:
+		return ""
+	}
+}
+
+// DefaultIterations returns the default iteration limit for a language.
+// Statically typed languages get fewer, as compiler errors point the fixes
+// at the problem; dynamically typed ones only fail at run time and get more.
+func DefaultIterations(language string) int {
+	switch language {
+	case "go", "csharp", "kotlin", "swift", "dart":
+		return 4
+	case "python", "php", "bash", "elixir":
+		return 6
+	default:
+		return DefaultMaxIterations
+	}
+}
+
+// FileNames returns the test and implementation file names for a language.
+func FileNames(language string) (testFile, implFile string) {
+	ext := FileExtension(language)
+	switch {
+	case ext == "":
+		return "", ""
+	case language == "php":
+		// PHPUnit expects the test class name to match its file name
+		return "MainTest.php", "Main.php"
+	case language == "csharp":
+		return "MainTests.cs", "Main.cs"
+	case language == "kotlin":
+		// Gradle's standard source set layout
+		return "src/test/kotlin/MainTest.kt", "src/main/kotlin/Main.kt"
+	case language == "bash":
+		return "main_test.bats", "main.sh"
+	case language == "swift":
+		// SwiftPM's layout, one directory per target
+		return "Tests/SolutionTests/SolutionTests.swift", "Sources/Solution/Solution.swift"
+	case language == "elixir":
+		// Mix's layout; test scripts are .exs so they aren't compiled into the app
+		return "test/main_test.exs", "lib/main.ex"
+	case language == "dart":
+		// The pub package layout; tests import the code as package:main/main.dart
+		return "test/main_test.dart", "lib/main.dart"
+	default:
+		return fmt.Sprintf("main_test.%s", ext), fmt.Sprintf("main.%s", ext)
+	}
+}
+
+// projectFiles returns scaffolding files, besides the test and
+// implementation, needed to build the output on its own.
+func projectFiles(language string) []string {
+	switch language {
+	case "csharp":
+		return []string{"Main.csproj"}
+	case "kotlin":
+		return []string{"build.gradle.kts", "settings.gradle.kts"}
+	case "swift":
+		return []string{"Package.swift"}
+	case "elixir":
+		return []string{"mix.exs", "test/test_helper.exs"}
+	case "dart":
+		return []string{"pubspec.yaml"}
+	default:
+		return nil
+	}
+}
+
+// filesLocation describes where the final files of a run are: the output
+// directory, or the session with Options.NoWrite.
+func (p *pipeline) filesLocation(ws *workspace) string {
+	if p.opts.NoWrite {
+		return fmt.Sprintf("session %s (%s)", ws.session.ID, p.store.SessionDir(ws.session.ID))
+	}
+	return ws.outputDir
+}
+
+// stripMarkers cleans formatting artifacts out of the content of a file
+// for Options.StripMarkers, reporting when anything was removed.
+func (p *pipeline) stripMarkers(content, name, language string) string {
+	stripped := generator.StripMarkers(content, language)
+	if strings.TrimSpace(stripped) != strings.TrimSpace(content) {
+		p.warn("Removed stray markers or trailing prose from %s", name)
+	}
+	return stripped
+}
+
+// cleanCode returns the implementation as the AI wrote it with
+// Options.StripMarkers applied, for every later step to use.
+func (p *pipeline) cleanCode(code string) string {
+	if !p.opts.StripMarkers {
+		return code
+	}
+	_, implName := FileNames(p.opts.Language)
+	return p.stripMarkers(code, implName, p.opts.Language)
+}
+
+// cleanTests returns the tests as the AI wrote them with
+// Options.StripMarkers applied, for every later step to use. Tests split
+// into files are cleaned file by file; when they can't be split, they are
+// left for writeFiles to report.
+func (p *pipeline) cleanTests(testCode string) string {
+	if !p.opts.StripMarkers {
+		return testCode
+	}
+	files, err := p.testFiles(testCode)
+	if err != nil {
+		return testCode
+	}
+	for i := range files {
+		files[i].Content = p.stripMarkers(files[i].Content, files[i].Name, p.opts.Language)
+	}
+	if p.opts.MultiFileTests {
+		return generator.FormatFiles(files)
+	}
+	return files[0].Content
+}
+
+func (p *pipeline) writeFiles(dir, testCode, code, language string) error {
+	p.info("Writing files to temporary directory: %s", dir)
+
+	testName, implName := FileNames(language)
+	if testName == "" {
+		return fmt.Errorf("unsupported language: %s", language)
+	}
+
+	// Write test files
+	files, err := p.testFiles(testCode)
+	if err != nil {
+		return err
+	}
+	if p.opts.MultiFileTests {
+		if err := removeStaleTestFiles(dir, language, files); err != nil {
+			return err
+		}
+	}
+	for _, file := range files {
+		testFile := filepath.Join(dir, file.Name)
+		p.info("Writing test file: %s", testFile)
+		if err := os.WriteFile(testFile, []byte(file.Content), 0644); err != nil {
+			return fmt.Errorf("failed to write test file: %w", err)
+		}
+	}
+
+	// Write implementation file
+	implFile := filepath.Join(dir, implName)
+	p.info("Writing implementation file: %s", implFile)
+	if err := os.WriteFile(implFile, []byte(code), 0644); err != nil {
+		return fmt.Errorf("failed to write implementation file: %w", err)
+	}
+
+	// Update dependencies if it's a Go project
+	if language == "go" {
+		runner := executor.NewTestRunner(dir, p.runnerOpts)
+		if err := p.approveNewModules(runner.ExternalModules(code, testCode)); err != nil {
+			return err
+		}
+		if err := runner.UpdateDependencies(code, testCode); err != nil {
+			return fmt.Errorf("failed to update dependencies: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// testFiles returns the files of the test code: the files it is split into
+// with Options.MultiFileTests, or the language's single test file.
+func (p *pipeline) testFiles(testCode string) ([]generator.GeneratedFile, error) {
+	if p.opts.MultiFileTests {
+		return generator.SplitTestFiles(testCode, p.opts.Language, p.opts.MaxFiles)
+	}
+	testName, _ := FileNames(p.opts.Language)
+	return []generator.GeneratedFile{{Name: testName, Content: testCode}}, nil
+}
+
+// removeStaleTestFiles deletes test files in dir left from earlier test
+// code that the current files no longer include, so they don't keep running.
+func removeStaleTestFiles(dir, language string, files []generator.GeneratedFile) error {
+	current := make(map[string]bool)
+	for _, file := range files {
+		current[file.Name] = true
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read workspace: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Type().IsRegular() && generator.IsTestFileName(name, language) && !current[name] {
+			if err := os.Remove(filepath.Join(dir, name)); err != nil {
+				return fmt.Errorf("failed to remove stale test file: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// approveNewModules enforces Options.MaxNewDeps and asks
+// Options.ConfirmDeps about modules not yet approved in this run.
+func (p *pipeline) approveNewModules(modules []string) error {
+	var added []string
+	for _, module := range modules {
+		if !p.approvedModules[module] {
+			added = append(added, module)
+		}
+	}
+	if len(added) == 0 {
+		return nil
+	}
+
+	if max := p.opts.MaxNewDeps; max > 0 && len(p.approvedModules)+len(added) > max {
+		return fmt.Errorf("%w: the code would add %d new modules, over the limit of %d: %s",
+			ErrDependenciesRejected, len(p.approvedModules)+len(added), max, strings.Join(append(p.sortedApprovedModules(), added...), ", "))
+	}
+	if p.opts.ConfirmDeps != nil {
+		ok, err := p.opts.ConfirmDeps(added)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrDependenciesRejected, strings.Join(added, ", "))
+		}
+	}
+
+	if p.approvedModules == nil {
+		p.approvedModules = make(map[string]bool)
+	}
+	for _, module := range added {
+		p.approvedModules[module] = true
+	}
+	return nil
+}
+
+func (p *pipeline) sortedApprovedModules() []string {
+	modules := make([]string, 0, len(p.approvedModules))
+	for module := range p.approvedModules {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+	return modules
+}
+
+// fileCopy maps a workspace file to its name in the output directory.
+type fileCopy struct {
+	src, dst string
+}
+
+// withTestFiles replaces the single test file in files with the ones the
+// test code is split into.
+func withTestFiles(files []fileCopy, language string, tests []generator.GeneratedFile) []fileCopy {
+	testName, _ := FileNames(language)
+	var result []fileCopy
+	for _, file := range files {
+		if file.src != testName {
+			result = append(result, file)
+		}
+	}
+	for _, test := range tests {
+		result = append(result, fileCopy{src: test.Name, dst: test.Name})
+	}
+	return result
+}
+
+// outputFiles lists the workspace files kept in the output directory.
+func outputFiles(language string) []fileCopy {
+	testName, implName := FileNames(language)
+	if testName == "" {
+		return nil
+	}
+	var files []fileCopy
+	for _, file := range append([]string{testName, implName}, projectFiles(language)...) {
+		files = append(files, fileCopy{src: file, dst: file})
+	}
+	return files
+}
+
+func (p *pipeline) copyFinalFiles(srcDir, dstDir string, files []fileCopy) error {
+	p.info("Copying files from %s to %s", srcDir, dstDir)
+
+	for _, file := range files {
+		src := filepath.Join(srcDir, file.src)
+		dst := filepath.Join(dstDir, file.dst)
+
+		p.info("Reading from: %s", src)
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file.src, err)
+		}
+
+		p.info("Writing to: %s", dst)
+		if err := p.makeOutputDir(filepath.Dir(dst)); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", file.dst, err)
+		}
+		if err := p.writeOutputFile(dst, data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.dst, err)
+		}
+		p.success("Successfully copied %s", file.dst)
+	}
+
+	return nil
+}
+
+// DefaultFileMode is the permission of output files when Options.FileMode is zero
+const DefaultFileMode os.FileMode = 0644
+
+// dirMode derives a directory permission from a file permission, adding
+// search permission wherever read permission is set (0644 gives 0755).
+func dirMode(fileMode os.FileMode) os.FileMode {
+	return fileMode | (fileMode&0444)>>2
+}
+
+// writeOutputFile writes a file to the output directory. An explicit
+// Options.FileMode is applied regardless of the umask or an existing file.
+func (p *pipeline) writeOutputFile(path string, data []byte) error {
+	mode := p.opts.FileMode
+	if mode == 0 {
+		return os.WriteFile(path, data, DefaultFileMode)
+	}
+	if err := os.WriteFile(path, data, mode); err != nil {
+		return err
+	}
+	return os.Chmod(path, mode)
+}
+
+// fallbackDirName names the output directory when the AI can't. The name
+// is timestamped, with a counter when needed, so runs falling back don't
+// overwrite each other's files; suffix is the Options.OutputDirSuffix.
+func fallbackDirName(suffix string) string {
+	base := "generated-function-" + time.Now().Format("20060102-150405")
+	for n := 1; ; n++ {
+		name := base
+		if n > 1 {
+			name = fmt.Sprintf("%s-%d", base, n)
+		}
+		if !fileExists(name + suffix) {
+			return name
+		}
+	}
+}
+
+// makeOutputDir creates a directory in the output, with permissions
+// derived from an explicit Options.FileMode. Existing directories are left
+// as they are. Failures are reported as an *OutputDirError.
+func (p *pipeline) makeOutputDir(dir string) error {
+	mode := p.opts.FileMode
+	if mode == 0 {
+		if err := os.MkdirAll(dir, dirMode(DefaultFileMode)); err != nil {
+			return outputDirError(dir, err)
+		}
+		return nil
+	}
+	if fileExists(dir) {
+		return nil
+	}
+	if err := os.MkdirAll(dir, dirMode(mode)); err != nil {
+		return outputDirError(dir, err)
+	}
+	if err := os.Chmod(dir, dirMode(mode)); err != nil {
+		return outputDirError(dir, err)
+	}
+	return nil
+}
+
+// gitignore returns .gitignore entries for a language's build artifacts
+// and coverage files.
+func gitignore(language string) string {
+	var entries []string
+	switch language {
+	case "go":
+		entries = []string{"*.test", "*.out", "*.prof", "coverage.*"}
+	case "python":
+		entries = []string{"__pycache__/", "*.py[cod]", ".pytest_cache/", ".coverage", "htmlcov/", ".venv/"}
+	case "php":
+		entries = []string{"vendor/", ".phpunit.result.cache", ".phpunit.cache/", "coverage/"}
+	case "csharp":
+		entries = []string{"bin/", "obj/", "TestResults/", "coverage*.xml"}
+	case "kotlin":
+		entries = []string{"build/", ".gradle/", ".kotlin/", "*.class"}
+	case "swift":
+		entries = []string{".build/", ".swiftpm/", "*.xcodeproj/", "default.profraw"}
+	case "bash":
+		entries = []string{"coverage/", "*.log"}
+	case "elixir":
+		entries = []string{"_build/", "deps/", "cover/", "*.ez", "erl_crash.dump"}
+	case "dart":
+		entries = []string{".dart_tool/", "build/", "coverage/", "pubspec.lock"}
+	}
+	entries = append(entries, ".DS_Store")
+	return strings.Join(entries, "\n") + "\n"
+}
+
+// writeGitignore writes a .gitignore to the output directory unless one
+// already exists.
+func (p *pipeline) writeGitignore(dir, language string) error {
+	path := filepath.Join(dir, ".gitignore")
+	if fileExists(path) {
+		p.info("Keeping existing %s", path)
+		return nil
+	}
+	if err := p.writeOutputFile(path, []byte(gitignore(language))); err != nil {
+		return fmt.Errorf("failed to write .gitignore: %w", err)
+	}
+	p.success("Successfully wrote .gitignore")
+	return nil
+}
@@ -0,0 +1,97 @@
+package aiterate
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"unicode/utf8"
+
+	"github.com/prathyushnallamothu/aiterate/internal/generator"
+)
+
+// fixturesDir is the directory, in the workspace and the output directory,
+// holding the files from Options.Fixtures
+const fixturesDir = "testdata"
+
+// maxFixturePreview caps, in bytes, how much of a fixture is shown to the AI
+const maxFixturePreview = 2048
+
+// fixturePath returns where the fixture at src goes, relative to the
+// workspace. The path uses slashes, as tests refer to it.
+func fixturePath(src string) string {
+	return path.Join(fixturesDir, filepath.Base(src))
+}
+
+// validateFixtures checks that the fixtures are regular files whose names
+// don't clash once they are copied into fixturesDir.
+func validateFixtures(fixtures []string) error {
+	seen := make(map[string]string)
+	for _, src := range fixtures {
+		info, err := os.Stat(src)
+		if err != nil {
+			return fmt.Errorf("fixture %s not found", src)
+		}
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("fixture %s is not a regular file", src)
+		}
+		name := filepath.Base(src)
+		if other, ok := seen[name]; ok {
+			return fmt.Errorf("fixtures %s and %s have the same name", other, src)
+		}
+		seen[name] = src
+	}
+	return nil
+}
+
+// fixturePrompts returns the fixtures as described to the AI: their paths
+// and the start of their contents.
+func fixturePrompts(fixtures []string) ([]generator.Fixture, error) {
+	var prompts []generator.Fixture
+	for _, src := range fixtures {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read fixture: %w", err)
+		}
+		prompts = append(prompts, generator.Fixture{Path: fixturePath(src), Preview: fixturePreview(data)})
+	}
+	return prompts, nil
+}
+
+// fixturePreview returns the start of a fixture's contents, cut at a line
+// when it is too long, or a note about its size for binary data.
+func fixturePreview(data []byte) string {
+	if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+		return fmt.Sprintf("(binary data, %d bytes)", len(data))
+	}
+	if len(data) <= maxFixturePreview {
+		return string(data)
+	}
+	preview := data[:maxFixturePreview]
+	if end := bytes.LastIndexByte(preview, '\n'); end > 0 {
+		preview = preview[:end+1]
+	}
+	for !utf8.Valid(preview) {
+		preview = preview[:len(preview)-1]
+	}
+	return fmt.Sprintf("%s... (%d more bytes)", preview, len(data)-len(preview))
+}
+
+// copyFixtures copies the fixtures into fixturesDir under dir.
+func copyFixtures(dir string, fixtures []string) error {
+	for _, src := range fixtures {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return fmt.Errorf("failed to read fixture: %w", err)
+		}
+		dst := filepath.Join(dir, filepath.FromSlash(fixturePath(src)))
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", fixturesDir, err)
+		}
+		if err := os.WriteFile(dst, data, 0644); err != nil {
+			return fmt.Errorf("failed to write fixture %s: %w", filepath.Base(src), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package aiterate
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"strings"
+)
+
+// interfaceFile is the workspace file holding the interface from Options.Implements
+const interfaceFile = "interface.go"
+
+// goInterface is a Go interface definition the generated code must implement.
+type goInterface struct {
+	// source is the interface file, declared in the generated code's package
+	source string
+	// names are the interfaces declared in source
+	names []string
+}
+
+// loadGoInterface reads the interface definitions in a Go file and moves
+// them to package pkg, so they compile next to the generated code.
+func loadGoInterface(path, pkg string) (*goInterface, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read interface file: %w", err)
+	}
+	source := string(data)
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, source, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse interface file: %w", err)
+	}
+
+	var names []string
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			if _, ok := typeSpec.Type.(*ast.InterfaceType); ok {
+				names = append(names, typeSpec.Name.Name)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no interface declared in %s", path)
+	}
+
+	start := fset.Position(file.Name.Pos()).Offset
+	end := fset.Position(file.Name.End()).Offset
+	source = source[:start] + pkg + source[end:]
+	return &goInterface{source: source, names: names}, nil
+}
+
+// missingAssertions returns the interfaces code has no compile-time
+// assertion for, like var _ Interface = (*Impl)(nil).
+func (iface *goInterface) missingAssertions(code string) []string {
+	asserted := make(map[string]bool)
+	file, err := parser.ParseFile(token.NewFileSet(), "main.go", code, 0)
+	if err == nil {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				value := spec.(*ast.ValueSpec)
+				if ident, ok := value.Type.(*ast.Ident); ok && len(value.Names) == 1 && value.Names[0].Name == "_" {
+					asserted[ident.Name] = true
+				}
+			}
+		}
+	}
+
+	var missing []string
+	for _, name := range iface.names {
+		if !asserted[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// assertionFailure describes missing interface assertions for the fix prompt.
+func assertionFailure(missing []string) string {
+	examples := make([]string, len(missing))
+	for i, name := range missing {
+		examples[i] = fmt.Sprintf("var _ %s = (*YourType)(nil)", name)
+	}
+	return "The tests pass, but the implementation has no compile-time check that it implements the required interface. " +
+		"Add an assertion for each interface, replacing YourType with the implementing type:\n" + strings.Join(examples, "\n")
+}
@@ -0,0 +1,65 @@
+package aiterate
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DetectGoPackage returns the package declared by the Go files in dir,
+// ignoring external test packages.
+func DetectGoPackage(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read package directory: %w", err)
+	}
+
+	fset := token.NewFileSet()
+	var name string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filepath.Join(dir, entry.Name()), nil, parser.PackageClauseOnly)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse %s: %w", entry.Name(), err)
+		}
+		pkg := file.Name.Name
+		if strings.HasSuffix(pkg, "_test") {
+			continue
+		}
+		if name != "" && pkg != name {
+			return "", fmt.Errorf("%s contains multiple packages: %s and %s", dir, name, pkg)
+		}
+		name = pkg
+	}
+
+	if name == "" {
+		return "", fmt.Errorf("no Go package found in %s", dir)
+	}
+	return name, nil
+}
+
+// uniqueGoFileNames returns implementation and test file names derived
+// from base that don't clash with files already in dir.
+func uniqueGoFileNames(dir, base string) (testFile, implFile string) {
+	base = strings.ReplaceAll(base, "-", "_")
+	for n := 1; ; n++ {
+		name := base
+		if n > 1 {
+			name = fmt.Sprintf("%s_%d", base, n)
+		}
+		implFile, testFile = name+".go", name+"_test.go"
+		if !fileExists(filepath.Join(dir, implFile)) && !fileExists(filepath.Join(dir, testFile)) {
+			return testFile, implFile
+		}
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,65 @@
+package aiterate
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// StdImports in Options.AllowedImports allows the whole standard library
+const StdImports = "std"
+
+// disallowedImports returns the packages imported by Go code that aren't in
+// allowed, sorted. An allowed path also allows the packages below it.
+func disallowedImports(code string, allowed []string) ([]string, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), "", code, parser.ImportsOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse imports: %w", err)
+	}
+
+	var disallowed []string
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		if !importAllowed(path, allowed) {
+			disallowed = append(disallowed, path)
+		}
+	}
+	sort.Strings(disallowed)
+	return disallowed, nil
+}
+
+// importAllowed reports whether path is in allowed. Standard library paths
+// are told apart by the missing dot in their first element, as go does.
+func importAllowed(path string, allowed []string) bool {
+	for _, entry := range allowed {
+		switch {
+		case entry == StdImports:
+			if !strings.Contains(strings.Split(path, "/")[0], ".") {
+				return true
+			}
+		case path == entry || strings.HasPrefix(path, entry+"/"):
+			return true
+		}
+	}
+	return false
+}
+
+// importsFailure describes disallowed imports as a test failure for the fix prompt.
+func importsFailure(disallowed, allowed []string) string {
+	var names []string
+	for _, entry := range allowed {
+		if entry == StdImports {
+			entry = "the standard library"
+		}
+		names = append(names, entry)
+	}
+	return fmt.Sprintf("The tests pass, but the implementation imports packages that aren't allowed: %s.\n"+
+		"Rewrite it using only these packages (and packages below them): %s. The tests may keep their imports.",
+		strings.Join(disallowed, ", "), strings.Join(names, ", "))
+}
@@ -0,0 +1,58 @@
+package aiterate
+
+import (
+	"fmt"
+
+	"github.com/prathyushnallamothu/aiterate/internal/ai"
+	"github.com/prathyushnallamothu/aiterate/internal/generator"
+)
+
+// TestsPlaceholder stands in for the generated tests in Prompts.Implementation
+const TestsPlaceholder = "<the generated tests>"
+
+// Prompts are the prompts a run sends first, as built by BuildPrompts.
+type Prompts struct {
+	// Tests is the test generation prompt
+	Tests string
+	// Implementation is the initial implementation prompt, with
+	// TestsPlaceholder in place of the tests; empty with
+	// Options.RegenerateTests, which keeps the implementation
+	Implementation string
+}
+
+// BuildPrompts returns the test generation and implementation prompts a
+// Generate run with opts would send, including Options.PromptPrefix and
+// Options.PromptSuffix, without calling the AI.
+func BuildPrompts(opts Options) (*Prompts, error) {
+	if _, err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if opts.Tests != "" {
+		return nil, fmt.Errorf("there are no generation prompts when the tests are provided")
+	}
+	genOpts, _, err := generatorOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// The prompts are built without a client, so nothing can be sent
+	description := opts.Description
+	if opts.RegenerateTests {
+		description = existingImplementation(description, opts.Implementation)
+	}
+	tests, err := generator.NewTestGenerator(nil, genOpts).TestsPrompt(description, opts.Language)
+	if err != nil {
+		return nil, err
+	}
+	prompts := &Prompts{Tests: ai.WrapPrompt(opts.PromptPrefix, tests, opts.PromptSuffix)}
+	if opts.RegenerateTests {
+		return prompts, nil
+	}
+
+	implementation, err := generator.NewCodeGenerator(nil, genOpts).ImplementationPrompt(TestsPlaceholder, opts.Language)
+	if err != nil {
+		return nil, err
+	}
+	prompts.Implementation = ai.WrapPrompt(opts.PromptPrefix, implementation, opts.PromptSuffix)
+	return prompts, nil
+}
@@ -0,0 +1,213 @@
+package aiterate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prathyushnallamothu/aiterate/internal/generator"
+	"github.com/prathyushnallamothu/aiterate/internal/telemetry"
+)
+
+// existingImplementation extends the description so that generated tests
+// target the API of an implementation that already exists.
+func existingImplementation(description, code string) string {
+	return description + "\n\nThe implementation already exists; test it through its public API exactly as written:\n" + code
+}
+
+// regenerateTests generates new tests for Options.Implementation without
+// changing it. Failing tests are diagnosed: test bugs are fixed, and an
+// implementation bug ends the run with ErrImplementationBug.
+func (p *pipeline) regenerateTests(ctx context.Context) (*Result, error) {
+	started := time.Now()
+	description, language, code := p.opts.Description, p.opts.Language, p.opts.Implementation
+
+	ws, err := p.prepare(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer p.release(ws)
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	p.info("Generating tests for the existing implementation...")
+	p.observer.OnGenerate(StepTests)
+	_, ph := p.startPhase(ctx, SpanGenerateTests)
+	testCode, err := p.testGen.GenerateTests(ctx, existingImplementation(description, code), language)
+	ph.end(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate tests: %w", err)
+	}
+	testCode = p.cleanTests(testCode)
+
+	if p.opts.MaxTestLines > 0 {
+		testCode, err = p.consolidateTests(ctx, description, testCode, language)
+		if err != nil {
+			return nil, fmt.Errorf("failed to consolidate tests: %w", err)
+		}
+	}
+
+	if p.opts.StrictTests {
+		testCode, err = p.strengthenTests(ctx, description, testCode, language)
+		if err != nil {
+			return nil, fmt.Errorf("failed to strengthen tests: %w", err)
+		}
+	}
+
+	if p.opts.TestsFirst {
+		testCode, err = p.checkTestsFail(ctx, ws, description, testCode, language)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.opts.ReviewTests != nil {
+		testCode, err = p.opts.ReviewTests(testCode, language)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.writeFiles(ws.dir, testCode, code, language); err != nil {
+		return nil, fmt.Errorf("failed to write files: %w", err)
+	}
+
+	var success bool
+	var lastTestOutput string
+	var iterations int
+	var mutantsChecked bool
+	var diagnosis *generator.Diagnosis
+	iterationLimit := p.opts.MaxIterations
+	for i := 0; i < iterationLimit; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		iterations = i + 1
+		p.observer.OnIterationStart(i+1, iterationLimit)
+
+		result, err := p.runTests(ctx, ws, i+1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to run tests: %w", err)
+		}
+		p.observer.OnTestResult(i+1, result)
+
+		lastTestOutput = result.Output
+		if err := p.store.AddIteration(ws.session.ID, p.iteration(testCode, code, result)); err != nil {
+			return nil, fmt.Errorf("failed to store iteration: %w", err)
+		}
+
+		if result.Success && p.opts.MutationTest && !mutantsChecked {
+			mutantsChecked = true
+			survivors, err := p.findSurvivingMutants(ctx, ws.runner, code, language)
+			if err != nil {
+				return nil, fmt.Errorf("failed to run mutation tests: %w", err)
+			}
+			if len(survivors) > 0 {
+				p.warn("Asking the AI to add test cases that catch the surviving mutants...")
+				p.observer.OnGenerate(StepStrengthen)
+				testCode, err = p.testGen.StrengthenTests(ctx, description, testCode, language, mutantWeakness(survivors))
+				if err != nil {
+					return nil, fmt.Errorf("failed to strengthen tests: %w", err)
+				}
+				testCode = p.cleanTests(testCode)
+				if err := p.writeFiles(ws.dir, testCode, code, language); err != nil {
+					return nil, fmt.Errorf("failed to write files: %w", err)
+				}
+				// The strengthened tests always get at least one run
+				if i == iterationLimit-1 {
+					iterationLimit++
+				}
+				continue
+			}
+		}
+
+		if result.Success {
+			success = true
+			p.success("All tests passed!")
+			break
+		}
+
+		p.info("Diagnosing the test failures...")
+		p.observer.OnGenerate(StepDiagnosis)
+		_, ph := p.startPhase(ctx, SpanDiagnose, telemetry.Attr("aiterate.iteration", i+1))
+		diagnosis, err = p.testGen.DiagnoseFailure(ctx, description, code, testCode, result.Output, language)
+		if err != nil {
+			ph.end(err)
+			return nil, fmt.Errorf("failed to diagnose test failures: %w", err)
+		}
+		ph.end(nil, telemetry.Attr("aiterate.diagnosis.cause", diagnosis.Cause))
+		if diagnosis.Cause == generator.CauseImplementation {
+			break
+		}
+
+		p.warn("The tests are at fault: %s", diagnosis.Explanation)
+		p.warn("Attempting to fix the tests...")
+		p.observer.OnGenerate(StepFixTests)
+		_, ph = p.startPhase(ctx, SpanFix,
+			telemetry.Attr("aiterate.iteration", i+1),
+			telemetry.Attr("aiterate.fix.reason", "tests only"),
+		)
+		testCode, err = p.testGen.FixTests(ctx, description, code, testCode, result.Output, language)
+		ph.end(err)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fix tests: %w", err)
+		}
+		testCode = p.cleanTests(testCode)
+		diagnosis = nil
+		if err := p.writeFiles(ws.dir, testCode, code, language); err != nil {
+			return nil, fmt.Errorf("failed to write files: %w", err)
+		}
+	}
+
+	// Always copy files, even if tests didn't pass
+	if !p.opts.NoWrite {
+		if err := p.copyFinalFiles(ws.dir, ws.outputDir, ws.finalFiles); err != nil {
+			return nil, fmt.Errorf("failed to copy final files: %w", err)
+		}
+		if p.opts.Gitignore {
+			if err := p.writeGitignore(ws.outputDir, language); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	summary := &Result{
+		SessionID:  ws.session.ID,
+		Success:    success,
+		Iterations: iterations,
+		OutputDir:  ws.outputDir,
+		Duration:   time.Since(started),
+		TestCode:   testCode,
+		Code:       code,
+	}
+
+	if diagnosis != nil {
+		summary.Diagnosis = diagnosis.Explanation
+		p.failure("The tests found a bug in the implementation: %s", diagnosis.Explanation)
+		p.warn("Failing test output:")
+		p.observer.OnMessage(EventOutput, lastTestOutput)
+		p.warn("The implementation was left unchanged. Files have been saved to: %s", p.filesLocation(ws))
+		return summary, fmt.Errorf("%w: %s", ErrImplementationBug, diagnosis.Explanation)
+	}
+	if !success {
+		p.failure("Failed to generate passing tests after %d iterations", iterations)
+		p.warn("Last test output:")
+		p.observer.OnMessage(EventOutput, lastTestOutput)
+		p.warn("Files have been saved to: %s", p.filesLocation(ws))
+		return summary, fmt.Errorf("%w after %d iterations", ErrNotConverged, iterations)
+	}
+
+	summary.API = p.recordAPI(ws.session.ID, code)
+	if p.opts.Explain {
+		summary.Explanation = p.explain(ctx, code, testCode, language, ws.outputDir)
+	}
+	if p.opts.CommitMessage {
+		summary.CommitMessage = p.commitMessage(ctx, description, code, testCode, language, ws.outputDir)
+	}
+
+	p.success("Successfully regenerated tests! Check %s for the files.", p.filesLocation(ws))
+	return summary, nil
+}
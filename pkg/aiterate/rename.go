@@ -0,0 +1,142 @@
+package aiterate
+
+import (
+	"context"
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"regexp"
+	"strings"
+
+	"github.com/prathyushnallamothu/aiterate/internal/generator"
+)
+
+// Rename is a new name for an identifier in the final code, proposed with
+// Options.DescribeOutput.
+type Rename = generator.Rename
+
+// renameIdentifiers applies renames to source. Go source is tokenized so
+// only identifiers and words in comments change, not strings; other
+// languages get a whole-word replacement.
+func renameIdentifiers(source, language string, renames []Rename) string {
+	if language != "go" {
+		return renameWords(source, renames)
+	}
+
+	to := make(map[string]string, len(renames))
+	for _, rename := range renames {
+		to[rename.From] = rename.To
+	}
+
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(source))
+	var s scanner.Scanner
+	// Errors are ignored: the tokens around them are still renamed
+	s.Init(file, []byte(source), nil, scanner.ScanComments)
+	var b strings.Builder
+	last := 0
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		replacement := lit
+		switch tok {
+		case token.IDENT:
+			if name, ok := to[lit]; ok {
+				replacement = name
+			}
+		case token.COMMENT:
+			// Keep doc comments, which start with the name, accurate
+			replacement = renameWords(lit, renames)
+		}
+		if replacement != lit {
+			offset := file.Offset(pos)
+			b.WriteString(source[last:offset])
+			b.WriteString(replacement)
+			last = offset + len(lit)
+		}
+	}
+	b.WriteString(source[last:])
+	return b.String()
+}
+
+// renameWords replaces whole-word occurrences of the renamed names in text.
+func renameWords(text string, renames []Rename) string {
+	for _, rename := range renames {
+		pattern := regexp.MustCompile(`\b` + regexp.QuoteMeta(rename.From) + `\b`)
+		text = pattern.ReplaceAllLiteralString(text, rename.To)
+	}
+	return text
+}
+
+// usableRenames drops renames to names already used in the code or tests,
+// which would make two identifiers collide.
+func (p *pipeline) usableRenames(renames []Rename, code, testCode string) []Rename {
+	var usable []Rename
+	for _, rename := range renames {
+		pattern := regexp.MustCompile(`\b` + regexp.QuoteMeta(rename.To) + `\b`)
+		if pattern.MatchString(code) || pattern.MatchString(testCode) {
+			p.warn("Skipping the rename %s: %s is already used", rename, rename.To)
+			continue
+		}
+		usable = append(usable, rename)
+	}
+	return usable
+}
+
+// describeOutput asks the AI for clearer names once the tests pass and,
+// when Options.ConfirmRenames accepts them, applies them to the code and
+// tests. The renamed code must still pass the tests, or the original names
+// are kept. Failures are reported but don't fail the run.
+func (p *pipeline) describeOutput(ctx context.Context, ws *workspace, code, testCode string) (string, string, []Rename, error) {
+	language := p.opts.Language
+	p.info("Asking the AI for clearer names...")
+	p.observer.OnGenerate(StepNames)
+	renames, err := p.codeGen.ProposeNames(ctx, p.opts.Description, code, language)
+	if err != nil {
+		p.warn("Failed to get name suggestions: %v", err)
+		return code, testCode, nil, nil
+	}
+	renames = p.usableRenames(renames, code, testCode)
+	if len(renames) == 0 {
+		p.info("The AI suggested no renames")
+		return code, testCode, nil, nil
+	}
+
+	if p.opts.ConfirmRenames != nil {
+		ok, err := p.opts.ConfirmRenames(renames)
+		if err != nil {
+			return "", "", nil, err
+		}
+		if !ok {
+			p.info("Keeping the original names")
+			return code, testCode, nil, nil
+		}
+	}
+
+	renamedCode := renameIdentifiers(code, language, renames)
+	renamedTests := renameIdentifiers(testCode, language, renames)
+	if err := p.writeFiles(ws.dir, renamedTests, renamedCode, language); err != nil {
+		return "", "", nil, fmt.Errorf("failed to write files: %w", err)
+	}
+	result, err := ws.runner.RunTests(ctx, language)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("failed to run tests: %w", err)
+	}
+	if !result.Success {
+		p.warn("The tests fail after renaming; keeping the original names")
+		if err := p.writeFiles(ws.dir, testCode, code, language); err != nil {
+			return "", "", nil, fmt.Errorf("failed to write files: %w", err)
+		}
+		return code, testCode, nil, nil
+	}
+
+	if err := p.store.AddIteration(ws.session.ID, p.iteration(renamedTests, renamedCode, result)); err != nil {
+		return "", "", nil, fmt.Errorf("failed to store iteration: %w", err)
+	}
+	for _, rename := range renames {
+		p.success("Renamed %s", rename)
+	}
+	return renamedCode, renamedTests, renames, nil
+}
@@ -0,0 +1,163 @@
+package aiterate
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/prathyushnallamothu/aiterate/internal/generator"
+	"github.com/prathyushnallamothu/aiterate/internal/storage"
+)
+
+// RunOptions are the options recorded with a session so a resumed run
+// continues the way the session's run started.
+type RunOptions = storage.RunOptions
+
+// runOptions returns the options of o to record with its session.
+func runOptions(o Options) RunOptions {
+	run := RunOptions{
+		TestStyle:       o.TestStyle,
+		TestFramework:   o.TestFramework,
+		MaxFileSize:     o.MaxFileSize,
+		Plateau:         o.Plateau,
+		EnvNames:        envNames(o.Env),
+		Deps:            o.Deps,
+		GoTestJSON:      o.GoTestJSON,
+		TestCommand:     o.TestCommand,
+		MultiFileTests:  o.MultiFileTests,
+		MaxFiles:        o.MaxFiles,
+		StripMarkers:    o.StripMarkers,
+		MutationTest:    o.MutationTest,
+		AllowedImports:  o.AllowedImports,
+		Vet:             o.Vet,
+		Fuzz:            o.Fuzz,
+		FuzzTime:        o.FuzzTime,
+		Examples:        o.Examples,
+		Generics:        o.Generics,
+		GoVersion:       o.GoVersion,
+		Concurrent:      o.Concurrent,
+		HTTP:            o.HTTP,
+		RunMain:         o.RunMain,
+		MainArgs:        o.MainArgs,
+		Review:          o.Review,
+		ReviewModel:     o.ReviewModel,
+		FixStrategy:     o.FixStrategy,
+		GuardTests:      o.GuardTests,
+		Conversational:  o.Conversational,
+		Signature:       o.Signature,
+		Implements:      absPath(o.Implements),
+		PromptPrefix:    o.PromptPrefix,
+		PromptSuffix:    o.PromptSuffix,
+		PackageDir:      absPath(o.PackageDir),
+		RegenerateTests: o.RegenerateTests,
+	}
+	for _, fixture := range o.Fixtures {
+		run.Fixtures = append(run.Fixtures, absPath(fixture))
+	}
+	return run
+}
+
+// envNames returns the names of KEY=VALUE environment variables.
+func envNames(env []string) []string {
+	var names []string
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		names = append(names, name)
+	}
+	return names
+}
+
+// absPath makes path absolute so it still resolves when a run is resumed
+// from another directory; it is left as it is when that fails.
+func absPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// RestoreRunOptions sets the options recorded with a session on opts,
+// whose Tests must already hold the tests resumed from. Prompt text passed
+// for the resumed run is kept. The values of the run's test environment
+// variables aren't recorded, so opts.Env must set each of them again. It
+// fails when the run can't be continued: runs into an existing package or
+// regenerating tests, runs whose interface or fixture files are gone or
+// whose environment variables aren't set, and, since nothing is known
+// about them, sessions without recorded options whose tests are split
+// into files.
+func RestoreRunOptions(opts *Options, run *RunOptions) error {
+	if run == nil {
+		if _, err := generator.ParseFiles(opts.Tests, 0); err == nil {
+			return fmt.Errorf("the session's tests are split into files, but its options weren't recorded")
+		}
+		return nil
+	}
+	if run.PackageDir != "" {
+		return fmt.Errorf("runs adding files to an existing package can't be resumed")
+	}
+	if run.RegenerateTests {
+		return fmt.Errorf("runs regenerating tests can't be resumed")
+	}
+	if run.Implements != "" && !fileExists(run.Implements) {
+		return fmt.Errorf("the session's interface file %s no longer exists", run.Implements)
+	}
+	for _, fixture := range run.Fixtures {
+		if !fileExists(fixture) {
+			return fmt.Errorf("the session's fixture %s no longer exists", fixture)
+		}
+	}
+	set := make(map[string]bool)
+	for _, name := range envNames(opts.Env) {
+		set[name] = true
+	}
+	var missing []string
+	for _, name := range run.EnvNames {
+		if !set[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("the session's tests ran with environment variables whose values aren't stored, which must be set again: %s", strings.Join(missing, ", "))
+	}
+
+	opts.TestStyle = run.TestStyle
+	opts.TestFramework = run.TestFramework
+	opts.MaxFileSize = run.MaxFileSize
+	opts.Plateau = run.Plateau
+	opts.Deps = run.Deps
+	opts.GoTestJSON = run.GoTestJSON
+	opts.TestCommand = run.TestCommand
+	opts.MultiFileTests = run.MultiFileTests
+	opts.MaxFiles = run.MaxFiles
+	opts.StripMarkers = run.StripMarkers
+	opts.MutationTest = run.MutationTest
+	opts.AllowedImports = run.AllowedImports
+	opts.Vet = run.Vet
+	opts.Fuzz = run.Fuzz
+	opts.FuzzTime = run.FuzzTime
+	opts.Examples = run.Examples
+	opts.Generics = run.Generics
+	opts.GoVersion = run.GoVersion
+	opts.Concurrent = run.Concurrent
+	opts.HTTP = run.HTTP
+	opts.RunMain = run.RunMain
+	opts.MainArgs = run.MainArgs
+	opts.Review = run.Review
+	opts.ReviewModel = run.ReviewModel
+	opts.FixStrategy = run.FixStrategy
+	opts.GuardTests = run.GuardTests
+	opts.Conversational = run.Conversational
+	opts.Signature = run.Signature
+	opts.Implements = run.Implements
+	opts.Fixtures = run.Fixtures
+	if opts.PromptPrefix == "" {
+		opts.PromptPrefix = run.PromptPrefix
+	}
+	if opts.PromptSuffix == "" {
+		opts.PromptSuffix = run.PromptSuffix
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package aiterate
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Patterns matching public function declarations per language, for
+// languages without a parser at hand
+var signaturePatterns = map[string]*regexp.Regexp{
+	"python": regexp.MustCompile(`(?m)^def ([a-zA-Z]\w*\(.*\)(\s*->\s*[^:]+)?):`),
+	"php":    regexp.MustCompile(`(?m)^\s*(?:public\s+)?(?:static\s+)?function\s+(\w+\s*\(.*\)(?:\s*:\s*\??[\w\\|]+)?)`),
+	"csharp": regexp.MustCompile(`(?m)^\s*public\s+(?:static\s+)?([\w<>\[\],?\s]+\s+\w+\s*\(.*\))`),
+	"kotlin": regexp.MustCompile(`(?m)^\s*(?:public\s+)?fun\s+((?:<[^>]+>\s*)?[\w.]+\s*\(.*\)(?:\s*:\s*[\w<>?,\s]+)?)`),
+	"swift":  regexp.MustCompile(`(?m)^\s*(?:public\s+)?func\s+(\w+(?:<[^>]+>)?\s*\(.*\)(?:\s*(?:throws|rethrows))?(?:\s*->\s*[^{]+)?)`),
+	"bash":   regexp.MustCompile(`(?m)^(?:function\s+)?([a-zA-Z_][\w-]*)\s*\(\)`),
+	"dart":   regexp.MustCompile(`(?m)^([\w<>?,\[\] ]+?\s+[a-zA-Z]\w*(?:<[^>]+>)?\s*\(.*\))\s*(?:async\s*)?(?:\{|=>)`),
+	"elixir": regexp.MustCompile(`(?m)^\s*def\s+([a-z_]\w*[?!]?(?:\(.*?\))?)(?:\s+when\b.*?)?,?\s+do\b`),
+}
+
+// PublicSignatures returns the signatures of the public functions declared
+// in code, e.g. "func Reverse(s string) string" for Go. It is a best effort
+// for languages other than Go.
+func PublicSignatures(code, language string) []string {
+	if language == "go" {
+		return goSignatures(code)
+	}
+
+	pattern, ok := signaturePatterns[language]
+	if !ok {
+		return nil
+	}
+	var signatures []string
+	for _, match := range pattern.FindAllStringSubmatch(code, -1) {
+		signature := strings.Join(strings.Fields(match[1]), " ")
+		if language == "python" && strings.HasPrefix(signature, "_") {
+			continue
+		}
+		signatures = append(signatures, signature)
+	}
+	return signatures
+}
+
+// pythonClassPattern matches top-level Python class declarations
+var pythonClassPattern = regexp.MustCompile(`(?m)^class ([A-Za-z]\w*(?:\(.*\))?):`)
+
+// PublicAPI returns the public API declared in code: for Go, the exported
+// types and functions, e.g. "type Stack struct" and
+// "func (s *Stack) Push(v int)"; for Python, the top-level classes and
+// functions in source order. Other languages get PublicSignatures.
+func PublicAPI(code, language string) []string {
+	switch language {
+	case "go":
+		return goAPI(code)
+	case "python":
+		type declaration struct {
+			at        int
+			signature string
+		}
+		var declarations []declaration
+		for _, match := range pythonClassPattern.FindAllStringSubmatchIndex(code, -1) {
+			declarations = append(declarations, declaration{match[0], "class " + code[match[2]:match[3]]})
+		}
+		for _, match := range signaturePatterns["python"].FindAllStringSubmatchIndex(code, -1) {
+			signature := strings.Join(strings.Fields(code[match[2]:match[3]]), " ")
+			if !strings.HasPrefix(signature, "_") {
+				declarations = append(declarations, declaration{match[0], "def " + signature})
+			}
+		}
+		sort.Slice(declarations, func(i, j int) bool { return declarations[i].at < declarations[j].at })
+		api := make([]string, len(declarations))
+		for i, d := range declarations {
+			api[i] = d.signature
+		}
+		return api
+	default:
+		return PublicSignatures(code, language)
+	}
+}
+
+// goAPI returns the exported types, followed by the exported functions and
+// methods, in Go source. Struct and interface types are listed without
+// their fields.
+func goAPI(code string) []string {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "main.go", code, 0)
+	if err != nil {
+		return nil
+	}
+
+	var api []string
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			if !typeSpec.Name.IsExported() {
+				continue
+			}
+			typeSpec.Doc, typeSpec.Comment = nil, nil
+			switch typeSpec.Type.(type) {
+			case *ast.StructType:
+				typeSpec.Type = ast.NewIdent("struct")
+			case *ast.InterfaceType:
+				typeSpec.Type = ast.NewIdent("interface")
+			}
+			var b strings.Builder
+			if err := printer.Fprint(&b, fset, typeSpec); err == nil {
+				api = append(api, "type "+b.String())
+			}
+		}
+	}
+	return append(api, goSignatures(code)...)
+}
+
+// goSignatures returns the exported top-level functions and methods in Go source.
+func goSignatures(code string) []string {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "main.go", code, 0)
+	if err != nil {
+		return nil
+	}
+
+	var signatures []string
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || !fn.Name.IsExported() {
+			continue
+		}
+		fn.Body = nil
+		fn.Doc = nil
+		var b strings.Builder
+		if err := printer.Fprint(&b, fset, fn); err == nil {
+			signatures = append(signatures, b.String())
+		}
+	}
+	return signatures
+}
+
+// parseGoSignature parses a Go function or method declaration without a body.
+func parseGoSignature(signature string) (*ast.FuncDecl, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), "signature.go", "package p\n"+signature, 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Go signature %q: %w", signature, err)
+	}
+	if len(file.Decls) != 1 {
+		return nil, fmt.Errorf("invalid Go signature %q: expected a single func declaration", signature)
+	}
+	fn, ok := file.Decls[0].(*ast.FuncDecl)
+	if !ok || fn.Body != nil {
+		return nil, fmt.Errorf("invalid Go signature %q: expected a func declaration without a body", signature)
+	}
+	return fn, nil
+}
+
+// goSignatureKey renders a declaration's receiver type, name and types,
+// leaving out parameter names, which callers don't depend on.
+func goSignatureKey(fn *ast.FuncDecl) string {
+	var b strings.Builder
+	writeTypes := func(fields *ast.FieldList) {
+		if fields == nil {
+			return
+		}
+		var list []string
+		for _, field := range fields.List {
+			for n := max(len(field.Names), 1); n > 0; n-- {
+				list = append(list, types.ExprString(field.Type))
+			}
+		}
+		b.WriteString(strings.Join(list, ", "))
+	}
+	if fn.Recv != nil {
+		b.WriteString("(")
+		writeTypes(fn.Recv)
+		b.WriteString(") ")
+	}
+	b.WriteString(fn.Name.Name)
+	if fn.Type.TypeParams != nil {
+		b.WriteString("[")
+		writeTypes(fn.Type.TypeParams)
+		b.WriteString("]")
+	}
+	b.WriteString("(")
+	writeTypes(fn.Type.Params)
+	b.WriteString(") (")
+	writeTypes(fn.Type.Results)
+	b.WriteString(")")
+	return b.String()
+}
+
+// declaresSignature reports whether code declares signature. Go code is
+// parsed and compared by name and types; for other languages it is a best
+// effort textual match, ignoring whitespace.
+func declaresSignature(code, language, signature string) bool {
+	if language != "go" {
+		return strings.Contains(strings.Join(strings.Fields(code), " "), strings.Join(strings.Fields(signature), " "))
+	}
+
+	want, err := parseGoSignature(signature)
+	if err != nil {
+		return false
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), "main.go", code, 0)
+	if err != nil {
+		return false
+	}
+	key := goSignatureKey(want)
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && goSignatureKey(fn) == key {
+			return true
+		}
+	}
+	return false
+}
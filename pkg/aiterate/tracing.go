@@ -0,0 +1,108 @@
+package aiterate
+
+import (
+	"context"
+
+	"github.com/prathyushnallamothu/aiterate/internal/generator"
+	"github.com/prathyushnallamothu/aiterate/internal/telemetry"
+)
+
+// Tracer starts the spans reported for each phase of a run; an
+// OpenTelemetry tracer can be adapted to it in a few lines.
+type Tracer = telemetry.Tracer
+
+// Span is a phase of a run reported to a Tracer.
+type Span = telemetry.Span
+
+// Attribute is a key/value pair attached to a Span.
+type Attribute = telemetry.Attribute
+
+// Span names reported to Options.Tracer
+const (
+	SpanGenerate               = "aiterate.generate"
+	SpanGenerateTests          = "aiterate.generate_tests"
+	SpanGenerateImplementation = "aiterate.generate_implementation"
+	SpanGenerateCandidates     = "aiterate.generate_candidates"
+	SpanRunTests               = "aiterate.run_tests"
+	SpanFix                    = "aiterate.fix"
+	SpanDiagnose               = "aiterate.diagnose"
+)
+
+// phase is a traced step of a run. Ending it records the tokens the step
+// consumed.
+type phase struct {
+	p     *pipeline
+	span  Span
+	usage Usage
+}
+
+// startPhase starts a span for a step of the run, tagged with the language
+// and model.
+func (p *pipeline) startPhase(ctx context.Context, name string, attrs ...Attribute) (context.Context, *phase) {
+	attrs = append([]Attribute{
+		telemetry.Attr("aiterate.language", p.opts.Language),
+		telemetry.Attr("aiterate.model", p.opts.Model),
+	}, attrs...)
+	ctx, span := p.tracer.Start(ctx, name, attrs...)
+	return ctx, &phase{p: p, span: span, usage: p.aiClient.Usage()}
+}
+
+// end finishes the phase, marking it failed when err is set.
+func (ph *phase) end(err error, attrs ...Attribute) {
+	usage := ph.p.aiClient.Usage()
+	attrs = append(attrs,
+		telemetry.Attr("aiterate.tokens.prompt", usage.PromptTokens-ph.usage.PromptTokens),
+		telemetry.Attr("aiterate.tokens.completion", usage.CompletionTokens-ph.usage.CompletionTokens),
+	)
+	ph.span.SetAttributes(attrs...)
+	ph.span.RecordError(err)
+	ph.span.End()
+}
+
+// runTests runs the tests in a span recording the iteration and outcome.
+func (p *pipeline) runTests(ctx context.Context, ws *workspace, iteration int) (*TestResult, error) {
+	_, ph := p.startPhase(ctx, SpanRunTests, telemetry.Attr("aiterate.iteration", iteration))
+	result, err := ws.runner.RunTests(ctx, p.opts.Language)
+	if err != nil {
+		ph.end(err)
+		return nil, err
+	}
+	ph.end(nil,
+		telemetry.Attr("aiterate.tests.success", result.Success),
+		telemetry.Attr("aiterate.tests.passed", result.Passed),
+		telemetry.Attr("aiterate.tests.failed", result.Failed),
+	)
+	return result, nil
+}
+
+// fix asks the AI to fix the files allowed by Options.FixStrategy in a span
+// recording the iteration and what prompted the fix.
+func (p *pipeline) fix(ctx context.Context, iteration int, reason, code, testCode, failure, hint string) (*generator.FixResult, error) {
+	_, ph := p.startPhase(ctx, SpanFix,
+		telemetry.Attr("aiterate.iteration", iteration),
+		telemetry.Attr("aiterate.fix.reason", reason),
+	)
+	if p.opts.FixStrategy == FixStrategyImplOnly {
+		fixed, err := p.codeGen.FixImplementation(ctx, p.opts.Description, code, testCode, failure, hint, p.opts.Language)
+		ph.end(err)
+		if err != nil {
+			return nil, err
+		}
+		return &generator.FixResult{Code: p.cleanCode(fixed), TestCode: testCode}, nil
+	}
+	fixResult, err := p.codeGen.FixBoth(ctx, p.opts.Description, code, testCode, failure, hint, p.opts.Language)
+	ph.end(err)
+	if err != nil {
+		return nil, err
+	}
+	fixResult.Code = p.cleanCode(fixResult.Code)
+	fixResult.TestCode = p.cleanTests(fixResult.TestCode)
+	if !p.opts.GuardTests {
+		return fixResult, nil
+	}
+	fixResult.TestCode, err = p.guardTests(iteration, testCode, fixResult.TestCode)
+	if err != nil {
+		return nil, err
+	}
+	return fixResult, nil
+}
@@ -0,0 +1,43 @@
+package aiterate
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/prathyushnallamothu/aiterate/internal/ai"
+)
+
+// spend is the running cost of a run's AI requests for Options.VerboseAI.
+// Requests can be made concurrently, e.g. for candidates.
+type spend struct {
+	mu   sync.Mutex
+	cost float64
+	// unpriced is set once a request was made to a model without a known price
+	unpriced bool
+}
+
+// add records the cost of an exchange and returns the running total.
+func (s *spend) add(exchange ai.Exchange) (total float64, unpriced bool) {
+	usage := ai.Usage{PromptTokens: exchange.PromptTokens, CompletionTokens: exchange.CompletionTokens}
+	cost, ok := ai.EstimateCost(exchange.Model, usage)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cost += cost
+	s.unpriced = s.unpriced || !ok
+	return s.cost, s.unpriced
+}
+
+// reportExchange prints a line with the phase, model and token usage of an
+// AI request and the running cost, for Options.VerboseAI.
+func (p *pipeline) reportExchange(exchange ai.Exchange) {
+	total, unpriced := p.spend.add(exchange)
+	line := fmt.Sprintf("AI %s (%s): %d prompt + %d completion tokens, $%.4f so far",
+		exchange.Phase, exchange.Model, exchange.PromptTokens, exchange.CompletionTokens, total)
+	if unpriced {
+		line += ", not counting models without a known price"
+	}
+	if exchange.Error != "" {
+		line += " (request failed)"
+	}
+	p.info("%s", line)
+}